@@ -2,9 +2,13 @@ module agent
 
 go 1.25
 
+require (
+	github.com/ollama/ollama v0.13.1
+	golang.org/x/text v0.23.0
+)
+
 require (
 	github.com/google/uuid v1.6.0 // indirect
-	github.com/ollama/ollama v0.13.1 // indirect
 	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 )
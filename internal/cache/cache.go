@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"agent/internal/errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// entryExt — расширение файлов с закэшированными ответами.
+const entryExt = ".txt"
+
+// Cache хранит ответы модели в директории, по файлу на запрос.
+type Cache struct {
+	dir string
+}
+
+// Stats — количество записей в кэше и их общий размер.
+type Stats struct {
+	Entries int
+	Bytes   int64
+}
+
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Key вычисляет ключ записи по частям запроса.
+func Key(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get возвращает сохранённый ответ, если он есть.
+func (c *Cache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Put сохраняет ответ под ключом.
+func (c *Cache) Put(key, response string) error {
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+	}
+	if err := os.WriteFile(c.path(key), []byte(response), 0644); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+	}
+	return nil
+}
+
+// Stats подсчитывает записи кэша. Отсутствующая директория — пустой кэш.
+func (c *Cache) Stats() (Stats, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stats.Entries++
+		stats.Bytes += info.Size()
+	}
+	return stats, nil
+}
+
+// Clear удаляет все записи кэша и возвращает их количество. Посторонние
+// файлы в директории не трогаются.
+func (c *Cache) Clear() (int, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (c *Cache) entries() ([]os.DirEntry, error) {
+	all, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+
+	var entries []os.DirEntry
+	for _, entry := range all {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), entryExt) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+entryExt)
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache_StatsAndClear(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+
+	responses := map[string]string{
+		Key([]byte("a")): "короткий",
+		Key([]byte("b")): "ответ подлиннее",
+		Key([]byte("c")): "x",
+	}
+	var wantBytes int64
+	for key, response := range responses {
+		if err := c.Put(key, response); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		wantBytes += int64(len(response))
+	}
+	// Посторонний файл не считается записью кэша
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("не кэш"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Entries != 3 || stats.Bytes != wantBytes {
+		t.Errorf("Stats() = %+v, want 3 entries and %d bytes", stats, wantBytes)
+	}
+
+	removed, err := c.Clear()
+	if err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Clear() removed %d, want 3", removed)
+	}
+	if stats, _ := c.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Stats() after Clear = %+v, want empty", stats)
+	}
+	if _, ok := c.Get(Key([]byte("a"))); ok {
+		t.Error("Get() after Clear should miss")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		t.Errorf("Clear() removed an unrelated file: %v", err)
+	}
+}
+
+func TestCache_GetPut(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "nested"))
+	key := Key([]byte("model"), []byte("prompt"))
+
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Get() on empty cache should miss")
+	}
+	if err := c.Put(key, "ответ"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, ok := c.Get(key); !ok || got != "ответ" {
+		t.Errorf("Get() = %q, %v; want cached response", got, ok)
+	}
+
+	if Key([]byte("ab"), []byte("c")) == Key([]byte("a"), []byte("bc")) {
+		t.Error("Key() should separate parts")
+	}
+}
+
+func TestCache_StatsMissingDir(t *testing.T) {
+	stats, err := New(filepath.Join(t.TempDir(), "missing")).Stats()
+	if err != nil || stats.Entries != 0 {
+		t.Errorf("Stats() on missing dir = %+v, %v; want empty, nil", stats, err)
+	}
+}
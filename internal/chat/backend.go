@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/openai"
+	"fmt"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Client — клиент сервера модели: генерация ответов и проверки /doctor.
+type Client interface {
+	AIClient
+	DiagnosticsClient
+}
+
+// NewClient создаёт клиент для сервера, выбранного в BACKEND: Ollama
+// (адрес из OLLAMA_HOST) или OpenAI-совместимый API (OPENAI_BASE_URL
+// и OPENAI_API_KEY).
+func NewClient(cfg *config.Config) (Client, error) {
+	if cfg.Backend == config.BackendOpenAI {
+		return openai.NewClient(cfg.OpenAIBaseURL, cfg.OpenAIAPIKey), nil
+	}
+
+	client, err := api.ClientFromEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrClientInit, err)
+	}
+	return client, nil
+}
@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PrintWelcome печатает приветствие при запуске: баннер STARTUP_BANNER,
+// если он задан, и сведения о продолжаемом или новом чате.
+func (c *Chat) PrintWelcome() {
+	out := c.output()
+	if banner := c.banner(); banner != "" {
+		fmt.Fprintln(out, banner)
+	}
+	fmt.Fprintf(out, "🤖 Добро пожаловать, %s!\n", c.session.UserName)
+
+	if len(c.session.Messages) > 0 {
+		fmt.Fprintf(out, "📚 Продолжаем существующий чат (%d сообщений в истории)\n", len(c.session.Messages))
+		fmt.Fprintln(out, "\n📜 Последние сообщения:")
+		c.DisplayRecentMessages(c.session.Messages, 4)
+	} else {
+		fmt.Fprintln(out, "🆕 Начинаем новый чат")
+	}
+
+	fmt.Fprintln(out, "Введите 'exit' или 'quit' для выхода")
+	fmt.Fprintln(out, "----------------------------------")
+}
+
+// banner возвращает текст STARTUP_BANNER. Если значение — путь к
+// существующему файлу, баннер читается из него. Токены {{user}}, {{date}}
+// и {{env:VAR}} подставляются так же, как в системном промпте.
+func (c *Chat) banner() string {
+	banner := c.cfg.StartupBanner
+	if banner == "" {
+		return ""
+	}
+	if data, err := os.ReadFile(banner); err == nil {
+		banner = strings.TrimRight(string(data), "\r\n")
+	}
+	return c.expandSystemPrompt(banner)
+}
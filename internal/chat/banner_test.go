@@ -0,0 +1,43 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChat_PrintWelcome_banner(t *testing.T) {
+	bannerFile := filepath.Join(t.TempDir(), "banner.txt")
+	if err := os.WriteFile(bannerFile, []byte("╔ ACME Assistant ╗\n✨ Для {{user}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		banner string
+		want   string
+	}{
+		{"default", "", "🤖 Добро пожаловать, testuser!\n"},
+		{"text", "🚀 Помощник {{user}}", "🚀 Помощник testuser\n🤖 Добро пожаловать, testuser!\n"},
+		{"file", bannerFile, "╔ ACME Assistant ╗\n✨ Для testuser\n🤖 Добро пожаловать, testuser!\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{StartupBanner: tt.banner})
+			var output strings.Builder
+			chat.SetOutput(&output)
+
+			chat.PrintWelcome()
+
+			if !strings.HasPrefix(output.String(), tt.want) {
+				t.Errorf("PrintWelcome() printed %q, want prefix %q", output.String(), tt.want)
+			}
+			if !strings.Contains(output.String(), "🆕 Начинаем новый чат") {
+				t.Errorf("PrintWelcome() must keep the welcome message, got %q", output.String())
+			}
+		})
+	}
+}
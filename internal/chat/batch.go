@@ -0,0 +1,155 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// BatchResult — ответ модели на один запрос из пакетного файла.
+type BatchResult struct {
+	Prompt   string
+	Response string
+	Err      error
+}
+
+// ReadBatchPrompts читает по одному запросу на строку, пропуская пустые.
+func ReadBatchPrompts(r io.Reader) ([]string, error) {
+	var prompts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			prompts = append(prompts, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+	return prompts, nil
+}
+
+// RunBatch отправляет каждый запрос модели с чистым контекстом, используя
+// не более workers одновременных запросов. Результаты возвращаются в порядке
+// запросов.
+func RunBatch(client AIClient, cfg *config.Config, prompts []string, workers int) []BatchResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]BatchResult, len(prompts))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				response, err := runBatchPrompt(client, cfg, prompts[i])
+				results[i] = BatchResult{Prompt: prompts[i], Response: response, Err: err}
+			}
+		}()
+	}
+
+	for i := range prompts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// runBatchPrompt выполняет один запрос без истории и без вывода в терминал.
+func runBatchPrompt(client AIClient, cfg *config.Config, prompt string) (string, error) {
+	message := model.Message{Role: model.RoleUser, Content: prompt, Timestamp: time.Now()}
+	c := newChat(client, cfg, &session.ChatSession{Messages: []model.Message{message}, Cfg: cfg})
+	return c.generateOnce()
+}
+
+// generateOnce отправляет историю сессии одним нестриминговым запросом и
+// возвращает очищенный ответ, ничего не печатая и не сохраняя. При заданном
+// RESPONSE_CACHE_DIR одинаковые запросы обслуживаются из кэша.
+func (c *Chat) generateOnce() (string, error) {
+	prompt, err := c.sanitizer().SanitizePrompt(c.buildContextPrompt(c.session.Messages))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errors.ErrInputRejected, err)
+	}
+
+	req := c.buildRequest(prompt)
+	req.Stream = &[]bool{false}[0]
+
+	responses, key := c.responseCache(req)
+	if responses != nil {
+		if cached, ok := responses.Get(key); ok {
+			return cached, nil
+		}
+	}
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+
+	var response strings.Builder
+	err = c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
+		response.WriteString(resp.Response)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errors.ErrMessageSend, err)
+	}
+
+	content := response.String()
+	if c.cfg.TrimStopSequences {
+		content = trimAtStopSequence(content, c.cfg.StopSequences)
+	}
+	content = stripPrefill(content, c.cfg.AssistantPrefill)
+
+	if responses != nil {
+		if err := responses.Put(key, content); err != nil {
+			c.debugf("не удалось сохранить ответ в кэш: %v", err)
+		}
+	}
+	return content, nil
+}
+
+// WriteBatchResults сохраняет ответы. Если path — существующая директория,
+// каждый ответ пишется в отдельный файл 001.txt, 002.txt, …; иначе все
+// ответы собираются в один файл по порядку.
+func WriteBatchResults(results []BatchResult, path string) error {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		for i, result := range results {
+			name := filepath.Join(path, fmt.Sprintf("%03d.txt", i+1))
+			if err := os.WriteFile(name, []byte(batchText(result)+"\n"), 0644); err != nil {
+				return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+			}
+		}
+		return nil
+	}
+
+	var builder strings.Builder
+	for i, result := range results {
+		builder.WriteString(fmt.Sprintf("### %d. %s\n\n%s\n\n", i+1, result.Prompt, batchText(result)))
+	}
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+	}
+	return nil
+}
+
+func batchText(result BatchResult) string {
+	if result.Err != nil {
+		return "Ошибка: " + result.Err.Error()
+	}
+	return result.Response
+}
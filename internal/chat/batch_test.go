@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestReadBatchPrompts(t *testing.T) {
+	prompts, err := ReadBatchPrompts(strings.NewReader("первый\n\n  второй  \nтретий\n"))
+	if err != nil {
+		t.Fatalf("ReadBatchPrompts() error = %v", err)
+	}
+	want := []string{"первый", "второй", "третий"}
+	if strings.Join(prompts, "|") != strings.Join(want, "|") {
+		t.Errorf("ReadBatchPrompts() = %q, want %q", prompts, want)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	prompts := make([]string, 20)
+	for i := range prompts {
+		prompts[i] = fmt.Sprintf("prompt-%d", i)
+	}
+
+	var active, maxActive atomic.Int32
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				peak := maxActive.Load()
+				if n <= peak || maxActive.CompareAndSwap(peak, n) {
+					break
+				}
+			}
+
+			if strings.Count(req.Prompt, "prompt-") != 1 {
+				return fmt.Errorf("other prompts leaked into the context: %q", req.Prompt)
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			// Ответ повторяет номер запроса, чтобы проверить сопоставление
+			_, current, _ := strings.Cut(req.Prompt, "Текущий вопрос: ")
+			return fn(api.GenerateResponse{Response: "answer to " + current})
+		},
+	}
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			maxActive.Store(0)
+			results := RunBatch(client, &config.Config{CtxSizeLimit: 10}, prompts, workers)
+
+			if len(results) != len(prompts) {
+				t.Fatalf("results = %d, want %d", len(results), len(prompts))
+			}
+			for i, result := range results {
+				if result.Err != nil {
+					t.Errorf("result[%d] error = %v", i, result.Err)
+				}
+				if result.Prompt != prompts[i] || result.Response != "answer to "+prompts[i] {
+					t.Errorf("result[%d] = {%q %q}, want answer to %q", i, result.Prompt, result.Response, prompts[i])
+				}
+			}
+			if got := maxActive.Load(); got > int32(workers) {
+				t.Errorf("max concurrent requests = %d, want at most %d", got, workers)
+			}
+		})
+	}
+}
+
+func TestWriteBatchResults(t *testing.T) {
+	results := []BatchResult{
+		{Prompt: "q1", Response: "a1"},
+		{Prompt: "q2", Err: fmt.Errorf("timeout")},
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := WriteBatchResults(results, dir); err != nil {
+			t.Fatalf("WriteBatchResults() error = %v", err)
+		}
+		for name, want := range map[string]string{"001.txt": "a1\n", "002.txt": "Ошибка: timeout\n"} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil || string(data) != want {
+				t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+			}
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.md")
+		if err := WriteBatchResults(results, path); err != nil {
+			t.Fatalf("WriteBatchResults() error = %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		want := "### 1. q1\n\na1\n\n### 2. q2\n\nОшибка: timeout\n\n"
+		if string(data) != want {
+			t.Errorf("output = %q, want %q", data, want)
+		}
+	})
+}
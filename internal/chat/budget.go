@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// defaultNumCtx — окно контекста Ollama, если NUM_CTX не задан.
+	defaultNumCtx = 4096
+	// charsPerToken — грубая оценка числа символов на один токен.
+	charsPerToken = 4
+)
+
+// contextUsage оценивает, на сколько процентов запрос заполнит окно
+// контекста модели. Оценка приблизительная: токены считаются как
+// символы / charsPerToken.
+func (c *Chat) contextUsage(system, prompt string) int {
+	numCtx := c.numCtx()
+	chars := utf8.RuneCountInString(system) + utf8.RuneCountInString(prompt)
+	tokens := (chars + charsPerToken - 1) / charsPerToken
+
+	return tokens * 100 / numCtx
+}
+
+// numCtx возвращает окно контекста, с которым работает модель.
+func (c *Chat) numCtx() int {
+	if c.cfg.NumCtx > 0 {
+		return c.cfg.NumCtx
+	}
+	return defaultNumCtx
+}
+
+// promptTruncated сообщает, что Ollama обрезала промпт под окно контекста.
+// Явного флага в ответе нет: при обрезке число обработанных токенов промпта
+// в финальном ответе упирается в num_ctx. Проверка работает только при
+// заданном NUM_CTX: без него настоящее окно модели неизвестно, и сравнение
+// с defaultNumCtx давало бы ложные срабатывания. Токены промпта из кэша
+// Ollama в PromptEvalCount не входят, поэтому часть обрезок остаётся
+// незамеченной.
+func (c *Chat) promptTruncated(resp api.GenerateResponse) bool {
+	return resp.Done && c.cfg.NumCtx > 0 && resp.PromptEvalCount >= c.cfg.NumCtx
+}
+
+func (c *Chat) warnPromptTruncated(tokens int) {
+	if c.cfg.Bare {
+		return
+	}
+	fmt.Fprintf(c.output(), "\n⚠️  Промпт (%d токенов) не поместился в окно контекста %d: Ollama отбросила начало истории. "+
+		"Уменьшите CTX_SIZE_LIMIT, сократите историю командой /prune или увеличьте NUM_CTX\n", tokens, c.numCtx())
+}
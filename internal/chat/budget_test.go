@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_contextUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		numCtx int
+		system string
+		prompt string
+		want   int
+	}{
+		{"empty prompt", 100, "", "", 0},
+		{"quarter of window", 100, "", strings.Repeat("a", 100), 25},
+		{"system prompt is counted", 100, strings.Repeat("s", 40), strings.Repeat("a", 200), 60},
+		{"runes, not bytes", 100, "", strings.Repeat("я", 200), 50},
+		{"default window when unset", 0, "", strings.Repeat("a", 4096*2), 50},
+		{"over budget", 10, "", strings.Repeat("a", 80), 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{NumCtx: tt.numCtx}}
+			if got := c.contextUsage(tt.system, tt.prompt); got != tt.want {
+				t.Errorf("contextUsage() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_contextUsage_knownHistory(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, NumCtx: 50, PromptStyle: config.PromptStyleMinimal}
+	c := &Chat{cfg: cfg}
+
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: strings.Repeat("q", 38), Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: strings.Repeat("a", 40), Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: strings.Repeat("q", 38), Timestamp: time.Now()},
+	}
+
+	// minimal: 38 + 2 + 40 + 2 + 38 = 120 символов = 30 токенов из 50
+	prompt := c.buildContextPrompt(messages)
+	if got := c.contextUsage("", prompt); got != 60 {
+		t.Errorf("contextUsage() = %d, want 60", got)
+	}
+}
+
+func TestChat_sendMessage_warnsOnPromptTruncation(t *testing.T) {
+	tests := []struct {
+		name        string
+		numCtx      int
+		promptEval  int
+		wantWarning bool
+	}{
+		{"prompt fits", 512, 300, false},
+		{"prompt cut to num_ctx", 512, 512, true},
+		{"num_ctx not set", 0, 4096, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, NumCtx: tt.numCtx}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					if err := fn(api.GenerateResponse{Response: "Ответ"}); err != nil {
+						return err
+					}
+					return fn(api.GenerateResponse{Done: true, Metrics: api.Metrics{PromptEvalCount: tt.promptEval}})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			var output strings.Builder
+			chat.SetOutput(&output)
+
+			chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Вопрос", Timestamp: time.Now()}}
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() unexpected error: %v", err)
+			}
+
+			if got := strings.Contains(output.String(), "не поместился в окно контекста"); got != tt.wantWarning {
+				t.Errorf("truncation warning = %v, want %v; output %q", got, tt.wantWarning, output.String())
+			}
+			if len(chat.session.Messages) != 2 {
+				t.Errorf("answer must be saved anyway, got %+v", chat.session.Messages)
+			}
+		})
+	}
+}
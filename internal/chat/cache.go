@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"agent/internal/cache"
+	"agent/internal/errors"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// responseCache возвращает кэш ответов и ключ для запроса. Если
+// RESPONSE_CACHE_DIR не задан, кэш равен nil.
+func (c *Chat) responseCache(req *api.GenerateRequest) (*cache.Cache, string) {
+	if c.cfg.ResponseCacheDir == "" {
+		return nil, ""
+	}
+
+	// Ключ зависит от всего, что влияет на ответ; json сортирует ключи опций
+	options, _ := json.Marshal(req.Options)
+	think, _ := json.Marshal(req.Think)
+	key := cache.Key([]byte(req.Model), []byte(req.System), []byte(req.Prompt), options, think)
+	return cache.New(c.cfg.ResponseCacheDir), key
+}
+
+// cache обрабатывает «/cache stats» и «/cache clear».
+func (c *Chat) cache(args string) error {
+	if c.cfg.ResponseCacheDir == "" {
+		fmt.Fprintln(c.output(), "🗄️  Кэш ответов отключён (RESPONSE_CACHE_DIR не задан)")
+		return nil
+	}
+	responses := cache.New(c.cfg.ResponseCacheDir)
+
+	switch strings.ToLower(args) {
+	case "", "stats":
+		stats, err := responses.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.output(), "🗄️  Кэш ответов: %d записей, %s (%s)\n",
+			stats.Entries, formatBytes(stats.Bytes), c.cfg.ResponseCacheDir)
+	case "clear":
+		if !c.confirm("🧹 Очистить кэш ответов") {
+			fmt.Fprintln(c.output(), "Отменено")
+			return nil
+		}
+		removed, err := responses.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.output(), "🧹 Удалено записей кэша: %d\n", removed)
+	default:
+		return fmt.Errorf("%w: /cache %s, ожидается stats или clear", errors.ErrInvalidOption, args)
+	}
+	return nil
+}
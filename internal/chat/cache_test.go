@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"agent/internal/cache"
+	"agent/internal/config"
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_cacheCommand(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, ResponseCacheDir: t.TempDir()}
+
+	calls := 0
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			calls++
+			return fn(api.GenerateResponse{Response: "ответ на " + req.Prompt})
+		},
+	}
+
+	for _, prompt := range []string{"первый", "второй", "первый"} {
+		if _, err := runBatchPrompt(client, cfg, prompt); err != nil {
+			t.Fatalf("runBatchPrompt(%q) error = %v", prompt, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Generate called %d times, want 2 (repeated prompt served from cache)", calls)
+	}
+
+	stats, err := cache.New(cfg.ResponseCacheDir).Stats()
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	chat := newTestChat(client, cfg)
+	var buf bytes.Buffer
+	chat.SetOutput(&buf)
+
+	if handled, err := chat.handleCommand("/cache stats"); !handled || err != nil {
+		t.Fatalf("handleCommand(/cache stats) = %v, %v", handled, err)
+	}
+	if want := "2 записей, " + formatBytes(stats.Bytes); !strings.Contains(buf.String(), want) {
+		t.Errorf("/cache stats printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	chat.input = bufio.NewScanner(strings.NewReader("y\n"))
+	if handled, err := chat.handleCommand("/cache clear"); !handled || err != nil {
+		t.Fatalf("handleCommand(/cache clear) = %v, %v", handled, err)
+	}
+	if !strings.Contains(buf.String(), "Удалено записей кэша: 2") {
+		t.Errorf("/cache clear printed %q", buf.String())
+	}
+	if stats, _ := cache.New(cfg.ResponseCacheDir).Stats(); stats.Entries != 0 {
+		t.Errorf("entries after /cache clear = %d, want 0", stats.Entries)
+	}
+
+	if _, err := runBatchPrompt(client, cfg, "первый"); err != nil {
+		t.Fatalf("runBatchPrompt() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Generate called %d times, want 3 after the cache was cleared", calls)
+	}
+}
@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// category показывает категорию сессии, задаёт её или, с аргументом
+// «clear», убирает.
+func (c *Chat) category(args string) error {
+	switch {
+	case args == "":
+		if c.session.Category == "" {
+			fmt.Fprintln(c.output(), "🗃️  У сессии нет категории")
+		} else {
+			fmt.Fprintf(c.output(), "🗃️  Категория: %s\n", c.session.Category)
+		}
+		return nil
+	case strings.EqualFold(args, "clear"):
+		c.session.Category = ""
+		fmt.Fprintln(c.output(), "🗃️  Категория сессии убрана")
+	default:
+		c.session.Category = args
+		fmt.Fprintf(c.output(), "🗃️  Категория: %s\n", c.session.Category)
+	}
+
+	c.session.Updated = time.Now()
+	return c.saveSession()
+}
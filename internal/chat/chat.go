@@ -7,12 +7,18 @@ import (
 	"agent/internal/session"
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/ollama/ollama/api"
+	"golang.org/x/text/unicode/norm"
 )
 
 type AIClient interface {
@@ -20,15 +26,41 @@ type AIClient interface {
 }
 
 type Chat struct {
-	client  AIClient
-	cfg     *config.Config
-	session *session.ChatSession
+	client        AIClient
+	cfg           *config.Config
+	session       *session.ChatSession
+	runCommand    CommandRunner
+	sanitize      Sanitizer      // политика обработки ввода и промптов; nil — без изменений
+	lastResponse  string         // последний ответ модели в исходном виде, до нормализации
+	turnOptions   map[string]any // опции модели только для текущего запроса (@key=value)
+	turnNoThink   bool           // размышления отключены для текущего запроса (!nothink)
+	onceSystem    string         // системный промпт только для следующего запроса (/once-system)
+	confirmed     bool           // команда введена с «!» и выполняется без подтверждения
+	now           func() time.Time
+	out           io.Writer       // куда выводится диалог; nil — os.Stdout
+	input         *bufio.Scanner  // ввод диалога, из которого команды читают подтверждения
+	elaborating   bool            // идёт повторный запрос к слишком короткому ответу (MIN_RESPONSE_LEN)
+	formatRetry   int             // номер повторного запроса после ответа в неверном формате (FORMAT)
+	autosaves     int             // сколько раз сессия автосохранена за этот запуск (/info)
+	turns         int             // завершённых обменов репликами за этот запуск (MAX_TURNS)
+	retries       int             // повторов /retry подряд на текущий вопрос (TEMPERATURE_STEP)
+	regenerating  bool            // идёт /regen или /replay: история временно укорочена, автосохранение отключено (под saveMu)
+	fullHistory   []model.Message // полная история на время /regen и /replay: её сохраняет Close (под saveMu)
+	cleared       bool            // история очищена командой /clear, пустая сессия уже сохранена
+	pendingImages []api.ImageData // изображения для следующего сообщения (/image)
+	saveMu        sync.Mutex      // не даёт завершению прервать идущее сохранение
+
+	genMu         sync.Mutex
+	cancelGen     context.CancelFunc // отменяет идущий запрос к модели; nil — запроса нет
+	interrupted   bool               // текущий запрос прерван по Ctrl+C
+	lastInterrupt time.Time          // когда генерация прерывалась в последний раз
+	closing       bool               // идёт завершение по сигналу: ввод читает основной цикл
 }
 
 func NewChat(userName string, cfg *config.Config) (*Chat, error) {
-	client, err := api.ClientFromEnvironment()
+	client, err := NewClient(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", errors.ErrClientInit, err)
+		return nil, err
 	}
 
 	return NewChatWithClient(userName, cfg, client)
@@ -44,35 +76,145 @@ func NewChatWithClient(userName string, cfg *config.Config, client AIClient) (*C
 		return nil, fmt.Errorf("%w: %v", errors.ErrSessionInit, err)
 	}
 
-	return &Chat{
-		client:  client,
-		cfg:     cfg,
-		session: chatSession,
-	}, nil
+	return newChat(client, cfg, chatSession), nil
+}
+
+// NewChatFromFile открывает сессию из указанного файла вместо поиска по имени.
+func NewChatFromFile(filePath string, cfg *config.Config) (*Chat, error) {
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	chatSession, err := session.OpenSessionFile(filePath, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrSessionInit, err)
+	}
+
+	return newChat(client, cfg, chatSession), nil
+}
+
+func newChat(client AIClient, cfg *config.Config, chatSession *session.ChatSession) *Chat {
+	c := &Chat{
+		client:     client,
+		cfg:        cfg,
+		session:    chatSession,
+		runCommand: execCommand,
+		now:        time.Now,
+	}
+	chatSession.SetQuotaConfirm(c.confirmQuota)
+	chatSession.SetTrimNotice(c.warnTrimmed)
+	return c
+}
+
+// SetOutput перенаправляет вывод чата, включая потоковый ответ модели,
+// например в буфер при встраивании Chat в другое приложение.
+func (c *Chat) SetOutput(w io.Writer) {
+	c.out = w
+}
+
+func (c *Chat) output() io.Writer {
+	if c.out != nil {
+		return c.out
+	}
+	return os.Stdout
 }
 
 func (c *Chat) StartChat() {
-	scanner := bufio.NewScanner(os.Stdin)
+	c.chatLoop(os.Stdin)
+}
+
+// RunOnce отправляет один вопрос без интерактивного диалога, выводит ответ
+// и сохраняет сессию — для запуска из скриптов (-p).
+func (c *Chat) RunOnce(prompt string) error {
+	err := c.processUserInput(prompt)
+	fmt.Fprintln(c.output())
+	if err != nil {
+		return err
+	}
+
+	return c.saveSession()
+}
+
+// chatLoop читает ввод пользователя построчно до выхода или конца потока.
+func (c *Chat) chatLoop(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	c.input = scanner
 
 	for {
-		fmt.Print("Вы: ")
+		if !c.cfg.Bare {
+			fmt.Fprint(c.output(), "Вы: ")
+		}
 
-		if !scanner.Scan() {
+		input, multiline, ok := c.readInput(scanner)
+		if !ok {
 			break
 		}
 
-		input := strings.TrimSpace(scanner.Text())
-
 		if c.isExitCommand(input) {
-			fmt.Println("До свидания! 👋")
+			if !c.cfg.Bare {
+				fmt.Fprintln(c.output(), "До свидания! 👋")
+			}
 			break
 		}
 
-		if err := c.processUserInput(input); err != nil {
-			fmt.Printf("Ошибка: %v\n", err)
+		// Блок """ всегда отправляется модели, даже если начинается с «/»
+		if !multiline {
+			if handled, err := c.handleCommand(input); handled {
+				if err != nil {
+					fmt.Fprintf(c.output(), "Ошибка: %v\n", err)
+				}
+				continue
+			}
+		}
+
+		var err error
+		if multiline {
+			err = c.sendUserMessage(input, nil, false)
+		} else {
+			err = c.processUserInput(input)
 		}
-		fmt.Println()
+		if err != nil {
+			fmt.Fprintf(c.output(), "Ошибка: %v\n", err)
+		} else {
+			c.turns++
+		}
+		fmt.Fprintln(c.output())
+		c.printTurnSeparator()
+
+		if c.turnLimitReached() {
+			c.finishAtTurnLimit()
+			break
+		}
+	}
+}
+
+// turnLimitReached сообщает, что за этот запуск выполнено MAX_TURNS обменов.
+func (c *Chat) turnLimitReached() bool {
+	return c.cfg.MaxTurns > 0 && c.turns >= c.cfg.MaxTurns
+}
+
+// finishAtTurnLimit сохраняет сессию перед выходом по MAX_TURNS.
+func (c *Chat) finishAtTurnLimit() {
+	if !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "🏁 Достигнут лимит MAX_TURNS=%d, сессия сохранена. До свидания! 👋\n", c.cfg.MaxTurns)
+	}
+	if err := c.Close(); err != nil {
+		fmt.Fprintf(c.output(), "⚠️  Ошибка сохранения сессии: %v\n", err)
+	}
+}
+
+// printTurnSeparator выводит TURN_SEPARATOR после завершённого обмена
+// репликами. Серым цветом — только если терминал поддерживает цвета.
+func (c *Chat) printTurnSeparator() {
+	if c.cfg.TurnSeparator == "" || c.cfg.Bare {
+		return
 	}
+	if colorsEnabled() {
+		fmt.Fprintln(c.output(), colorGray+c.cfg.TurnSeparator+colorReset)
+		return
+	}
+	fmt.Fprintln(c.output(), c.cfg.TurnSeparator)
 }
 
 const (
@@ -80,84 +222,302 @@ const (
 	colorReset = "\033[0m"
 )
 
+// requestContext ограничивает запрос к модели сроком REQUEST_TIMEOUT_SECONDS;
+// при нуле или отрицательном значении срока нет.
+func (c *Chat) requestContext() (context.Context, context.CancelFunc) {
+	if c.cfg.RequestTimeout > 0 {
+		return context.WithTimeout(context.Background(), c.cfg.RequestTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 func (c *Chat) sendMessage(message []model.Message) error {
 	if len(message) == 0 {
 		return errors.ErrNoMessages
 	}
 
-	prompt := c.buildContextPrompt(message)
-
-	if c.cfg.UseAssistantPrefill {
-		prompt += "\n\nНачни свой ответ с фразы: " + c.cfg.AssistantPrefill
+	prompt, err := c.sanitizer().SanitizePrompt(c.assembleContext(message))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInputRejected, err)
 	}
 
-	req := &api.GenerateRequest{
-		Think:  c.cfg.ThinkValue,
-		Model:  c.cfg.ModelName,
-		Prompt: prompt,
-		Stream: &[]bool{true}[0],
-		System: c.cfg.SystemPrompt,
-		Options: map[string]interface{}{
-			"temperature": c.cfg.Temperature,
-			"stop":        c.cfg.StopSequences,
-			"num_predict": c.cfg.MaxResponseSize,
-		},
+	req := c.buildRequest(prompt)
+	c.debugf("запрос: модель %s, промпт %d символов, системный промпт %d символов, опции %v",
+		req.Model, len([]rune(req.Prompt)), len([]rune(req.System)), req.Options)
+
+	if !c.cfg.Bare {
+		if c.cfg.ShowBudget {
+			fmt.Fprintf(c.output(), "📊 Контекст заполнен на %d%%\n", c.contextUsage(req.System, req.Prompt))
+		}
+		fmt.Fprint(c.output(), "AI: ")
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
+	ctx, cancel := c.requestContext()
 	defer cancel()
+	c.setGeneration(cancel)
+	defer c.setGeneration(nil)
+
+	watchdog := newStallWatchdog(c.cfg.StallTimeout, cancel)
+	defer watchdog.Stop()
+	if !c.cfg.Bare {
+		watchdog.HintAfter(c.cfg.StallHint, c.stallHint)
+	}
+
+	firstChunk := func() {}
+	if c.cfg.Spinner && !c.cfg.Bare && colorsEnabled() {
+		s := newSpinner(c.output(), 100*time.Millisecond)
+		s.Start()
+		defer s.Stop()
+		firstChunk = s.Stop
+	}
 
-	var response strings.Builder
+	var response, thinking strings.Builder
 	var thinkingStarted bool
+	var loops *loopDetector
+	var truncated string
+	var stoppedAtBlank bool
+	var promptTokens int
+	out := c.responseWriter()
+
+	if c.cfg.DetectLoops {
+		loops = newLoopDetector()
+	}
+
+	err = c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
+		watchdog.Reset()
+		firstChunk()
+		if c.promptTruncated(resp) {
+			promptTokens = resp.PromptEvalCount
+		}
 
-	err := c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
-		if resp.Thinking != "" {
+		thinking.WriteString(resp.Thinking)
+		if resp.Thinking != "" && !c.cfg.Bare {
 			if !thinkingStarted {
-				fmt.Print(colorGray + "💭 ")
+				fmt.Fprint(c.output(), colorGray+"💭 ")
 				thinkingStarted = true
 			}
-			fmt.Print(colorGray + resp.Thinking + colorReset)
+			fmt.Fprint(c.output(), colorGray+resp.Thinking+colorReset)
 		}
 		if resp.Response != "" {
-			fmt.Print(resp.Response)
-			response.WriteString(resp.Response)
+			chunk, blank := resp.Response, false
+			if c.cfg.StopOnBlankLine {
+				chunk, blank = cutAtBlankLine(response.String(), chunk)
+			}
+			fmt.Fprint(out, chunk)
+			response.WriteString(chunk)
+
+			if blank {
+				stoppedAtBlank = true
+				return errors.ErrBlankLineStop
+			}
+			if loops != nil && loops.Feed(resp.Response) {
+				truncated = model.TruncatedLoop
+				return errors.ErrLoopDetected
+			}
 		}
 		return nil
 	})
 
+	out.Flush()
 	if thinkingStarted {
-		fmt.Print(colorReset + "\n\n")
+		fmt.Fprint(c.output(), colorReset+"\n\n")
+	}
+
+	if c.generationInterrupted() {
+		return c.saveInterrupted(response.String(), thinking.String())
+	}
+
+	if truncated == model.TruncatedLoop {
+		if !c.cfg.Bare {
+			fmt.Fprintln(c.output(), "\n⚠️  Генерация остановлена: модель зациклилась, ответ сохранён обрезанным")
+		}
+		err = nil
+	}
+
+	if stoppedAtBlank {
+		err = nil
+	}
+
+	if watchdog.Stalled() {
+		if !watchdog.Received() {
+			return fmt.Errorf("%w: сервер принял запрос, но за %v не прислал ни одного фрагмента — возможно, модель не загрузилась или Ollama зависла",
+				errors.ErrNoResponse, c.cfg.StallTimeout)
+		}
+		return fmt.Errorf("%w: нет данных дольше %v", errors.ErrStreamStalled, c.cfg.StallTimeout)
 	}
 
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("ответ не получен за %v (REQUEST_TIMEOUT_SECONDS): %w", c.cfg.RequestTimeout, err)
+	}
 	if err != nil {
-		return fmt.Errorf("%w: %v", errors.ErrMessageSend, err)
+		genErr := &errors.GenerateError{
+			Model:     req.Model,
+			PromptLen: len([]rune(req.Prompt)),
+			Options:   req.Options,
+			Err:       err,
+		}
+		c.debugf("запрос завершился ошибкой: %v, опции: %v", genErr, genErr.Options)
+		return fmt.Errorf("%w: %w", errors.ErrMessageSend, genErr)
+	}
+
+	if promptTokens > 0 {
+		c.warnPromptTruncated(promptTokens)
 	}
 
-	c.addAIResponse(response.String())
+	c.lastResponse = response.String()
+
+	content := response.String()
+	if c.cfg.TrimStopSequences {
+		content = trimAtStopSequence(content, c.cfg.StopSequences)
+	}
+	content = stripPrefill(content, c.cfg.AssistantPrefill)
+
+	// Рассуждающая модель может выдать всё в Thinking и оставить ответ пустым
+	if strings.TrimSpace(content) == "" && strings.TrimSpace(thinking.String()) != "" {
+		switch {
+		case c.cfg.ThinkingOnly == config.ThinkingOnlySave:
+			content = c.thinkingAsAnswer(thinking.String())
+		case c.cfg.ThinkingOnly == config.ThinkingOnlyRetry && !c.turnNoThink:
+			return c.retryWithoutThinking(message)
+		default:
+			// В режиме --json ответ без текста тоже выводится объектом,
+			// а при уточнении остаётся короткий ответ перед ним
+			if c.jsonOutput() {
+				return c.replyJSON(c.keptReply(), thinking.String())
+			}
+			c.warnThinkingOnly()
+			return nil
+		}
+	}
+
+	if !c.validFormat(content) {
+		if c.formatRetry < c.cfg.FormatRetries {
+			return c.reaskFormat(message)
+		}
+		c.warnInvalidFormat()
+	}
+
+	c.addAIResponse(content, thinking.String())
+	c.session.Messages[len(c.session.Messages)-1].Truncated = truncated
+	if c.elaborating {
+		content = c.mergeElaboration()
+	} else if c.tooShort(content) {
+		return c.elaborate()
+	}
+	if err := c.replyJSON(content, thinking.String()); err != nil {
+		return err
+	}
+	c.runResponseHook(content)
 	c.autoSave()
 	return nil
 }
 
+// buildRequest формирует запрос к модели для собранного промпта с учётом
+// префилла и разовых настроек текущего сообщения.
+func (c *Chat) buildRequest(prompt string) *api.GenerateRequest {
+	if c.cfg.UseAssistantPrefill {
+		prompt += "\n\n" + c.prefillInstruction()
+	}
+
+	if c.formatRetry > 0 {
+		prompt += "\n\n" + formatInstruction
+	}
+
+	think := c.cfg.ThinkValue
+	if c.turnNoThink {
+		think = &api.ThinkValue{Value: false}
+	}
+
+	req := &api.GenerateRequest{
+		Think:     think,
+		Model:     c.cfg.ModelName,
+		Prompt:    prompt,
+		Stream:    &[]bool{true}[0],
+		KeepAlive: c.cfg.KeepAlive,
+		System:    c.requestSystemPrompt(),
+		Images:    c.pendingImages,
+		Options: map[string]interface{}{
+			"temperature": c.temperature(),
+			"stop":        c.cfg.StopSequences,
+			"num_predict": c.cfg.MaxResponseSize,
+		},
+	}
+	if c.cfg.Format == config.FormatJSON {
+		req.Format = json.RawMessage(`"json"`)
+	}
+	if c.cfg.NumCtx > 0 {
+		req.Options["num_ctx"] = c.cfg.NumCtx
+	}
+	if c.cfg.NumThread > 0 {
+		req.Options["num_thread"] = c.cfg.NumThread
+	}
+	if c.cfg.NumGPU > 0 {
+		req.Options["num_gpu"] = c.cfg.NumGPU
+	}
+	for key, value := range c.turnOptions {
+		req.Options[key] = value
+	}
+	return req
+}
+
 func (c *Chat) isExitCommand(input string) bool {
 	return input == "exit" || input == "quit" || input == ""
 }
 
+// processUserInput отделяет директивы !nothink и @key=value в начале строки
+// и отправляет сообщение модели.
 func (c *Chat) processUserInput(input string) error {
+	input, noThink := cutNoThink(input)
+	content, options, err := parseInlineOptions(input)
+	if err != nil {
+		return err
+	}
+	return c.sendUserMessage(content, options, noThink)
+}
+
+// sendUserMessage добавляет сообщение пользователя в историю и отправляет
+// его модели с разовыми настройками options и noThink. Блок """ приходит
+// сюда напрямую: директивы в нём не разбираются, а пробелы по краям
+// (например, отступ кода) сохраняются.
+func (c *Chat) sendUserMessage(content string, options map[string]any, noThink bool) error {
+	c.turnOptions = options
+	c.turnNoThink = noThink
+	c.retries = 0
+	defer func() {
+		c.turnOptions = nil
+		c.turnNoThink = false
+		c.pendingImages = nil
+		c.onceSystem = ""
+	}()
+
+	content, err := c.sanitizer().SanitizeInput(c.normalizeContent(content))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrInputRejected, err)
+	}
+
 	userMessage := model.Message{
 		Role:      model.RoleUser,
-		Content:   input,
+		Content:   content,
 		Timestamp: time.Now(),
 	}
 
 	c.session.Messages = append(c.session.Messages, userMessage)
 	c.session.Updated = time.Now()
 
-	fmt.Print("AI: ")
+	return c.sendMessage(c.session.Messages)
+}
 
-	err := c.sendMessage(c.session.Messages)
-	if err != nil {
-		return err
+// systemPrompt возвращает системный промпт сессии, а если он не задан —
+// глобальный из конфигурации.
+func (c *Chat) systemPrompt() string {
+	if c.session.SystemPrompt != "" {
+		return c.session.SystemPrompt
 	}
-	return nil
+	return c.cfg.SystemPrompt
+}
+
+// LastResponse возвращает последний полученный ответ модели без нормализации.
+func (c *Chat) LastResponse() string {
+	return c.lastResponse
 }
 
 func (c *Chat) GetMessages() []model.Message {
@@ -168,35 +528,168 @@ func (c *Chat) GetSession() *session.ChatSession {
 	return c.session
 }
 
+// DisplayRecentMessages выводит последние count сообщений в порядке
+// DISPLAY_ORDER. Сам срез messages не меняется.
 func (c *Chat) DisplayRecentMessages(messages []model.Message, count int) {
 	start := c.calculateStartIndex(len(messages), count)
 
-	for i := start; i < len(messages); i++ {
-		c.displayMessage(messages[i])
+	if c.cfg.DisplayOrder == config.DisplayNewest {
+		for i := len(messages) - 1; i >= start; i-- {
+			c.displayMessage(messages[i])
+		}
+	} else {
+		for i := start; i < len(messages); i++ {
+			c.displayMessage(messages[i])
+		}
 	}
-	fmt.Println()
+	fmt.Fprintln(c.output())
 }
 
-func (c *Chat) addAIResponse(response string) {
+// addAIResponse добавляет ответ модели в сессию. Размышления сохраняются
+// вместе с ответом только при SAVE_THINKING=true.
+func (c *Chat) addAIResponse(response, thinking string) {
+	content := c.normalizeContent(response)
+	if c.cfg.NormalizeWhitespace {
+		content = tidyWhitespace(content)
+	}
+
 	aiMessage := model.Message{
 		Role:      model.RoleAssistant,
-		Content:   response,
+		Content:   content,
 		Timestamp: time.Now(),
 	}
+	if c.cfg.SaveThinking {
+		aiMessage.Thinking = thinking
+	}
 	c.session.Messages = append(c.session.Messages, aiMessage)
 	c.session.Updated = time.Now()
 }
 
+// cutAtBlankLine ищет первую пустую строку (двойной перевод строки) после
+// начала текста ответа. written — уже полученная часть ответа, chunk — новый
+// фрагмент. Возвращает часть фрагмента до пустой строки и признак остановки.
+func cutAtBlankLine(written, chunk string) (string, bool) {
+	text := written + chunk
+	start := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	if start == len(text) {
+		return chunk, false
+	}
+
+	idx := strings.Index(text[start:], "\n\n")
+	if idx < 0 {
+		return chunk, false
+	}
+	cut := start + idx - len(written)
+	if cut < 0 {
+		return "", true
+	}
+	return chunk[:cut], true
+}
+
+// trimAtStopSequence обрезает ответ по первой встреченной стоп-последовательности,
+// которую модель всё же вывела, например «…ответ. Пользователь:».
+func trimAtStopSequence(response string, stops []string) string {
+	cut := len(response)
+	for _, stop := range stops {
+		if stop == "" {
+			continue
+		}
+		if i := strings.Index(response, stop); i >= 0 && i < cut {
+			cut = i
+		}
+	}
+	if cut == len(response) {
+		return response
+	}
+	return strings.TrimRightFunc(response[:cut], unicode.IsSpace)
+}
+
+// prefillInstruction подставляет префилл в шаблон PREFILL_INSTRUCTION.
+// Если плейсхолдера в шаблоне нет, фраза дописывается в конец.
+func (c *Chat) prefillInstruction() string {
+	instruction := c.cfg.PrefillInstruction
+	if instruction == "" {
+		instruction = config.DefaultPrefillInstruction
+	}
+	if !strings.Contains(instruction, prefillPlaceholder) {
+		return instruction + " " + c.cfg.AssistantPrefill
+	}
+	return strings.ReplaceAll(instruction, prefillPlaceholder, c.cfg.AssistantPrefill)
+}
+
+const prefillPlaceholder = "{{prefill}}"
+
+// stripPrefill убирает фразу префилла из начала ответа. Делается всегда, а не
+// только при USE_ASSISTANT_PREFILL: так сохранённые ответы выглядят одинаково,
+// даже если префилл включали и выключали посреди сессии.
+func stripPrefill(response, prefill string) string {
+	prefill = strings.TrimSpace(prefill)
+	if prefill == "" {
+		return response
+	}
+
+	trimmed := strings.TrimLeftFunc(response, unicode.IsSpace)
+	if !strings.HasPrefix(trimmed, prefill) {
+		return response
+	}
+	return strings.TrimLeftFunc(trimmed[len(prefill):], unicode.IsSpace)
+}
+
+var blankLineRun = regexp.MustCompile(`\n[ \t]*(?:\n[ \t]*){2,}\n`)
+
+// tidyWhitespace убирает пробелы и пустые строки по краям ответа и сжимает
+// серии из нескольких пустых строк в одну.
+func tidyWhitespace(content string) string {
+	content = strings.TrimSpace(content)
+	return blankLineRun.ReplaceAllString(content, "\n\n")
+}
+
+// normalizeContent приводит переводы строк к LF, а при NORMALIZE_UNICODE —
+// текст к NFC.
+func (c *Chat) normalizeContent(content string) string {
+	content = normalizeNewlines(content)
+	if !c.cfg.NormalizeUnicode {
+		return content
+	}
+	return norm.NFC.String(content)
+}
+
+// normalizeNewlines заменяет CRLF и одиночные CR (ввод из Windows) на LF,
+// чтобы модель получала и в историю попадал текст с одним видом переводов строк.
+func normalizeNewlines(content string) string {
+	if !strings.Contains(content, "\r") {
+		return content
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.ReplaceAll(content, "\r", "\n")
+}
+
 func (c *Chat) autoSave() {
-	msgCount := len(c.session.Messages)
-	if msgCount == 2 || msgCount%4 == 0 {
-		fmt.Println("\n💾 Автосохранение сессии...")
-		if err := c.session.SaveSession(c.session); err != nil {
-			fmt.Printf("⚠️  Ошибка автосохранения: %v\n", err)
+	if c.regenerating {
+		return
+	}
+	if c.autoSaveDue(len(c.session.Messages)) {
+		if !c.cfg.Bare {
+			fmt.Fprintln(c.output(), "\n💾 Автосохранение сессии...")
+		}
+		c.saveMu.Lock()
+		err := c.session.AutoSave()
+		c.saveMu.Unlock()
+		if err != nil {
+			fmt.Fprintf(c.output(), "⚠️  Ошибка автосохранения: %v\n", err)
+			return
 		}
+		c.autosaves++
 	}
 }
 
+// autoSaveDue сообщает, сохраняется ли сессия автоматически, когда в истории
+// msgCount сообщений. Первый обмен сохраняется сразу, чтобы появился файл
+// сессии; после /clear файл уже есть, и отдельное сохранение не нужно.
+func (c *Chat) autoSaveDue(msgCount int) bool {
+	return msgCount == 2 && !c.cleared || msgCount%4 == 0
+}
+
 func (c *Chat) calculateStartIndex(totalMessages, count int) int {
 	start := totalMessages - count
 	if start < 0 {
@@ -207,10 +700,12 @@ func (c *Chat) calculateStartIndex(totalMessages, count int) int {
 
 func (c *Chat) displayMessage(msg model.Message) {
 	if msg.IsUser() {
-		fmt.Printf("  👤 Вы: %s\n", msg.Content)
+		fmt.Fprintf(c.output(), "  👤 Вы: %s\n", msg.Content)
+	} else if msg.IsSystem() {
+		fmt.Fprintf(c.output(), "  📌 Система: %s\n", msg.Content)
 	} else {
 		content := c.truncateContent(msg.Content, 1000)
-		fmt.Printf("  🤖 AI: %s\n", content)
+		fmt.Fprintf(c.output(), "  🤖 AI: %s\n", content)
 	}
 }
 
@@ -221,28 +716,3 @@ func (c *Chat) truncateContent(content string, maxLength int) string {
 	}
 	return content
 }
-
-func (c *Chat) buildContextPrompt(messages []model.Message) string {
-	if len(messages) == 0 {
-		return ""
-	}
-
-	var builder strings.Builder
-
-	start := c.calculateStartIndex(len(messages), c.cfg.CtxSizeLimit)
-
-	builder.WriteString("Предыдущий контекст беседы:\n")
-	for i := start; i < len(messages)-1; i++ { // -1 чтобы исключить текущее сообщение
-		msg := messages[i]
-		if msg.IsUser() {
-			builder.WriteString(fmt.Sprintf("Пользователь: %s\n", msg.Content))
-		} else {
-			builder.WriteString(fmt.Sprintf("Ассистент: %s\n", msg.Content))
-		}
-	}
-
-	currentMessage := messages[len(messages)-1]
-	builder.WriteString(fmt.Sprintf("\nТекущий вопрос: %s", currentMessage.Content))
-
-	return builder.String()
-}
@@ -5,8 +5,13 @@ import (
 	"agent/internal/errors"
 	"agent/internal/model"
 	"agent/internal/session"
+	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,6 +40,7 @@ func newTestChat(client AIClient, cfg *config.Config) *Chat {
 			Updated:  time.Now(),
 			Cfg:      cfg,
 		},
+		now: time.Now,
 	}
 }
 
@@ -327,41 +333,127 @@ func TestChat_sendMessage_success(t *testing.T) {
 }
 
 func TestChat_sendMessage_withThinking(t *testing.T) {
-	cfg := &config.Config{
-		CtxSizeLimit:        10,
-		ModelName:           "deepseek-r1:8b",
-		UseAssistantPrefill: false,
+	tests := []struct {
+		name         string
+		saveThinking bool
+		wantThinking string
+	}{
+		{"thinking discarded", false, ""},
+		{"thinking saved", true, "Let me think... about this."},
 	}
 
-	client := &mockAIClient{
-		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
-			// Симулируем thinking + response
-			fn(api.GenerateResponse{Thinking: "Let me think..."})
-			fn(api.GenerateResponse{Thinking: " about this."})
-			fn(api.GenerateResponse{Response: "Here is my answer."})
-			return nil
-		},
-	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				CtxSizeLimit:        10,
+				ModelName:           "deepseek-r1:8b",
+				UseAssistantPrefill: false,
+				SaveThinking:        tt.saveThinking,
+			}
 
-	chat := newTestChat(client, cfg)
-	messages := []model.Message{
-		{Role: model.RoleUser, Content: "Complex question", Timestamp: time.Now()},
-	}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					// Симулируем thinking + response
+					fn(api.GenerateResponse{Thinking: "Let me think..."})
+					fn(api.GenerateResponse{Thinking: " about this."})
+					fn(api.GenerateResponse{Response: "Here is my answer."})
+					return nil
+				},
+			}
 
-	err := chat.sendMessage(messages)
+			chat := newTestChat(client, cfg)
+			messages := []model.Message{
+				{Role: model.RoleUser, Content: "Complex question", Timestamp: time.Now()},
+			}
 
-	if err != nil {
-		t.Fatalf("sendMessage() unexpected error: %v", err)
+			err := chat.sendMessage(messages)
+
+			if err != nil {
+				t.Fatalf("sendMessage() unexpected error: %v", err)
+			}
+
+			// Thinking не должен попасть в сохранённый ответ
+			if len(chat.session.Messages) != 1 {
+				t.Fatalf("Expected 1 message in session, got %d", len(chat.session.Messages))
+			}
+
+			saved := chat.session.Messages[0]
+			if saved.Content != "Here is my answer." {
+				t.Errorf("Saved response = %q, want %q (thinking should not be included)", saved.Content, "Here is my answer.")
+			}
+			if saved.Thinking != tt.wantThinking {
+				t.Errorf("Saved thinking = %q, want %q", saved.Thinking, tt.wantThinking)
+			}
+		})
 	}
+}
 
-	// Thinking не должен попасть в сохранённый ответ
-	if len(chat.session.Messages) != 1 {
-		t.Fatalf("Expected 1 message in session, got %d", len(chat.session.Messages))
+func TestChat_sendMessage_thinkingOnly(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantCalls int
+		want      []string // содержимое сообщений сессии после ответа
+	}{
+		{config.ThinkingOnlyWarn, 1, []string{"Complex question"}},
+		{config.ThinkingOnlySave, 1, []string{"Complex question", "Let me think about this."}},
+		{config.ThinkingOnlyRetry, 2, []string{"Complex question", "Short answer."}},
 	}
 
-	savedContent := chat.session.Messages[0].Content
-	if savedContent != "Here is my answer." {
-		t.Errorf("Saved response = %q, want %q (thinking should not be included)", savedContent, "Here is my answer.")
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &config.Config{
+				CtxSizeLimit: 10,
+				CtxDir:       t.TempDir(),
+				CtxFileExt:   ".json",
+				ModelName:    "deepseek-r1:8b",
+				ThinkValue:   &api.ThinkValue{Value: true},
+				ThinkingOnly: tt.mode,
+			}
+
+			var requests []*api.GenerateRequest
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					requests = append(requests, req)
+					if req.Think.Bool() {
+						// Только размышления, Response пуст
+						fn(api.GenerateResponse{Thinking: "Let me think"})
+						fn(api.GenerateResponse{Thinking: " about this."})
+						return nil
+					}
+					return fn(api.GenerateResponse{Response: "Short answer."})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			var out bytes.Buffer
+			chat.SetOutput(&out)
+			chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Complex question", Timestamp: time.Now()}}
+
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() unexpected error: %v", err)
+			}
+
+			if len(requests) != tt.wantCalls {
+				t.Errorf("Generate called %d times, want %d", len(requests), tt.wantCalls)
+			}
+			if tt.wantCalls == 2 && requests[1].Think.Bool() {
+				t.Error("retry request should have thinking disabled")
+			}
+			if !cfg.ThinkValue.Bool() {
+				t.Error("configured think value must not change")
+			}
+
+			var got []string
+			for _, msg := range chat.session.Messages {
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("session messages = %q, want %q", got, tt.want)
+			}
+			if tt.mode == config.ThinkingOnlyWarn && !strings.Contains(out.String(), "только размышления") {
+				t.Errorf("warn mode should print a warning, got %q", out.String())
+			}
+		})
 	}
 }
 
@@ -400,6 +492,44 @@ func TestChat_sendMessage_clientError(t *testing.T) {
 	}
 }
 
+func TestChat_sendMessage_generateErrorContext(t *testing.T) {
+	cfg := &config.Config{
+		ModelName:    "test-model",
+		CtxSizeLimit: 10,
+		Temperature:  0.3,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			return fmt.Errorf("connection refused")
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Привет", Timestamp: time.Now()}})
+
+	if !stderrors.Is(err, errors.ErrMessageSend) {
+		t.Errorf("error = %v, want ErrMessageSend", err)
+	}
+
+	var genErr *errors.GenerateError
+	if !stderrors.As(err, &genErr) {
+		t.Fatalf("errors.As() could not extract GenerateError from %v", err)
+	}
+	if genErr.Model != "test-model" {
+		t.Errorf("Model = %q, want %q", genErr.Model, "test-model")
+	}
+	if genErr.PromptLen < len([]rune("Привет")) {
+		t.Errorf("PromptLen = %d, want the length of the sent prompt", genErr.PromptLen)
+	}
+	if genErr.Options["temperature"] != 0.3 {
+		t.Errorf("Options[temperature] = %v, want 0.3", genErr.Options["temperature"])
+	}
+	if genErr.Err == nil || genErr.Err.Error() != "connection refused" {
+		t.Errorf("Err = %v, want the client error", genErr.Err)
+	}
+}
+
 func TestChat_sendMessage_withPrefill(t *testing.T) {
 	cfg := &config.Config{
 		CtxSizeLimit:        10,
@@ -433,6 +563,34 @@ func TestChat_sendMessage_withPrefill(t *testing.T) {
 	}
 }
 
+func TestChat_buildRequest_prefillInstruction(t *testing.T) {
+	tests := []struct {
+		name        string
+		instruction string
+		want        string
+	}{
+		{"default", "", "\n\nНачни свой ответ с фразы: Sure"},
+		{"placeholder", "Start your answer with: \"{{prefill}}\"", "\n\nStart your answer with: \"Sure\""},
+		{"no placeholder", "Begin with", "\n\nBegin with Sure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				UseAssistantPrefill: true,
+				AssistantPrefill:    "Sure",
+				PrefillInstruction:  tt.instruction,
+			}
+			chat := newTestChat(&mockAIClient{}, cfg)
+
+			req := chat.buildRequest("Question")
+			if req.Prompt != "Question"+tt.want {
+				t.Errorf("Prompt = %q, want %q", req.Prompt, "Question"+tt.want)
+			}
+		})
+	}
+}
+
 func TestChat_sendMessage_requestOptions(t *testing.T) {
 	cfg := &config.Config{
 		CtxSizeLimit:    10,
@@ -476,3 +634,614 @@ func TestChat_sendMessage_requestOptions(t *testing.T) {
 		t.Errorf("num_predict = %v, want 1024", opts["num_predict"])
 	}
 }
+
+func TestChat_sendMessage_performanceOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		numThread int
+		numGPU    int
+	}{
+		{"unset", 0, 0},
+		{"threads only", 8, 0},
+		{"both", 8, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: 10, NumThread: tt.numThread, NumGPU: tt.numGPU}
+
+			var options map[string]any
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					options = req.Options
+					return fn(api.GenerateResponse{Response: "OK"})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+				t.Fatalf("sendMessage() unexpected error: %v", err)
+			}
+
+			for key, want := range map[string]int{"num_thread": tt.numThread, "num_gpu": tt.numGPU} {
+				got, ok := options[key]
+				if want == 0 && ok {
+					t.Errorf("%s = %v, should not be sent when unset", key, got)
+				}
+				if want != 0 && got != want {
+					t.Errorf("%s = %v, want %d", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestChat_normalizeContent(t *testing.T) {
+	decomposed := "café"
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{"disabled keeps content as is", false, decomposed},
+		{"enabled converts to NFC", true, "café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{NormalizeUnicode: tt.enabled}}
+			if got := c.normalizeContent(decomposed); got != tt.want {
+				t.Errorf("normalizeContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"одна строка", "одна строка"},
+		{"первая\r\nвторая\r\n", "первая\nвторая\n"},
+		{"старый\rMac", "старый\nMac"},
+		{"смесь\r\n\r\nабзац\nконец", "смесь\n\nабзац\nконец"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeNewlines(tt.input); got != tt.want {
+			t.Errorf("normalizeNewlines(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestChat_processUserInput_crlf(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var prompt string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompt = req.Prompt
+			return fn(api.GenerateResponse{Response: "строка 1\r\nстрока 2"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	if err := chat.processUserInput("первая строка\r\nвторая строка\r\n"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+
+	if strings.Contains(prompt, "\r") {
+		t.Errorf("sent prompt contains CR: %q", prompt)
+	}
+	if !strings.Contains(prompt, "первая строка\nвторая строка") {
+		t.Errorf("sent prompt = %q, want LF line endings", prompt)
+	}
+	for _, msg := range chat.session.Messages {
+		if strings.Contains(msg.Content, "\r") {
+			t.Errorf("stored %s message contains CR: %q", msg.Role, msg.Content)
+		}
+	}
+	if got := chat.session.Messages[1].Content; got != "строка 1\nстрока 2" {
+		t.Errorf("stored answer = %q, want LF line endings", got)
+	}
+}
+
+func TestTrimAtStopSequence(t *testing.T) {
+	stops := []string{"Human:", "Пользователь:"}
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"no stop sequence", "Обычный ответ.", "Обычный ответ."},
+		{"dangling label", "Вот ответ. Пользователь:", "Вот ответ."},
+		{"earliest sequence wins", "A\nHuman: B\nПользователь: C", "A"},
+		{"stop at start", "Human: hi", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimAtStopSequence(tt.response, stops); got != tt.want {
+				t.Errorf("trimAtStopSequence(%q) = %q, want %q", tt.response, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCutAtBlankLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		written  string
+		chunk    string
+		want     string
+		wantStop bool
+	}{
+		{"no blank line", "Первый", " абзац.\n", " абзац.\n", false},
+		{"blank line inside chunk", "Первый", " абзац.\n\nВторой", " абзац.", true},
+		{"blank line across chunks", "Первый абзац.\n", "\nВторой", "", true},
+		{"leading blank lines ignored", "", "\n\nОтвет", "\n\nОтвет", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stop := cutAtBlankLine(tt.written, tt.chunk)
+			if got != tt.want || stop != tt.wantStop {
+				t.Errorf("cutAtBlankLine(%q, %q) = %q, %v; want %q, %v", tt.written, tt.chunk, got, stop, tt.want, tt.wantStop)
+			}
+		})
+	}
+}
+
+func TestChat_sendMessage_stopOnBlankLine(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, CtxDir: t.TempDir(), CtxFileExt: ".json", StopOnBlankLine: true}
+
+	chunks := []string{"Короткий", " ответ.\n", "\nЛишний абзац", " и ещё текст."}
+	var sent int
+	var streamErr error
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			for _, chunk := range chunks {
+				sent++
+				if streamErr = fn(api.GenerateResponse{Response: chunk}); streamErr != nil {
+					return streamErr
+				}
+			}
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	if !stderrors.Is(streamErr, errors.ErrBlankLineStop) {
+		t.Errorf("callback error = %v, want ErrBlankLineStop", streamErr)
+	}
+	if sent != 3 {
+		t.Errorf("chunks consumed = %d, want 3 (generation should stop at the blank line)", sent)
+	}
+	saved := chat.session.Messages[len(chat.session.Messages)-1].Content
+	if strings.TrimSpace(saved) != "Короткий ответ." {
+		t.Errorf("saved content = %q, want it to end before the blank line", saved)
+	}
+}
+
+func TestChat_sendMessage_trimsStopSequence(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit:      10,
+		StopSequences:     []string{"Пользователь:"},
+		TrimStopSequences: true,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Готовый ответ. "})
+			fn(api.GenerateResponse{Response: "Пользователь:"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	if got := chat.session.Messages[0].Content; got != "Готовый ответ." {
+		t.Errorf("saved response = %q, want %q", got, "Готовый ответ.")
+	}
+	if chat.LastResponse() != "Готовый ответ. Пользователь:" {
+		t.Errorf("LastResponse() should keep the raw response, got %q", chat.LastResponse())
+	}
+}
+
+func TestChat_sendMessage_keepAlive(t *testing.T) {
+	keepAlive := &api.Duration{Duration: 10 * time.Minute}
+	cfg := &config.Config{CtxSizeLimit: 10, KeepAlive: keepAlive}
+
+	var capturedReq *api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			capturedReq = req
+			fn(api.GenerateResponse{Response: "OK"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	if capturedReq.KeepAlive == nil || capturedReq.KeepAlive.Duration != 10*time.Minute {
+		t.Errorf("KeepAlive = %v, want 10m", capturedReq.KeepAlive)
+	}
+}
+
+// captureStdout перехватывает всё, что fn печатает в os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+// captureFile подменяет *file каналом на время fn и возвращает записанное.
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+
+	original := *file
+	*file = w
+	defer func() { *file = original }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestChat_sendMessage_bareOutput(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		Bare:         true,
+		ShowBudget:   true,
+		CtxDir:       t.TempDir(),
+		CtxFileExt:   ".json",
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Thinking: "Хм, подумаем..."})
+			fn(api.GenerateResponse{Response: "Чистый "})
+			fn(api.GenerateResponse{Response: "ответ"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	// Первый ход вызывает автосохранение — его сообщение тоже не должно попасть в вывод
+	chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}
+
+	out := captureStdout(t, func() {
+		if err := chat.sendMessage(chat.session.Messages); err != nil {
+			t.Errorf("sendMessage() unexpected error: %v", err)
+		}
+	})
+
+	if out != "Чистый ответ" {
+		t.Errorf("bare output = %q, want exactly %q", out, "Чистый ответ")
+	}
+}
+
+func TestChat_chatLoop_turnSeparator(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	tests := []struct {
+		name      string
+		separator string
+		bare      bool
+		want      int
+	}{
+		{"disabled by default", "", false, 0},
+		{"printed after each turn", "-----", false, 2},
+		{"suppressed in bare mode", "-----", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, TurnSeparator: tt.separator, Bare: tt.bare}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					return fn(api.GenerateResponse{Response: "OK"})
+				},
+			}
+			chat := newTestChat(client, cfg)
+
+			output := captureStdout(t, func() {
+				chat.chatLoop(strings.NewReader("first\nsecond\nexit\n"))
+			})
+
+			if got := strings.Count(output, "-----\n"); got != tt.want {
+				t.Errorf("separator printed %d times, want %d; output:\n%s", got, tt.want, output)
+			}
+		})
+	}
+}
+
+func TestChat_chatLoop_save(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		calls int
+	}{
+		{"turn not yet autosaved", "first\nsecond\nthird\n/SAVE\nexit\n", 3},
+		{"empty session", "/save\nexit\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+			var calls int
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					calls++
+					return fn(api.GenerateResponse{Response: "OK"})
+				},
+			}
+			chat := newTestChat(client, cfg)
+			var out bytes.Buffer
+			chat.SetOutput(&out)
+
+			captureStdout(t, func() {
+				chat.chatLoop(strings.NewReader(tt.input))
+			})
+
+			if calls != tt.calls {
+				t.Errorf("Generate called %d times, want %d", calls, tt.calls)
+			}
+			if len(chat.session.Messages) != 2*tt.calls {
+				t.Errorf("messages = %d, want %d: /save must not be sent as a message", len(chat.session.Messages), 2*tt.calls)
+			}
+			if !strings.Contains(out.String(), "💾 Сессия сохранена") || strings.Contains(out.String(), "Ошибка") {
+				t.Errorf("output should confirm the save without errors:\n%s", out.String())
+			}
+
+			loaded, err := session.NewChatSession("testuser", cfg)
+			if err != nil {
+				t.Fatalf("reloading session: %v", err)
+			}
+			if len(loaded.Messages) != 2*tt.calls {
+				t.Errorf("saved messages = %d, want %d", len(loaded.Messages), 2*tt.calls)
+			}
+		})
+	}
+}
+
+func TestChat_RunOnce(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Bare: true}
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Кратко: "})
+			return fn(api.GenerateResponse{Response: "всё хорошо"})
+		},
+	}
+	chat := newTestChat(client, cfg)
+	var out bytes.Buffer
+	chat.SetOutput(&out)
+
+	if err := chat.RunOnce("summarize this"); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if out.String() != "Кратко: всё хорошо\n" {
+		t.Errorf("output = %q, want only the streamed answer", out.String())
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if len(loaded.Messages) != 2 || loaded.Messages[0].Content != "summarize this" {
+		t.Errorf("saved messages = %+v, want the question and the answer", loaded.Messages)
+	}
+}
+
+func TestChat_RunOnce_error(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Bare: true}
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			return fmt.Errorf("connection refused")
+		},
+	}
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+
+	if err := chat.RunOnce("Hi"); !stderrors.Is(err, errors.ErrMessageSend) {
+		t.Errorf("RunOnce() error = %v, want ErrMessageSend", err)
+	}
+}
+
+func TestChat_chatLoop_maxTurns(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, MaxTurns: 2}
+	var calls int
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			calls++
+			return fn(api.GenerateResponse{Response: "OK"})
+		},
+	}
+	chat := newTestChat(client, cfg)
+	var out bytes.Buffer
+	chat.SetOutput(&out)
+
+	chat.chatLoop(strings.NewReader("first\nsecond\nthird\nfourth\n"))
+
+	if calls != 2 {
+		t.Errorf("Generate called %d times, want 2", calls)
+	}
+	if !strings.Contains(out.String(), "MAX_TURNS=2") {
+		t.Errorf("output should announce the turn limit:\n%s", out.String())
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if len(loaded.Messages) != 4 {
+		t.Errorf("saved messages = %d, want 4", len(loaded.Messages))
+	}
+}
+
+func TestStripPrefill(t *testing.T) {
+	const prefill = "Хорошо, давайте разберем ваш вопрос. "
+
+	tests := []struct {
+		name     string
+		response string
+		prefill  string
+		want     string
+	}{
+		{"with prefill", "Хорошо, давайте разберем ваш вопрос. Ответ.", prefill, "Ответ."},
+		{"leading whitespace", "\n Хорошо, давайте разберем ваш вопрос.\nОтвет.", prefill, "Ответ."},
+		{"without prefill", "Ответ.", prefill, "Ответ."},
+		{"prefill in the middle", "Ответ. Хорошо, давайте разберем ваш вопрос.", prefill, "Ответ. Хорошо, давайте разберем ваш вопрос."},
+		{"empty prefill", "Ответ.", "", "Ответ."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripPrefill(tt.response, tt.prefill); got != tt.want {
+				t.Errorf("stripPrefill(%q) = %q, want %q", tt.response, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_prefillToggle_savedContentConsistent(t *testing.T) {
+	cfg := &config.Config{
+		CtxDir:           t.TempDir(),
+		CtxFileExt:       ".json",
+		CtxSizeLimit:     10,
+		AssistantPrefill: "Хорошо, давайте разберем ваш вопрос. ",
+	}
+
+	// Модель послушно начинает ответ с префилла, только если её об этом попросили
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			if strings.Contains(req.Prompt, "Начни свой ответ с фразы") {
+				fn(api.GenerateResponse{Response: cfg.AssistantPrefill})
+			}
+			return fn(api.GenerateResponse{Response: "Ответ."})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	for _, usePrefill := range []bool{true, false, true} {
+		cfg.UseAssistantPrefill = usePrefill
+		if err := chat.processUserInput("Вопрос"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+	}
+
+	for i, msg := range chat.session.Messages {
+		if msg.IsUser() {
+			continue
+		}
+		if msg.Content != "Ответ." {
+			t.Errorf("message[%d] content = %q, want %q regardless of prefill toggling", i, msg.Content, "Ответ.")
+		}
+	}
+}
+
+func TestTidyWhitespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"clean response unchanged", "Первый абзац.\n\nВторой абзац.", "Первый абзац.\n\nВторой абзац."},
+		{"surrounding blank lines", "\n\n  Ответ.  \n\n\n", "Ответ."},
+		{"blank line runs collapsed", "A\n\n\n\nB\n \n\t\n\nC", "A\n\nB\n\nC"},
+		{"single newlines kept", "строка 1\nстрока 2", "строка 1\nстрока 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tidyWhitespace(tt.content); got != tt.want {
+				t.Errorf("tidyWhitespace(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_addAIResponse_normalizeWhitespace(t *testing.T) {
+	const padded = "\n\nОтвет.\n\n\n\nЕщё абзац.\n\n"
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{"enabled", true, "Ответ.\n\nЕщё абзац."},
+		{"disabled", false, padded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{NormalizeWhitespace: tt.enabled})
+			chat.addAIResponse(padded, "")
+
+			if got := chat.session.Messages[0].Content; got != tt.want {
+				t.Errorf("saved content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_SetOutput_capturesStream(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10}
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			for _, chunk := range []string{"Привет", ", ", "мир!"} {
+				if err := fn(api.GenerateResponse{Response: chunk}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	var buf bytes.Buffer
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&buf)
+
+	stdout := captureStdout(t, func() {
+		if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+			t.Fatalf("sendMessage() unexpected error: %v", err)
+		}
+	})
+
+	if got := buf.String(); got != "AI: Привет, мир!" {
+		t.Errorf("writer captured %q, want %q", got, "AI: Привет, мир!")
+	}
+	if stdout != "" {
+		t.Errorf("nothing should be written to stdout, got %q", stdout)
+	}
+}
@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"agent/internal/model"
+	"fmt"
+	"time"
+)
+
+// ClearHistory удаляет все сообщения текущей сессии и сохраняет её пустой.
+// Имя пользователя, заголовок, заметки и системный промпт сессии остаются.
+func (c *Chat) ClearHistory() error {
+	c.session.Messages = []model.Message{}
+	c.session.Updated = time.Now()
+	c.cleared = true
+
+	return c.saveSession()
+}
+
+// clearHistory обрабатывает /clear: после подтверждения начинает разговор
+// заново, ничего не отправляя модели.
+func (c *Chat) clearHistory() error {
+	if !c.confirm(fmt.Sprintf("🧹 Удалить всю историю сессии (%d сообщений)", len(c.session.Messages))) {
+		fmt.Fprintln(c.output(), "Отменено")
+		return nil
+	}
+	if err := c.ClearHistory(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.output(), "🧹 История очищена")
+	return nil
+}
@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"agent/internal/session"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_ClearHistory(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.Notes = "заметка"
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+	}
+	before := chat.session.Updated
+
+	if err := chat.ClearHistory(); err != nil {
+		t.Fatalf("ClearHistory() error = %v", err)
+	}
+
+	if len(chat.session.Messages) != 0 {
+		t.Errorf("messages = %d, want 0", len(chat.session.Messages))
+	}
+	if !chat.session.Updated.After(before) {
+		t.Error("Updated should change after clearing")
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if len(loaded.Messages) != 0 || loaded.Notes != "заметка" {
+		t.Errorf("saved session = %d messages, notes %q; want an empty history with notes kept", len(loaded.Messages), loaded.Notes)
+	}
+}
+
+func TestChat_clearCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    int
+	}{
+		{"/clear", 2}, // без подтверждения история остаётся
+		{"/CLEAR!", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					t.Error("/clear must not call the model")
+					return nil
+				},
+			}
+			chat := newTestChat(client, cfg)
+			var output strings.Builder
+			chat.SetOutput(&output)
+			chat.session.Messages = []model.Message{
+				{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+				{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+			}
+
+			if handled, err := chat.handleCommand(tt.command); !handled || err != nil {
+				t.Fatalf("handleCommand(%q) = %v, %v", tt.command, handled, err)
+			}
+			if len(chat.session.Messages) != tt.want {
+				t.Errorf("messages = %d, want %d", len(chat.session.Messages), tt.want)
+			}
+			if cleared := strings.Contains(output.String(), "🧹 История очищена"); cleared != (tt.want == 0) {
+				t.Errorf("output = %q", output.String())
+			}
+		})
+	}
+}
+
+func TestChat_ClearHistory_noAutosaveOnFirstTurn(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			return fn(api.GenerateResponse{Response: "OK"})
+		},
+	}
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+	}
+
+	if err := chat.ClearHistory(); err != nil {
+		t.Fatalf("ClearHistory() error = %v", err)
+	}
+	if err := chat.processUserInput("Новый вопрос"); err != nil {
+		t.Fatalf("processUserInput() error = %v", err)
+	}
+
+	if strings.Contains(output.String(), "Автосохранение") || chat.autosaves != 0 {
+		t.Errorf("first turn after /clear should not autosave, output:\n%s", output.String())
+	}
+}
@@ -0,0 +1,243 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// handleCommand выполняет slash-команду. Возвращает true, если ввод был
+// распознан как команда и не должен отправляться модели.
+func (c *Chat) handleCommand(input string) (bool, error) {
+	if !strings.HasPrefix(input, "/") {
+		return false, nil
+	}
+
+	name, args, _ := strings.Cut(input, " ")
+	args = strings.TrimSpace(args)
+
+	// «!» в конце имени команды снимает вопрос о подтверждении
+	if forced, ok := strings.CutSuffix(name, "!"); ok {
+		name = forced
+		c.confirmed = true
+		defer func() { c.confirmed = false }()
+	}
+
+	switch strings.ToLower(name) {
+	case "/cls", "/clear-screen":
+		c.clearScreen()
+	case "/config":
+		c.showConfig(args)
+	case "/set":
+		return true, c.setConfig(args)
+	case "/save":
+		return true, c.save()
+	case "/save-profile":
+		return true, c.saveProfile(args)
+	case "/get":
+		return true, c.getConfig(args)
+	case "/history":
+		return true, c.history(args)
+	case "/last":
+		c.showLastResponse()
+	case "/retry", "/regenerate":
+		if err := c.dropLastAnswer(); err != nil {
+			return true, err
+		}
+		c.rampTemperature()
+		return true, c.retry()
+	case "/regen":
+		return true, c.regen(args)
+	case "/again":
+		return true, c.again()
+	case "/skip-think":
+		return true, c.skipThink()
+	case "/system":
+		return true, c.setSystemPrompt(args)
+	case "/once-system":
+		return true, c.setOnceSystem(args)
+	case "/prune":
+		return true, c.prune(args)
+	case "/clear":
+		return true, c.clearHistory()
+	case "/delete-msg":
+		return true, c.deleteMessage(args)
+	case "/title":
+		return true, c.title(args)
+	case "/note":
+		return true, c.note(args)
+	case "/notes":
+		c.showNotes()
+	case "/category":
+		return true, c.category(args)
+	case "/image":
+		return true, c.attachImage(args)
+	case "/export":
+		return true, c.export(args)
+	case "/sessions":
+		return true, c.sessions(args)
+	case "/doctor":
+		c.doctor()
+	case "/verbose":
+		return true, c.verbose(args)
+	case "/whoami":
+		c.whoami()
+	case "/info":
+		c.info()
+	case "/usage":
+		return true, c.usage()
+	case "/cache":
+		return true, c.cache(args)
+	case "/replay":
+		return true, c.replay()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *Chat) clearScreen() {
+	if !colorsEnabled() {
+		return
+	}
+	fmt.Fprint(c.output(), clearScreenSeq)
+}
+
+func (c *Chat) showConfig(args string) {
+	if strings.EqualFold(args, "source") {
+		c.cfg.DisplaySources()
+		return
+	}
+	c.cfg.DisplayConfig()
+}
+
+// setConfig обрабатывает «/set <ключ> <значение>». Изменения действуют до
+// конца работы и в .env не записываются.
+func (c *Chat) setConfig(args string) error {
+	key, value, _ := strings.Cut(args, " ")
+	if key == "" {
+		return fmt.Errorf("%w: использование /set <ключ> <значение>", errors.ErrInvalidOption)
+	}
+	if err := c.cfg.Set(key, value); err != nil {
+		return err
+	}
+
+	current, _ := c.cfg.Get(key)
+	fmt.Fprintf(c.output(), "⚙️  %s = %s\n", config.ResolveKey(key), current)
+	return nil
+}
+
+// getConfig показывает значение одной настройки, а без аргумента — все.
+func (c *Chat) getConfig(args string) error {
+	if args == "" {
+		c.cfg.DisplaySources()
+		return nil
+	}
+
+	value, err := c.cfg.Get(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.output(), "⚙️  %s = %s\n", config.ResolveKey(args), value)
+	return nil
+}
+
+// save сразу сохраняет сессию, не дожидаясь автосохранения, — например,
+// перед тем как закрыть терминал посреди разговора.
+func (c *Chat) save() error {
+	if err := c.saveSession(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.output(), "💾 Сессия сохранена: %s (%d сообщений)\n", c.session.FilePath(), len(c.session.Messages))
+	return nil
+}
+
+func (c *Chat) showLastResponse() {
+	if c.lastResponse == "" {
+		fmt.Fprintln(c.output(), "📭 В этом запуске ещё не было ответов")
+		return
+	}
+	fmt.Fprintln(c.output(), c.lastResponse)
+}
+
+// retry отбрасывает последний ответ ассистента и заново генерирует ответ
+// на последний вопрос пользователя. Температуру не меняет: повышение по
+// TEMPERATURE_STEP засчитывается только командой /retry.
+func (c *Chat) retry() error {
+	if err := c.dropLastAnswer(); err != nil {
+		return err
+	}
+
+	defer func() { c.onceSystem = "" }()
+	return c.sendMessage(c.session.Messages)
+}
+
+// dropLastAnswer убирает из сессии последний ответ ассистента, чтобы история
+// заканчивалась вопросом пользователя. Без такого вопроса возвращает
+// ErrNothingToRetry.
+func (c *Chat) dropLastAnswer() error {
+	messages := c.session.Messages
+	if n := len(messages); n > 0 && !messages[n-1].IsUser() {
+		messages = messages[:n-1]
+	}
+	if len(messages) == 0 || !messages[len(messages)-1].IsUser() {
+		return errors.ErrNothingToRetry
+	}
+
+	c.session.Messages = messages
+	return nil
+}
+
+// skipThink повторяет последний ответ с отключёнными размышлениями —
+// для случаев, когда модель слишком долго рассуждает. Настройка
+// MODEL_THINK_VALUE при этом не меняется.
+func (c *Chat) skipThink() error {
+	c.turnNoThink = true
+	defer func() { c.turnNoThink = false }()
+	return c.retry()
+}
+
+// again отправляет последний вопрос пользователя ещё раз отдельным ходом,
+// сохраняя предыдущий ответ, чтобы ответы можно было сравнить.
+func (c *Chat) again() error {
+	for i := len(c.session.Messages) - 1; i >= 0; i-- {
+		if msg := c.session.Messages[i]; msg.IsUser() {
+			return c.processUserInput(msg.Content)
+		}
+	}
+	return errors.ErrNothingToRetry
+}
+
+// setSystemPrompt показывает или меняет системный промпт текущей сессии.
+// «/system reset» возвращает промпт из конфигурации.
+func (c *Chat) setSystemPrompt(args string) error {
+	switch {
+	case args == "":
+		fmt.Fprintf(c.output(), "🧭 Системный промпт: %s\n", c.systemPrompt())
+		return nil
+	case strings.EqualFold(args, "reset"):
+		c.session.SystemPrompt = ""
+		fmt.Fprintln(c.output(), "🧭 Системный промпт сброшен к значению из конфигурации")
+	default:
+		c.session.SystemPrompt = args
+		fmt.Fprintln(c.output(), "🧭 Системный промпт сессии обновлён")
+	}
+
+	c.session.Updated = time.Now()
+	return c.saveSession()
+}
+
+// setOnceSystem задаёт системный промпт только для следующего запроса —
+// например, разовое требование к формату ответа. Затем снова действует
+// промпт сессии или конфигурации.
+func (c *Chat) setOnceSystem(args string) error {
+	if args == "" {
+		return fmt.Errorf("%w: использование /once-system <текст>", errors.ErrInvalidOption)
+	}
+	c.onceSystem = args
+	fmt.Fprintln(c.output(), "🧭 Системный промпт заменён только для следующего запроса")
+	return nil
+}
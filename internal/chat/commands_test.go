@@ -0,0 +1,338 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_handleCommand_clearScreen(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Hello", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Hi!", Timestamp: time.Now()},
+	}
+	updated := chat.session.Updated
+
+	for _, input := range []string{"/cls", "/clear-screen", "/CLS"} {
+		t.Run(input, func(t *testing.T) {
+			handled, err := chat.handleCommand(input)
+			if err != nil {
+				t.Fatalf("handleCommand(%q) unexpected error: %v", input, err)
+			}
+			if !handled {
+				t.Errorf("handleCommand(%q) should be recognized as a command", input)
+			}
+			if len(chat.session.Messages) != 2 {
+				t.Errorf("session messages = %d, want 2 (session must stay intact)", len(chat.session.Messages))
+			}
+			if !chat.session.Updated.Equal(updated) {
+				t.Error("session.Updated should not change on clear screen")
+			}
+		})
+	}
+}
+
+func TestChat_handleCommand_notACommand(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+
+	for _, input := range []string{"hello", "/unknown", "cls"} {
+		if handled, _ := chat.handleCommand(input); handled {
+			t.Errorf("handleCommand(%q) should not be handled", input)
+		}
+	}
+}
+
+func TestChat_handleCommand_config(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{ThinkValue: &api.ThinkValue{Value: false}})
+
+	for _, input := range []string{"/config", "/config source"} {
+		handled, err := chat.handleCommand(input)
+		if err != nil {
+			t.Fatalf("handleCommand(%q) unexpected error: %v", input, err)
+		}
+		if !handled {
+			t.Errorf("handleCommand(%q) should be recognized as a command", input)
+		}
+	}
+}
+
+func TestChat_setAndGetConfig(t *testing.T) {
+	cfg := &config.Config{ModelName: "llama3", Temperature: 0.1, ThinkValue: &api.ThinkValue{Value: false}}
+	chat := newTestChat(&mockAIClient{}, cfg)
+
+	output := captureStdout(t, func() {
+		if handled, err := chat.handleCommand("/set temp 0.9"); !handled || err != nil {
+			t.Fatalf("handleCommand(/set temp 0.9) = %v, %v", handled, err)
+		}
+	})
+	if cfg.Temperature != 0.9 {
+		t.Errorf("Temperature = %v, want 0.9", cfg.Temperature)
+	}
+	if !strings.Contains(output, "TEMPERATURE = 0.9") {
+		t.Errorf("/set printed %q", output)
+	}
+
+	if _, err := chat.handleCommand("/set system Отвечай кратко и по делу"); err != nil {
+		t.Fatalf("/set system error = %v", err)
+	}
+	if cfg.SystemPrompt != "Отвечай кратко и по делу" {
+		t.Errorf("SystemPrompt = %q, want the full value with spaces", cfg.SystemPrompt)
+	}
+
+	if _, err := chat.handleCommand("/set temp горячо"); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("/set with invalid value error = %v, want ErrInvalidOption", err)
+	}
+	if _, err := chat.handleCommand("/set"); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("/set without key error = %v, want ErrInvalidOption", err)
+	}
+
+	output = captureStdout(t, func() {
+		if _, err := chat.handleCommand("/get model"); err != nil {
+			t.Fatalf("/get model error = %v", err)
+		}
+	})
+	if !strings.Contains(output, "MODEL_NAME = llama3") {
+		t.Errorf("/get model printed %q", output)
+	}
+}
+
+func TestChat_LastResponse(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	replies := []string{"Первый ответ", "Второй ответ"}
+	var calls int
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: replies[calls]})
+			calls++
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	if chat.LastResponse() != "" {
+		t.Errorf("LastResponse() = %q, want empty before any generation", chat.LastResponse())
+	}
+
+	chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Q", Timestamp: time.Now()}}
+	if err := chat.sendMessage(chat.session.Messages); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+	if chat.LastResponse() != "Первый ответ" {
+		t.Errorf("LastResponse() = %q, want %q", chat.LastResponse(), "Первый ответ")
+	}
+
+	handled, err := chat.handleCommand("/retry")
+	if !handled || err != nil {
+		t.Fatalf("handleCommand(/retry) = %v, %v", handled, err)
+	}
+	if chat.LastResponse() != "Второй ответ" {
+		t.Errorf("LastResponse() after /retry = %q, want %q", chat.LastResponse(), "Второй ответ")
+	}
+
+	if len(chat.session.Messages) != 2 {
+		t.Fatalf("session messages = %d, want 2 (retry replaces the answer)", len(chat.session.Messages))
+	}
+	if chat.session.Messages[1].Content != "Второй ответ" {
+		t.Errorf("saved answer = %q, want %q", chat.session.Messages[1].Content, "Второй ответ")
+	}
+}
+
+func TestChat_retry_nothingToRetry(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: 10})
+
+	if err := chat.retry(); err != errors.ErrNothingToRetry {
+		t.Errorf("retry() on empty session error = %v, want ErrNothingToRetry", err)
+	}
+}
+
+func TestChat_skipThink(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, ThinkValue: &api.ThinkValue{Value: true}}
+
+	var requests []*api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			requests = append(requests, req)
+			return fn(api.GenerateResponse{Response: fmt.Sprintf("Ответ %d", len(requests))})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Долгий ответ", Timestamp: time.Now()},
+	}
+
+	if handled, err := chat.handleCommand("/skip-think"); !handled || err != nil {
+		t.Fatalf("handleCommand(/skip-think) = %v, %v", handled, err)
+	}
+	if err := chat.processUserInput("Следующий вопрос"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+
+	if requests[0].Think == nil || requests[0].Think.Bool() {
+		t.Errorf("retry request Think = %v, want false", requests[0].Think)
+	}
+	if !requests[1].Think.Bool() {
+		t.Errorf("next request Think = %v, want configured true", requests[1].Think)
+	}
+	if !cfg.ThinkValue.Bool() {
+		t.Error("configured think value must not change")
+	}
+	if len(chat.session.Messages) != 4 || chat.session.Messages[1].Content != "Ответ 1" {
+		t.Errorf("retry should replace the previous answer, got %+v", chat.session.Messages)
+	}
+}
+
+func TestChat_again(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var prompts []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompts = append(prompts, req.Prompt)
+			fn(api.GenerateResponse{Response: "Ответ B"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Расскажи шутку", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Ответ A", Timestamp: time.Now()},
+	}
+
+	handled, err := chat.handleCommand("/again")
+	if !handled || err != nil {
+		t.Fatalf("handleCommand(/again) = %v, %v", handled, err)
+	}
+
+	if len(prompts) != 1 {
+		t.Fatalf("expected one generation, got %d", len(prompts))
+	}
+
+	wantRoles := []string{model.RoleUser, model.RoleAssistant, model.RoleUser, model.RoleAssistant}
+	if len(chat.session.Messages) != len(wantRoles) {
+		t.Fatalf("session messages = %d, want %d", len(chat.session.Messages), len(wantRoles))
+	}
+	for i, role := range wantRoles {
+		if chat.session.Messages[i].Role != role {
+			t.Errorf("message[%d].Role = %q, want %q", i, chat.session.Messages[i].Role, role)
+		}
+	}
+
+	if chat.session.Messages[2].Content != "Расскажи шутку" {
+		t.Errorf("repeated question = %q, want %q", chat.session.Messages[2].Content, "Расскажи шутку")
+	}
+	if chat.session.Messages[1].Content != "Ответ A" || chat.session.Messages[3].Content != "Ответ B" {
+		t.Error("both answers should be kept side by side in history")
+	}
+}
+
+func TestChat_again_noUserMessage(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: 10})
+
+	if err := chat.again(); err != errors.ErrNothingToRetry {
+		t.Errorf("again() on empty session error = %v, want ErrNothingToRetry", err)
+	}
+}
+
+func TestChat_systemPrompt_sessionOverridesConfig(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		CtxDir:       t.TempDir(),
+		CtxFileExt:   ".json",
+		SystemPrompt: "global prompt",
+	}
+
+	var systems []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			systems = append(systems, req.System)
+			fn(api.GenerateResponse{Response: "OK"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+
+	steps := []struct {
+		command string
+		want    string
+	}{
+		{"", "global prompt"},
+		{"/system session prompt", "session prompt"},
+		{"/system reset", "global prompt"},
+	}
+
+	for _, step := range steps {
+		if step.command != "" {
+			if handled, err := chat.handleCommand(step.command); !handled || err != nil {
+				t.Fatalf("handleCommand(%q) = %v, %v", step.command, handled, err)
+			}
+		}
+		if err := chat.processUserInput("Hi"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+		if got := systems[len(systems)-1]; got != step.want {
+			t.Errorf("after %q request System = %q, want %q", step.command, got, step.want)
+		}
+	}
+}
+
+func TestChat_onceSystem(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		CtxDir:       t.TempDir(),
+		CtxFileExt:   ".json",
+		SystemPrompt: "global prompt",
+	}
+
+	var systems []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			systems = append(systems, req.System)
+			return fn(api.GenerateResponse{Response: "OK"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&strings.Builder{})
+	chat.session.SystemPrompt = "session prompt"
+
+	if handled, err := chat.handleCommand("/once-system Ответь одной строкой"); !handled || err != nil {
+		t.Fatalf("handleCommand(/once-system) = %v, %v", handled, err)
+	}
+	for range 2 {
+		if err := chat.processUserInput("Hi"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+	}
+
+	want := []string{"Ответь одной строкой", "session prompt"}
+	if len(systems) != len(want) {
+		t.Fatalf("requests = %d, want %d", len(systems), len(want))
+	}
+	for i := range want {
+		if systems[i] != want[i] {
+			t.Errorf("request %d System = %q, want %q", i+1, systems[i], want[i])
+		}
+	}
+	if chat.session.SystemPrompt != "session prompt" {
+		t.Errorf("session system prompt changed to %q", chat.session.SystemPrompt)
+	}
+
+	if err := chat.setOnceSystem(""); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("setOnceSystem(\"\") error = %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// confirm задаёт вопрос «да/нет» перед разрушительной командой и читает
+// ответ из ввода диалога. На непонятный ответ вопрос повторяется, пустой
+// ответ означает «нет». Подтверждение не спрашивается, если команда введена
+// с «!» (например, /prune! 30d) или программа запущена с --yes. Без
+// интерактивного ввода действие не подтверждается.
+func (c *Chat) confirm(prompt string) bool {
+	if c.confirmed || c.cfg.AssumeYes {
+		return true
+	}
+	if c.input == nil {
+		return false
+	}
+
+	fmt.Fprintf(c.output(), "%s? [y/N]: ", prompt)
+	for c.input.Scan() {
+		switch strings.ToLower(strings.TrimSpace(c.input.Text())) {
+		case "y", "yes", "д", "да":
+			return true
+		case "", "n", "no", "н", "нет":
+			return false
+		}
+		fmt.Fprint(c.output(), "Ответьте y или n: ")
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_confirm(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"yes", "y\n", true},
+		{"russian yes", "да\n", true},
+		{"no", "n\n", false},
+		{"empty answer means no", "\n", false},
+		{"invalid then yes", "может быть\ny\n", true},
+		{"invalid then no", "?\nнет\n", false},
+		{"end of input", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{})
+			var buf bytes.Buffer
+			chat.SetOutput(&buf)
+			chat.input = bufio.NewScanner(strings.NewReader(tt.input))
+
+			if got := chat.confirm("Удалить"); got != tt.want {
+				t.Errorf("confirm() = %v, want %v (output %q)", got, tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestChat_confirm_bypass(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		assumeYes bool
+		wantKept  int
+	}{
+		{"declined without input", "/prune 30d", false, 2},
+		{"exclamation suffix", "/prune! 30d", false, 1},
+		{"--yes flag", "/prune 30d", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", AssumeYes: tt.assumeYes}
+			chat := newTestChat(&mockAIClient{}, cfg)
+			chat.session.Messages = []model.Message{
+				{Role: model.RoleUser, Content: "давно", Timestamp: time.Now().AddDate(-1, 0, 0)},
+				{Role: model.RoleAssistant, Content: "сейчас", Timestamp: time.Now()},
+			}
+
+			captureStdout(t, func() {
+				if handled, err := chat.handleCommand(tt.command); !handled || err != nil {
+					t.Fatalf("handleCommand(%q) = %v, %v", tt.command, handled, err)
+				}
+			})
+
+			if got := len(chat.session.Messages); got != tt.wantKept {
+				t.Errorf("messages after %q = %d, want %d", tt.command, got, tt.wantKept)
+			}
+			if chat.confirmed {
+				t.Error("confirmed flag should be reset after the command")
+			}
+		})
+	}
+}
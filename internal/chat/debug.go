@@ -0,0 +1,14 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+)
+
+// debugf печатает отладочное сообщение в stderr, если включён DEBUG.
+func (c *Chat) debugf(format string, args ...any) {
+	if !c.cfg.Debug {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "🐞 "+format+"\n", args...)
+}
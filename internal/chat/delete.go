@@ -0,0 +1,58 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// deleteMessage обрабатывает «/delete-msg [номер] [pair]». Без аргументов
+// показывает сообщения с номерами; с номером удаляет сообщение (и парное
+// ему при pair), сдвигая следующие, и сохраняет сессию.
+func (c *Chat) deleteMessage(args string) error {
+	if args == "" {
+		c.listMessages()
+		return nil
+	}
+
+	fields := strings.Fields(args)
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 1 || n > len(c.session.Messages) || len(fields) > 2 {
+		return fmt.Errorf("%w: /delete-msg %s, ожидается номер сообщения от 1 до %d и необязательное pair",
+			errors.ErrInvalidOption, args, len(c.session.Messages))
+	}
+	pair := len(fields) == 2
+	if pair && !strings.EqualFold(fields[1], "pair") {
+		return fmt.Errorf("%w: /delete-msg %s, неизвестный параметр %q", errors.ErrInvalidOption, args, fields[1])
+	}
+
+	what := fmt.Sprintf("сообщение №%d", n)
+	if pair {
+		what += " вместе с парным"
+	}
+	if !c.confirm("🗑️  Удалить " + what) {
+		fmt.Fprintln(c.output(), "Отменено")
+		return nil
+	}
+
+	removed := c.session.DeleteMessage(n-1, pair)
+	fmt.Fprintf(c.output(), "🗑️  Удалено сообщений: %d, осталось %d\n", removed, len(c.session.Messages))
+	return c.saveSession()
+}
+
+// listMessages печатает сообщения сессии с номерами для /delete-msg.
+func (c *Chat) listMessages() {
+	if len(c.session.Messages) == 0 {
+		fmt.Fprintln(c.output(), "📭 История пуста")
+		return
+	}
+	for i, msg := range c.session.Messages {
+		icon := "🤖"
+		if msg.IsUser() {
+			icon = "👤"
+		}
+		content := strings.ReplaceAll(c.truncateContent(msg.Content, 80), "\n", " ")
+		fmt.Fprintf(c.output(), "  %d. %s %s\n", i+1, icon, content)
+	}
+}
@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func deleteTestChat(t *testing.T) *Chat {
+	t.Helper()
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.SetOutput(&strings.Builder{})
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Q2", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A2", Timestamp: time.Now()},
+	}
+	return chat
+}
+
+func contents(messages []model.Message) string {
+	parts := make([]string, len(messages))
+	for i, msg := range messages {
+		parts[i] = msg.Content
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestChat_deleteMessage(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"/delete-msg! 2", "Q1,Q2,A2"},
+		{"/delete-msg! 3 pair", "Q1,A1"},
+		{"/delete-msg! 2 pair", "Q2,A2"},
+		{"/delete-msg! 4 PAIR", "Q1,A1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			chat := deleteTestChat(t)
+			if handled, err := chat.handleCommand(tt.command); !handled || err != nil {
+				t.Fatalf("handleCommand(%q) = %v, %v", tt.command, handled, err)
+			}
+			if got := contents(chat.session.Messages); got != tt.want {
+				t.Errorf("messages = %s, want %s", got, tt.want)
+			}
+
+			loaded, err := session.NewChatSession("testuser", chat.cfg)
+			if err != nil {
+				t.Fatalf("reloading session: %v", err)
+			}
+			if got := contents(loaded.Messages); got != tt.want {
+				t.Errorf("persisted messages = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_deleteMessage_invalid(t *testing.T) {
+	for _, args := range []string{"0", "5", "-1", "abc", "2 both", "2 pair extra"} {
+		chat := deleteTestChat(t)
+		chat.confirmed = true
+		if err := chat.deleteMessage(args); !stderrors.Is(err, errors.ErrInvalidOption) {
+			t.Errorf("deleteMessage(%q) error = %v, want ErrInvalidOption", args, err)
+		}
+		if got := contents(chat.session.Messages); got != "Q1,A1,Q2,A2" {
+			t.Errorf("deleteMessage(%q) changed messages to %s", args, got)
+		}
+	}
+}
+
+func TestChat_deleteMessage_needsConfirmation(t *testing.T) {
+	chat := deleteTestChat(t)
+	if err := chat.deleteMessage("1"); err != nil {
+		t.Fatalf("deleteMessage() error = %v", err)
+	}
+	if got := contents(chat.session.Messages); got != "Q1,A1,Q2,A2" {
+		t.Errorf("message deleted without confirmation: %s", got)
+	}
+}
+
+func TestChat_deleteMessage_list(t *testing.T) {
+	chat := deleteTestChat(t)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	if err := chat.deleteMessage(""); err != nil {
+		t.Fatalf("deleteMessage(\"\") error = %v", err)
+	}
+	if !strings.Contains(output.String(), "  1. 👤 Q1\n") || !strings.Contains(output.String(), "  4. 🤖 A2\n") {
+		t.Errorf("listing = %q, want numbered messages", output.String())
+	}
+}
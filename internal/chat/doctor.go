@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// doctorTimeout ограничивает каждое обращение к серверу при диагностике.
+const doctorTimeout = 5 * time.Second
+
+// DiagnosticsClient — методы клиента Ollama, нужные для /doctor.
+type DiagnosticsClient interface {
+	Heartbeat(ctx context.Context) error
+	List(ctx context.Context) (*api.ListResponse, error)
+}
+
+// CheckResult — итог одной проверки /doctor.
+type CheckResult struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// RunDoctor проверяет доступность Ollama, наличие настроенной модели
+// и возможность записи в директорию чатов.
+func RunDoctor(client DiagnosticsClient, cfg *config.Config) []CheckResult {
+	results := []CheckResult{checkConnectivity(client)}
+	if results[0].OK {
+		results = append(results, checkModelInstalled(client, cfg.ModelName))
+	} else {
+		results = append(results, CheckResult{Name: "Модель " + cfg.ModelName, Detail: "не проверена: сервер недоступен"})
+	}
+	return append(results, checkCtxDirWritable(cfg.CtxDir))
+}
+
+// PrintDoctor выводит результаты проверок и возвращает true, если все прошли.
+func PrintDoctor(results []CheckResult) bool {
+	healthy := true
+	for _, r := range results {
+		mark := "✅"
+		if !r.OK {
+			mark = "❌"
+			healthy = false
+		}
+		fmt.Printf("%s %s: %s\n", mark, r.Name, r.Detail)
+	}
+	return healthy
+}
+
+func checkConnectivity(client DiagnosticsClient) CheckResult {
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	if err := client.Heartbeat(ctx); err != nil {
+		return CheckResult{Name: "Подключение к Ollama", Detail: err.Error()}
+	}
+	return CheckResult{Name: "Подключение к Ollama", OK: true, Detail: "сервер отвечает"}
+}
+
+func checkModelInstalled(client DiagnosticsClient, modelName string) CheckResult {
+	name := "Модель " + modelName
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	list, err := client.List(ctx)
+	if err != nil {
+		return CheckResult{Name: name, Detail: "не удалось получить список моделей: " + err.Error()}
+	}
+
+	want := modelName
+	if !strings.Contains(want, ":") {
+		want += ":latest"
+	}
+	for _, m := range list.Models {
+		if m.Name == modelName || m.Name == want {
+			return CheckResult{Name: name, OK: true, Detail: "установлена"}
+		}
+	}
+	return CheckResult{Name: name, Detail: fmt.Sprintf("не установлена, выполните: ollama pull %s", modelName)}
+}
+
+func checkCtxDirWritable(dir string) CheckResult {
+	name := "Директория чатов " + dir
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return CheckResult{Name: name, Detail: err.Error()}
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return CheckResult{Name: name, Detail: "нет прав на запись: " + err.Error()}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return CheckResult{Name: name, OK: true, Detail: "доступна для записи"}
+}
+
+// doctor выполняет диагностику из чата и показывает итоговые настройки.
+func (c *Chat) doctor() {
+	client, ok := c.client.(DiagnosticsClient)
+	if !ok {
+		fmt.Println("⚠️  Текущий клиент не поддерживает диагностику")
+		return
+	}
+
+	PrintDoctor(RunDoctor(client, c.cfg))
+	fmt.Println()
+	c.cfg.DisplaySources()
+}
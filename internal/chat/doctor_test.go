@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+type mockDiagnosticsClient struct {
+	mockAIClient
+	heartbeatErr error
+	models       []string
+}
+
+func (m *mockDiagnosticsClient) Heartbeat(ctx context.Context) error {
+	return m.heartbeatErr
+}
+
+func (m *mockDiagnosticsClient) List(ctx context.Context) (*api.ListResponse, error) {
+	list := &api.ListResponse{}
+	for _, name := range m.models {
+		list.Models = append(list.Models, api.ListModelResponse{Name: name})
+	}
+	return list, nil
+}
+
+func TestRunDoctor(t *testing.T) {
+	// Директорию нельзя создать внутри обычного файла даже с правами root
+	notADir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notADir, nil, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		client *mockDiagnosticsClient
+		model  string
+		ctxDir string
+		want   []bool
+	}{
+		{
+			name:   "healthy",
+			client: &mockDiagnosticsClient{models: []string{"deepseek-r1:8b"}},
+			model:  "deepseek-r1:8b",
+			ctxDir: t.TempDir(),
+			want:   []bool{true, true, true},
+		},
+		{
+			name:   "model with implicit latest tag",
+			client: &mockDiagnosticsClient{models: []string{"llama3:latest"}},
+			model:  "llama3",
+			ctxDir: t.TempDir(),
+			want:   []bool{true, true, true},
+		},
+		{
+			name:   "model not installed",
+			client: &mockDiagnosticsClient{models: []string{"llama3:latest"}},
+			model:  "deepseek-r1:8b",
+			ctxDir: t.TempDir(),
+			want:   []bool{true, false, true},
+		},
+		{
+			name:   "server unreachable",
+			client: &mockDiagnosticsClient{heartbeatErr: fmt.Errorf("connection refused")},
+			model:  "deepseek-r1:8b",
+			ctxDir: t.TempDir(),
+			want:   []bool{false, false, true},
+		},
+		{
+			name:   "ctx dir not writable",
+			client: &mockDiagnosticsClient{models: []string{"llama3:latest"}},
+			model:  "llama3",
+			ctxDir: filepath.Join(notADir, "chats"),
+			want:   []bool{true, true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := RunDoctor(tt.client, &config.Config{ModelName: tt.model, CtxDir: tt.ctxDir})
+
+			if len(results) != len(tt.want) {
+				t.Fatalf("results = %d, want %d", len(results), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if results[i].OK != want {
+					t.Errorf("check %q OK = %v, want %v (%s)", results[i].Name, results[i].OK, want, results[i].Detail)
+				}
+			}
+
+			var healthy bool
+			captureStdout(t, func() { healthy = PrintDoctor(results) })
+			if wantHealthy := !slices.Contains(tt.want, false); healthy != wantHealthy {
+				t.Errorf("PrintDoctor() = %v, want %v", healthy, wantHealthy)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+const elaborateInstruction = "Твой предыдущий ответ слишком краткий. Раскрой его подробнее."
+
+// tooShort сообщает, что ответ короче MIN_RESPONSE_LEN и его стоит
+// попросить раскрыть. Уточнение запрашивается только один раз.
+func (c *Chat) tooShort(content string) bool {
+	return c.cfg.MinResponseLen > 0 && !c.elaborating &&
+		utf8.RuneCountInString(content) < c.cfg.MinResponseLen
+}
+
+// elaborate повторно отправляет разговор вместе с коротким ответом и
+// просьбой рассказать подробнее. Вопрос и короткий ответ уходят историей,
+// а просьба — текущим сообщением: иначе за вопрос был бы принят сам ответ.
+// В сессию просьба не попадает.
+func (c *Chat) elaborate() error {
+	if !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "\n📝 Ответ короче %d символов, прошу модель раскрыть подробнее\n", c.cfg.MinResponseLen)
+	}
+
+	n := len(c.session.Messages)
+	short := c.session.Messages[n-1].Content
+	request := append(c.session.Messages[:n:n], model.Message{
+		Role:      model.RoleUser,
+		Content:   elaborateInstruction,
+		Timestamp: time.Now(),
+	})
+
+	c.elaborating = true
+	err := c.sendMessage(request)
+	c.elaborating = false
+
+	// Уточнение не удалось, но короткий ответ сохранён: в режиме --json
+	// выводим его, чтобы на вопрос всё равно пришёл ответ
+	if err != nil {
+		if jsonErr := c.replyJSON(short, ""); jsonErr != nil {
+			return jsonErr
+		}
+	}
+	return err
+}
+
+// keptReply возвращает текст ответа, если генерация не дала нового текста:
+// во время уточнения это сохранённый короткий ответ, иначе — пустая строка.
+func (c *Chat) keptReply() string {
+	if !c.elaborating {
+		return ""
+	}
+	return c.session.Messages[len(c.session.Messages)-1].Content
+}
+
+// mergeElaboration объединяет уточнение с коротким ответом перед ним:
+// дописывает его или заменяет им короткий ответ по MIN_RESPONSE_MODE.
+// Возвращает итоговый текст ответа.
+func (c *Chat) mergeElaboration() string {
+	n := len(c.session.Messages)
+	short, extra := c.session.Messages[n-2], c.session.Messages[n-1]
+
+	if c.cfg.MinResponseMode != config.ElaborateReplace {
+		extra.Content = short.Content + "\n\n" + extra.Content
+	}
+	c.session.Messages = append(c.session.Messages[:n-2], extra)
+	return extra.Content
+}
@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_sendMessage_minResponseLen(t *testing.T) {
+	const (
+		short    = "Да."
+		detailed = "Да, потому что кэш уже прогрет и повторный запрос не нужен."
+	)
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{config.ElaborateAppend, short + "\n\n" + detailed},
+		{config.ElaborateReplace, detailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := &config.Config{
+				CtxSizeLimit:    10,
+				CtxDir:          t.TempDir(),
+				CtxFileExt:      ".json",
+				MinResponseLen:  20,
+				MinResponseMode: tt.mode,
+			}
+
+			var prompts []string
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					prompts = append(prompts, req.Prompt)
+					if len(prompts) == 1 {
+						return fn(api.GenerateResponse{Response: short})
+					}
+					return fn(api.GenerateResponse{Response: detailed})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			chat.SetOutput(&bytes.Buffer{})
+			chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Нужен ли повтор?", Timestamp: time.Now()}}
+
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() error = %v", err)
+			}
+
+			if len(prompts) != 2 {
+				t.Fatalf("Generate called %d times, want 2", len(prompts))
+			}
+			if !strings.Contains(prompts[1], short) || !strings.Contains(prompts[1], elaborateInstruction) {
+				t.Errorf("elaboration prompt should include the short answer and the instruction:\n%s", prompts[1])
+			}
+
+			messages := chat.session.Messages
+			if len(messages) != 2 {
+				t.Fatalf("len(Messages) = %d, want 2", len(messages))
+			}
+			got := messages[1].Content
+			if got != tt.want {
+				t.Errorf("Content = %q, want %q", got, tt.want)
+			}
+			if utf8.RuneCountInString(got) < cfg.MinResponseLen {
+				t.Errorf("final content has %d runes, want at least %d", utf8.RuneCountInString(got), cfg.MinResponseLen)
+			}
+		})
+	}
+}
+
+func TestChat_elaborate_prompt(t *testing.T) {
+	for _, stateless := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stateless=%v", stateless), func(t *testing.T) {
+			cfg := &config.Config{
+				CtxSizeLimit:   10,
+				CtxDir:         t.TempDir(),
+				CtxFileExt:     ".json",
+				MinResponseLen: 20,
+				Stateless:      stateless,
+			}
+
+			var prompts []string
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					prompts = append(prompts, req.Prompt)
+					return fn(api.GenerateResponse{Response: "Да."})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			chat.SetOutput(&bytes.Buffer{})
+			chat.session.Messages = []model.Message{
+				{Role: model.RoleUser, Content: "Старый вопрос", Timestamp: time.Now()},
+				{Role: model.RoleAssistant, Content: "Старый ответ", Timestamp: time.Now()},
+				{Role: model.RoleUser, Content: "Нужен ли повтор?", Timestamp: time.Now()},
+			}
+
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() error = %v", err)
+			}
+			if len(prompts) != 2 {
+				t.Fatalf("Generate called %d times, want 2", len(prompts))
+			}
+
+			prompt := prompts[1]
+			for _, want := range []string{"Пользователь: Нужен ли повтор?\n", "Ассистент: Да.\n"} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("elaboration prompt should contain %q:\n%s", want, prompt)
+				}
+			}
+			if !strings.HasSuffix(prompt, "Текущий вопрос: "+elaborateInstruction) {
+				t.Errorf("elaboration prompt should end with the instruction as the current question:\n%s", prompt)
+			}
+			if strings.Contains(prompt, "Старый вопрос") == stateless {
+				t.Errorf("older history in stateless=%v prompt:\n%s", stateless, prompt)
+			}
+			if last := chat.session.Messages[len(chat.session.Messages)-1]; last.Content == elaborateInstruction {
+				t.Error("elaboration instruction must not be saved to the session")
+			}
+		})
+	}
+}
+
+func TestChat_sendMessage_minResponseLen_asksOnce(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, CtxDir: t.TempDir(), CtxFileExt: ".json", MinResponseLen: 100}
+
+	calls := 0
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			calls++
+			return fn(api.GenerateResponse{Response: "Нет."})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Вопрос", Timestamp: time.Now()}}
+
+	if err := chat.sendMessage(chat.session.Messages); err != nil {
+		t.Fatalf("sendMessage() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Generate called %d times, want 2", calls)
+	}
+	if chat.elaborating {
+		t.Error("elaborating flag should be reset after the re-prompt")
+	}
+}
@@ -0,0 +1,43 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"agent/internal/session"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// export сохраняет сессию в файл: /export html|md|openai <файл>.
+func (c *Chat) export(args string) error {
+	format, path, _ := strings.Cut(args, " ")
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return fmt.Errorf("%w: использование /export html|md|openai <файл>", errors.ErrInvalidOption)
+	}
+
+	var write func(*session.ChatSession, io.Writer) error
+	switch strings.ToLower(format) {
+	case "html":
+		write = session.ExportHTML
+	case "md", "markdown":
+		write = session.ExportMarkdown
+	case "openai":
+		write = session.ExportOpenAIMessages
+	default:
+		return fmt.Errorf("%w: неизвестный формат экспорта %q", errors.ErrInvalidOption, format)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+	}
+	defer file.Close()
+
+	if err := write(c.session, file); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.output(), "📤 Сессия экспортирована в %s\n", path)
+	return nil
+}
@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const formatInstruction = "Верни только корректный JSON, без пояснений и разметки."
+
+// validFormat проверяет ответ на соответствие FORMAT. Без FORMAT подходит
+// любой ответ.
+func (c *Chat) validFormat(content string) bool {
+	if c.cfg.Format != config.FormatJSON {
+		return true
+	}
+	return json.Valid([]byte(strings.TrimSpace(content)))
+}
+
+// reaskFormat повторяет запрос со строгой просьбой вернуть JSON. Неверный
+// ответ в историю не попадает; повторов не больше FORMAT_RETRIES.
+func (c *Chat) reaskFormat(messages []model.Message) error {
+	c.formatRetry++
+	defer func() { c.formatRetry-- }()
+
+	if !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "\n⚠️  Ответ не является корректным JSON, переспрашиваем модель (%d/%d)\n", c.formatRetry, c.cfg.FormatRetries)
+	}
+	return c.sendMessage(messages)
+}
+
+// warnInvalidFormat сообщает, что повторы исчерпаны и ответ сохраняется
+// как есть.
+func (c *Chat) warnInvalidFormat() {
+	fmt.Fprintln(c.output(), "\n⚠️  Ответ так и не стал корректным JSON, сохраняем как есть")
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_sendMessage_formatJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		wantCalls int
+		want      string
+	}{
+		{"re-asks once", 1, 2, `{"answer": 42}`},
+		{"retries disabled", 0, 1, "Ответ: 42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				CtxSizeLimit:  10,
+				CtxDir:        t.TempDir(),
+				CtxFileExt:    ".json",
+				Format:        config.FormatJSON,
+				FormatRetries: tt.retries,
+			}
+
+			var requests []*api.GenerateRequest
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					requests = append(requests, req)
+					if len(requests) == 1 {
+						return fn(api.GenerateResponse{Response: "Ответ: 42"})
+					}
+					return fn(api.GenerateResponse{Response: `{"answer": 42}`})
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			chat.SetOutput(&bytes.Buffer{})
+			chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Сколько?", Timestamp: time.Now()}}
+
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() unexpected error: %v", err)
+			}
+
+			if len(requests) != tt.wantCalls {
+				t.Fatalf("Generate called %d times, want %d", len(requests), tt.wantCalls)
+			}
+			if string(requests[0].Format) != `"json"` {
+				t.Errorf("request Format = %s, want \"json\"", requests[0].Format)
+			}
+			if strings.Contains(requests[0].Prompt, formatInstruction) {
+				t.Error("first request should not contain the stricter instruction")
+			}
+			if tt.wantCalls == 2 && !strings.Contains(requests[1].Prompt, formatInstruction) {
+				t.Errorf("re-ask should contain %q, got %q", formatInstruction, requests[1].Prompt)
+			}
+
+			messages := chat.session.Messages
+			if len(messages) != 2 {
+				t.Fatalf("len(Messages) = %d, want 2 (invalid answer should not be kept)", len(messages))
+			}
+			if messages[1].Content != tt.want {
+				t.Errorf("saved answer = %q, want %q", messages[1].Content, tt.want)
+			}
+			if chat.formatRetry != 0 {
+				t.Errorf("formatRetry = %d after sendMessage, want 0", chat.formatRetry)
+			}
+		})
+	}
+}
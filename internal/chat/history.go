@@ -0,0 +1,29 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strconv"
+)
+
+// defaultHistoryCount — сколько сообщений показывает /history без аргумента.
+const defaultHistoryCount = 10
+
+// history выводит последние N сообщений сессии в порядке DISPLAY_ORDER.
+func (c *Chat) history(args string) error {
+	count := defaultHistoryCount
+	if args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%w: /history %s, ожидается число сообщений больше 0", errors.ErrInvalidOption, args)
+		}
+		count = n
+	}
+
+	if len(c.session.Messages) == 0 {
+		fmt.Fprintln(c.output(), "📭 История пуста")
+		return nil
+	}
+	c.DisplayRecentMessages(c.session.Messages, count)
+	return nil
+}
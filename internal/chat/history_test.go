@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_DisplayRecentMessages_order(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Q2", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A2", Timestamp: time.Now()},
+	}
+
+	tests := []struct {
+		order string
+		count int
+		want  []string
+	}{
+		{config.DisplayOldest, 4, []string{"Q1", "A1", "Q2", "A2"}},
+		{config.DisplayNewest, 4, []string{"A2", "Q2", "A1", "Q1"}},
+		{config.DisplayNewest, 3, []string{"A2", "Q2", "A1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{DisplayOrder: tt.order})
+			var output strings.Builder
+			chat.SetOutput(&output)
+
+			chat.DisplayRecentMessages(messages, tt.count)
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+				got = append(got, line[strings.LastIndex(line, " ")+1:])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("displayed %v, want %v", got, tt.want)
+			}
+			if messages[0].Content != "Q1" || messages[3].Content != "A2" {
+				t.Errorf("DisplayRecentMessages() must not reorder the slice, got %+v", messages)
+			}
+		})
+	}
+}
+
+func TestChat_history(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{DisplayOrder: config.DisplayNewest})
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "старый вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "новый вопрос", Timestamp: time.Now()},
+	}
+
+	if handled, err := chat.handleCommand("/history 2"); !handled || err != nil {
+		t.Fatalf("handleCommand(/history 2) = %v, %v", handled, err)
+	}
+	got := output.String()
+	if strings.Contains(got, "старый вопрос") || strings.Index(got, "новый вопрос") > strings.Index(got, "старый ответ") {
+		t.Errorf("/history 2 printed %q, want the last two messages newest first", got)
+	}
+
+	if err := chat.history("abc"); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("history(abc) error = %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	hookTimeout     = 30 * time.Second
+	hookResponseEnv = "AGENT_RESPONSE"
+)
+
+// CommandRunner запускает внешнюю команду, передавая ей текст ответа.
+// Выделен в тип, чтобы подменять запуск в тестах.
+type CommandRunner func(ctx context.Context, command, input string) error
+
+// execCommand выполняет команду через sh -c: ответ подаётся на stdin
+// и дублируется в переменную окружения AGENT_RESPONSE.
+func execCommand(ctx context.Context, command, input string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), hookResponseEnv+"="+input)
+	return cmd.Run()
+}
+
+// runResponseHook запускает ON_RESPONSE_CMD в фоне, не блокируя диалог.
+// Команда ограничена по времени hookTimeout.
+func (c *Chat) runResponseHook(response string) {
+	command := c.cfg.OnResponseCmd
+	if command == "" {
+		return
+	}
+
+	runner := c.runCommand
+	if runner == nil {
+		runner = execCommand
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+		defer cancel()
+
+		if err := runner(ctx, command, response); err != nil {
+			fmt.Printf("\n⚠️  Ошибка команды ON_RESPONSE_CMD: %v\n", err)
+		}
+	}()
+}
@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_sendMessage_runsResponseHook(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit:  10,
+		OnResponseCmd: "notify-send done",
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Готово!"})
+			return nil
+		},
+	}
+
+	type call struct{ command, input string }
+	calls := make(chan call, 1)
+
+	chat := newTestChat(client, cfg)
+	chat.runCommand = func(ctx context.Context, command, input string) error {
+		calls <- call{command, input}
+		return nil
+	}
+
+	err := chat.sendMessage([]model.Message{
+		{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	select {
+	case got := <-calls:
+		if got.command != "notify-send done" {
+			t.Errorf("hook command = %q, want %q", got.command, "notify-send done")
+		}
+		if got.input != "Готово!" {
+			t.Errorf("hook input = %q, want %q", got.input, "Готово!")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("response hook was not invoked")
+	}
+}
+
+func TestChat_runResponseHook_disabled(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+
+	called := make(chan struct{}, 1)
+	chat.runCommand = func(ctx context.Context, command, input string) error {
+		called <- struct{}{}
+		return nil
+	}
+
+	chat.runResponseHook("response")
+
+	select {
+	case <-called:
+		t.Error("hook should not run when ON_RESPONSE_CMD is empty")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ollama/ollama/api"
+)
+
+// maxImageSize — наибольший размер прикрепляемого изображения.
+const maxImageSize = 20 << 20
+
+// imageTypes — форматы, которые понимают мультимодальные модели Ollama.
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// attachImage читает изображение и прикрепляет его к следующему запросу.
+// В base64 данные кодирует клиент Ollama при отправке (поле images).
+func (c *Chat) attachImage(path string) error {
+	if path == "" {
+		return fmt.Errorf("%w: использование /image <путь>", errors.ErrInvalidImage)
+	}
+
+	image, err := readImage(path)
+	if err != nil {
+		return err
+	}
+
+	c.pendingImages = append(c.pendingImages, image)
+	fmt.Fprintf(c.output(), "🖼️  %s прикреплено к следующему сообщению (%s)\n", filepath.Base(path), formatBytes(int64(len(image))))
+	return nil
+}
+
+// readImage проверяет размер и формат файла по его содержимому.
+func readImage(path string) (api.ImageData, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+	if info.Size() > maxImageSize {
+		return nil, fmt.Errorf("%w: %s больше %s", errors.ErrInvalidImage, path, formatBytes(maxImageSize))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+	if kind := http.DetectContentType(data); !slices.Contains(imageTypes, kind) {
+		return nil, fmt.Errorf("%w: %s имеет тип %s, поддерживаются PNG, JPEG, GIF и WebP", errors.ErrInvalidImage, path, kind)
+	}
+	return api.ImageData(data), nil
+}
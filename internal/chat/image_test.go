@@ -0,0 +1,98 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+// pngHeader — минимальная сигнатура PNG, по которой определяется тип файла.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestChat_attachImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cat.png")
+	image := append(append([]byte{}, pngHeader...), "пиксели"...)
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	cfg := &config.Config{CtxSizeLimit: 10, CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	var requests []*api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			requests = append(requests, req)
+			return fn(api.GenerateResponse{Response: "На картинке кот"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+
+	if handled, err := chat.handleCommand("/image " + path); !handled || err != nil {
+		t.Fatalf("handleCommand(/image) = %v, %v", handled, err)
+	}
+	if err := chat.processUserInput("Что на картинке?"); err != nil {
+		t.Fatalf("processUserInput() error = %v", err)
+	}
+	if err := chat.processUserInput("А теперь без картинки"); err != nil {
+		t.Fatalf("processUserInput() error = %v", err)
+	}
+
+	if len(requests[0].Images) != 1 || !bytes.Equal(requests[0].Images[0], image) {
+		t.Fatalf("request images = %v, want the attached file", requests[0].Images)
+	}
+	encoded, err := json.Marshal(requests[0])
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(encoded), base64.StdEncoding.EncodeToString(image)) {
+		t.Error("serialized request should carry the image as base64")
+	}
+	if len(requests[1].Images) != 0 {
+		t.Errorf("image should be attached to one message only, next request has %d", len(requests[1].Images))
+	}
+	if got := chat.session.Messages[1].Content; got != "На картинке кот" {
+		t.Errorf("reply = %q, want plain text", got)
+	}
+}
+
+func TestReadImage_invalid(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(text, []byte("просто текст"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	large := filepath.Join(dir, "large.png")
+	if err := os.WriteFile(large, append(pngHeader, make([]byte, maxImageSize)...), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr error
+	}{
+		{"not an image", text, errors.ErrInvalidImage},
+		{"too large", large, errors.ErrInvalidImage},
+		{"missing", filepath.Join(dir, "missing.png"), errors.ErrFileRead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readImage(tt.path); !stderrors.Is(err, tt.wantErr) {
+				t.Errorf("readImage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+)
+
+// infoTimeLayout — формат дат в /info; время выводится в местном часовом поясе.
+const infoTimeLayout = "02.01.2006 15:04:05 MST"
+
+// info показывает, когда сессия создана и когда в ней была последняя
+// активность, а также сколько раз она автосохранялась за этот запуск.
+func (c *Chat) info() {
+	out := c.output()
+	fmt.Fprintf(out, "🗓️  Создана: %s\n", formatLocalTime(c.session.Created))
+	fmt.Fprintf(out, "🕒 Последняя активность: %s", formatLocalTime(c.session.Updated))
+	if !c.session.Updated.IsZero() {
+		fmt.Fprintf(out, " (%s назад)", formatElapsed(c.now().Sub(c.session.Updated)))
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "💾 Автосохранений за этот запуск: %d\n", c.autosaves)
+}
+
+func formatLocalTime(t time.Time) string {
+	if t.IsZero() {
+		return "неизвестно"
+	}
+	return t.Local().Format(infoTimeLayout)
+}
+
+// formatElapsed округляет промежуток до секунд, а начиная с часа — до минут.
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	if d >= time.Hour {
+		return d.Round(time.Minute).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_info(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", Bare: true}
+	chat := newTestChat(&mockAIClient{}, cfg)
+
+	created := time.Date(2025, 12, 1, 9, 30, 0, 0, time.UTC)
+	updated := time.Date(2025, 12, 3, 18, 15, 20, 0, time.UTC)
+	chat.session.Created = created
+	chat.session.Updated = updated
+	chat.now = func() time.Time { return updated.Add(90 * time.Minute) }
+
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Привет", Timestamp: updated},
+		{Role: model.RoleAssistant, Content: "Здравствуйте", Timestamp: updated},
+	}
+	chat.autoSave()
+
+	var buf bytes.Buffer
+	chat.SetOutput(&buf)
+	if handled, err := chat.handleCommand("/info"); !handled || err != nil {
+		t.Fatalf("handleCommand(/info) = %v, %v", handled, err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"Создана: " + created.Local().Format(infoTimeLayout),
+		"Последняя активность: " + updated.Local().Format(infoTimeLayout) + " (1h30m0s назад)",
+		"Автосохранений за этот запуск: 1",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("/info output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{-time.Second, "0s"},
+		{42*time.Second + 400*time.Millisecond, "42s"},
+		{3*time.Hour + 20*time.Minute + 40*time.Second, "3h21m0s"},
+	}
+
+	for _, tt := range tests {
+		if got := formatElapsed(tt.d); got != tt.want {
+			t.Errorf("formatElapsed(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
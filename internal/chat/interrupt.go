@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"agent/internal/model"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// interruptWindow — повторный Ctrl+C в течение этого времени после
+// прерывания генерации завершает программу.
+const interruptWindow = 2 * time.Second
+
+// setGeneration запоминает, как отменить идущий запрос к модели; nil —
+// запроса нет. С новым запросом окно повторного Ctrl+C начинается заново:
+// прерывание прошлой генерации не должно завершать программу.
+func (c *Chat) setGeneration(cancel context.CancelFunc) {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	c.cancelGen = cancel
+	if cancel != nil {
+		c.interrupted = false
+		c.lastInterrupt = time.Time{}
+	}
+}
+
+// interruptGeneration прерывает идущую генерацию по Ctrl+C. Возвращает
+// false, если прерывать нечего или прошлое прерывание было меньше
+// interruptWindow назад, — тогда сигнал завершает программу.
+func (c *Chat) interruptGeneration() bool {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	if c.cancelGen == nil || time.Since(c.lastInterrupt) < interruptWindow {
+		return false
+	}
+
+	c.lastInterrupt = time.Now()
+	c.interrupted = true
+	c.cancelGen()
+	return true
+}
+
+// generationInterrupted сообщает, был ли текущий запрос прерван по Ctrl+C.
+func (c *Chat) generationInterrupted() bool {
+	c.genMu.Lock()
+	defer c.genMu.Unlock()
+	return c.interrupted
+}
+
+// saveInterrupted сохраняет полученную до прерывания часть ответа, чтобы
+// она осталась в контексте разговора.
+func (c *Chat) saveInterrupted(partial, thinking string) error {
+	if !c.cfg.Bare {
+		fmt.Fprintln(c.output(), "\n⏹️  Генерация прервана")
+	}
+	if strings.TrimSpace(partial) == "" {
+		return c.replyJSON(c.keptReply(), thinking)
+	}
+
+	c.lastResponse = partial
+	c.addAIResponse(stripPrefill(partial, c.cfg.AssistantPrefill), thinking)
+	c.session.Messages[len(c.session.Messages)-1].Truncated = model.TruncatedInterrupted
+	content := c.session.Messages[len(c.session.Messages)-1].Content
+	if c.elaborating {
+		content = c.mergeElaboration()
+	}
+	if err := c.replyJSON(content, thinking); err != nil {
+		return err
+	}
+	c.autoSave()
+	return nil
+}
@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"encoding/json"
+	"io"
+)
+
+// jsonReply — ответ модели в режиме --json.
+type jsonReply struct {
+	Role     string `json:"role"`
+	Content  string `json:"content"`
+	Thinking string `json:"thinking,omitempty"`
+}
+
+// jsonOutput сообщает, что ответы выводятся JSON-объектами, а не потоком.
+func (c *Chat) jsonOutput() bool {
+	return c.cfg.OutputFormat == config.OutputJSON
+}
+
+// streamOutput возвращает, куда печатать ответ по мере генерации: в режиме
+// --json поток не выводится, ответ печатается целиком в replyJSON.
+func (c *Chat) streamOutput() io.Writer {
+	if c.jsonOutput() {
+		return io.Discard
+	}
+	return c.output()
+}
+
+// replyJSON в режиме --json выводит ответ одной строкой JSON. Вызывается
+// на каждом пути, которым заканчивается ответ, — в том числе при прерывании
+// и ответе из одних размышлений, — чтобы на каждый вопрос приходил ровно
+// один объект.
+func (c *Chat) replyJSON(content, thinking string) error {
+	if !c.jsonOutput() {
+		return nil
+	}
+	return json.NewEncoder(c.output()).Encode(jsonReply{
+		Role:     model.RoleAssistant,
+		Content:  content,
+		Thinking: thinking,
+	})
+}
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_sendMessage_jsonOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []api.GenerateResponse
+		want     string
+		thinking bool
+	}{
+		{
+			name:   "answer only",
+			chunks: []api.GenerateResponse{{Response: "При"}, {Response: "вет \"мир\""}},
+			want:   `{"role":"assistant","content":"Привет \"мир\""}`,
+		},
+		{
+			name:     "thinking only",
+			chunks:   []api.GenerateResponse{{Thinking: "Подумаю"}},
+			want:     `{"role":"assistant","content":"","thinking":"Подумаю"}`,
+			thinking: true,
+		},
+		{
+			name:     "with thinking",
+			chunks:   []api.GenerateResponse{{Thinking: "Подумаю"}, {Response: "Готово"}},
+			want:     `{"role":"assistant","content":"Готово","thinking":"Подумаю"}`,
+			thinking: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: 10, Bare: true, OutputFormat: config.OutputJSON}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					for _, chunk := range tt.chunks {
+						if err := fn(chunk); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			}
+			chat := newTestChat(client, cfg)
+			var output strings.Builder
+			chat.SetOutput(&output)
+
+			err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+			if err != nil {
+				t.Fatalf("sendMessage() error = %v", err)
+			}
+
+			if got := strings.TrimSuffix(output.String(), "\n"); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+
+			var reply map[string]string
+			if err := json.Unmarshal([]byte(output.String()), &reply); err != nil {
+				t.Fatalf("output is not a JSON object: %v", err)
+			}
+			if _, ok := reply["thinking"]; ok != tt.thinking {
+				t.Errorf("thinking present = %t, want %t", ok, tt.thinking)
+			}
+		})
+	}
+}
+
+func TestChat_sendMessage_jsonOutput_elaborationFails(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, Bare: true, OutputFormat: config.OutputJSON, MinResponseLen: 20}
+	calls := 0
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			calls++
+			if calls == 2 {
+				return fn(api.GenerateResponse{Thinking: "Больше нечего сказать"})
+			}
+			return fn(api.GenerateResponse{Response: "Да."})
+		},
+	}
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+	if err != nil {
+		t.Fatalf("sendMessage() error = %v", err)
+	}
+
+	want := `{"role":"assistant","content":"Да.","thinking":"Больше нечего сказать"}` + "\n"
+	if output.String() != want {
+		t.Errorf("output = %q, want one object with the short answer %q", output.String(), want)
+	}
+}
+
+func TestChat_saveInterrupted_jsonOutput(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Bare: true, OutputFormat: config.OutputJSON}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+	chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}
+
+	if err := chat.saveInterrupted("Длинный ответ, ", ""); err != nil {
+		t.Fatalf("saveInterrupted() error = %v", err)
+	}
+
+	want := `{"role":"assistant","content":"Длинный ответ, "}` + "\n"
+	if output.String() != want {
+		t.Errorf("output = %q, want %q", output.String(), want)
+	}
+}
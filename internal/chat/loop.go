@@ -0,0 +1,45 @@
+package chat
+
+const (
+	loopWindow    = 600 // сколько последних символов ответа анализируется
+	loopMinPeriod = 8   // минимальная длина повторяющегося фрагмента
+	loopRepeats   = 4   // сколько повторов подряд считается зацикливанием
+)
+
+// loopDetector отслеживает хвост потокового ответа и сообщает, когда он
+// заканчивается одним и тем же фрагментом, повторённым loopRepeats раз подряд.
+type loopDetector struct {
+	tail []rune
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{tail: make([]rune, 0, loopWindow)}
+}
+
+// Feed добавляет очередной фрагмент ответа и возвращает true,
+// если обнаружено зацикливание.
+func (d *loopDetector) Feed(chunk string) bool {
+	d.tail = append(d.tail, []rune(chunk)...)
+	if len(d.tail) > loopWindow {
+		d.tail = append(d.tail[:0], d.tail[len(d.tail)-loopWindow:]...)
+	}
+
+	for period := loopMinPeriod; period*loopRepeats <= len(d.tail); period++ {
+		if d.endsWithRepeats(period) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWithRepeats проверяет, что последние period*loopRepeats символов
+// имеют период period.
+func (d *loopDetector) endsWithRepeats(period int) bool {
+	n := len(d.tail)
+	for i := n - period*loopRepeats; i < n-period; i++ {
+		if d.tail[i] != d.tail[i+period] {
+			return false
+		}
+	}
+	return true
+}
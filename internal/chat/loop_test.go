@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestLoopDetector_Feed(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   bool
+	}{
+		{
+			name:   "normal text",
+			chunks: []string{"Это обычный ответ, ", "в котором ничего ", "не повторяется подряд."},
+			want:   false,
+		},
+		{
+			name:   "phrase repeated below threshold",
+			chunks: []string{"ха-ха, смешно. ", "ха-ха, смешно. ", "ха-ха, смешно. "},
+			want:   false,
+		},
+		{
+			name:   "phrase repeated at threshold",
+			chunks: []string{"Начало. ", "и снова то же ", "и снова то же ", "и снова то же ", "и снова то же "},
+			want:   true,
+		},
+		{
+			name:   "short repeated chars are ignored",
+			chunks: []string{"ну", "ну", "ну", "ну"},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newLoopDetector()
+			var got bool
+			for _, chunk := range tt.chunks {
+				got = d.Feed(chunk)
+			}
+			if got != tt.want {
+				t.Errorf("loopDetector.Feed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_sendMessage_stopsOnLoop(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		DetectLoops:  true,
+	}
+
+	var sent int
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			if err := fn(api.GenerateResponse{Response: "Ответ: "}); err != nil {
+				return err
+			}
+			for i := 0; i < 100; i++ {
+				sent++
+				if err := fn(api.GenerateResponse{Response: "бла-бла-бла "}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	err := chat.sendMessage([]model.Message{
+		{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	if sent >= 100 {
+		t.Error("stream should be stopped once the loop is detected")
+	}
+
+	if len(chat.session.Messages) != 1 {
+		t.Fatalf("Expected 1 message in session, got %d", len(chat.session.Messages))
+	}
+
+	saved := chat.session.Messages[0]
+	if saved.Truncated != model.TruncatedLoop {
+		t.Errorf("saved.Truncated = %q, want %q", saved.Truncated, model.TruncatedLoop)
+	}
+	if !strings.HasPrefix(saved.Content, "Ответ: бла-бла-бла") {
+		t.Errorf("saved content should keep the partial response, got %q", saved.Content)
+	}
+}
+
+func TestChat_sendMessage_loopDetectionDisabled(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			for i := 0; i < 10; i++ {
+				fn(api.GenerateResponse{Response: "бла-бла-бла "})
+			}
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	if err := chat.sendMessage([]model.Message{
+		{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()},
+	}); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	saved := chat.session.Messages[0]
+	if saved.Truncated != "" {
+		t.Errorf("saved.Truncated = %q, want empty when DETECT_LOOPS is off", saved.Truncated)
+	}
+	if saved.Content != strings.Repeat("бла-бла-бла ", 10) {
+		t.Errorf("saved content = %q, want full stream", saved.Content)
+	}
+}
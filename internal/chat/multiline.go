@@ -0,0 +1,44 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// multilineDelimiter на отдельной строке начинает и заканчивает
+// многострочный ввод — для вставки кода и текста из нескольких абзацев.
+const multilineDelimiter = `"""`
+
+// readInput читает следующее сообщение пользователя. Обычный ввод — одна
+// строка без пробелов по краям. Строки между двумя `"""` склеиваются через
+// перевод строки без изменений; незакрытый блок в конце ввода отправляется
+// как есть. Пустой блок равносилен пустому вводу. multiline сообщает, что
+// сообщение введено блоком; ok == false — ввод закончился.
+func (c *Chat) readInput(scanner *bufio.Scanner) (input string, multiline, ok bool) {
+	if !scanner.Scan() {
+		return "", false, false
+	}
+
+	input = strings.TrimSpace(scanner.Text())
+	if input != multilineDelimiter {
+		return input, false, true
+	}
+
+	var lines []string
+	for {
+		if !c.cfg.Bare {
+			fmt.Fprint(c.output(), "... ")
+		}
+		if !scanner.Scan() || strings.TrimSpace(scanner.Text()) == multilineDelimiter {
+			break
+		}
+		lines = append(lines, scanner.Text())
+	}
+
+	input = strings.Join(lines, "\n")
+	if strings.TrimSpace(input) == "" {
+		return "", true, true
+	}
+	return input, true, true
+}
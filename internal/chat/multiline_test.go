@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_chatLoop_multiline(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "two-line block",
+			input: "\"\"\"\nfunc main() {\n\tprintln(1)\n\"\"\"\nexit\n",
+			want:  []string{"func main() {\n\tprintln(1)"},
+		},
+		{
+			name:  "unterminated block at EOF",
+			input: "first\n\"\"\"\nline one\n\nline two",
+			want:  []string{"first", "line one\n\nline two"},
+		},
+		{
+			name:  "empty block exits",
+			input: "\"\"\"\n  \n\"\"\"\nnot sent\n",
+			want:  nil,
+		},
+		{
+			name:  "block is not a command",
+			input: "  \"\"\"  \n/save this path\n\"\"\"\n",
+			want:  []string{"/save this path"},
+		},
+		{
+			name:  "block keeps leading indentation",
+			input: "\"\"\"\n    indented code\n\"\"\"\n",
+			want:  []string{"    indented code"},
+		},
+		{
+			name:  "block directives are plain text",
+			input: "\"\"\"\n!nothink @temp=0.1 keep as is\n\"\"\"\n",
+			want:  []string{"!nothink @temp=0.1 keep as is"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Stateless: true}
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					return fn(api.GenerateResponse{Response: "OK"})
+				},
+			}
+			chat := newTestChat(client, cfg)
+			chat.SetOutput(&strings.Builder{})
+
+			chat.chatLoop(strings.NewReader(tt.input))
+
+			var got []string
+			for _, msg := range chat.session.Messages {
+				if msg.IsUser() {
+					got = append(got, msg.Content)
+				}
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("user messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
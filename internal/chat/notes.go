@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"time"
+)
+
+// note дописывает строку в заметки сессии. Заметки хранятся в файле сессии,
+// но в контекст модели не попадают.
+func (c *Chat) note(args string) error {
+	if args == "" {
+		return fmt.Errorf("%w: использование /note <текст>", errors.ErrInvalidOption)
+	}
+
+	if c.session.Notes != "" {
+		c.session.Notes += "\n"
+	}
+	c.session.Notes += args
+	c.session.Updated = time.Now()
+	fmt.Fprintln(c.output(), "📝 Заметка добавлена")
+	return c.saveSession()
+}
+
+func (c *Chat) showNotes() {
+	if c.session.Notes == "" {
+		fmt.Fprintln(c.output(), "📝 Заметок пока нет, добавьте: /note <текст>")
+		return
+	}
+	fmt.Fprintf(c.output(), "📝 Заметки:\n%s\n", c.session.Notes)
+}
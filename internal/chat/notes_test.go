@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_notes(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var prompts []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompts = append(prompts, req.System+"\n"+req.Prompt)
+			return fn(api.GenerateResponse{Response: "Ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	for _, command := range []string{"/note купить молоко", "/note позвонить Ивану"} {
+		if handled, err := chat.handleCommand(command); !handled || err != nil {
+			t.Fatalf("handleCommand(%q) = %v, %v", command, handled, err)
+		}
+	}
+	if want := "купить молоко\nпозвонить Ивану"; chat.session.Notes != want {
+		t.Errorf("Notes = %q, want %q", chat.session.Notes, want)
+	}
+
+	chat.handleCommand("/notes")
+	if !strings.Contains(output.String(), "купить молоко\nпозвонить Ивану") {
+		t.Errorf("/notes printed %q", output.String())
+	}
+
+	if err := chat.processUserInput("Вопрос"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+	for _, prompt := range prompts {
+		if strings.Contains(prompt, "молоко") || strings.Contains(prompt, "Ивану") {
+			t.Errorf("notes leaked into the request: %q", prompt)
+		}
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if loaded.Notes != chat.session.Notes {
+		t.Errorf("persisted Notes = %q, want %q", loaded.Notes, chat.session.Notes)
+	}
+}
+
+func TestChat_buildContextPrompt_ignoresNotes(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10}
+	for _, style := range []string{config.PromptStyleLabeled, config.PromptStyleChatML, config.PromptStyleMinimal} {
+		cfg.PromptStyle = style
+		chat := newTestChat(&mockAIClient{}, cfg)
+		chat.session.Notes = "секретная заметка"
+		chat.session.Messages = []model.Message{
+			{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+			{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+			{Role: model.RoleUser, Content: "Q2", Timestamp: time.Now()},
+		}
+
+		if prompt := chat.buildContextPrompt(chat.session.Messages); strings.Contains(prompt, "заметка") {
+			t.Errorf("%s prompt contains notes: %q", style, prompt)
+		}
+	}
+}
+
+func TestChat_note_empty(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+	if err := chat.note(""); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("note(\"\") error = %v, want ErrInvalidOption", err)
+	}
+}
@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReadMessagesJSON читает массив сообщений [{"role": ..., "content": ...}]
+// с ролями user, assistant или system и проверяет, что последнее
+// сообщение — вопрос пользователя.
+func ReadMessagesJSON(r io.Reader) ([]model.Message, error) {
+	var messages []model.Message
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileParse, err)
+	}
+	if len(messages) == 0 {
+		return nil, errors.ErrNoMessages
+	}
+
+	for i, msg := range messages {
+		if !model.ValidRole(msg.Role) {
+			return nil, fmt.Errorf("%w: сообщение %d: %q", errors.ErrInvalidRole, i+1, msg.Role)
+		}
+		if msg.Content == "" {
+			return nil, fmt.Errorf("%w: сообщение %d", errors.ErrEmptyContent, i+1)
+		}
+	}
+	if !messages[len(messages)-1].IsUser() {
+		return nil, fmt.Errorf("%w: последним должно быть сообщение пользователя", errors.ErrInvalidMessage)
+	}
+	return messages, nil
+}
+
+// RunOneShotJSON продолжает переданный в JSON разговор одним ответом модели,
+// не используя файлы сессий. В out пишется текст ответа, а при withMessages —
+// обновлённый массив сообщений с добавленным ответом.
+func RunOneShotJSON(client AIClient, cfg *config.Config, in io.Reader, out io.Writer, withMessages bool) error {
+	messages, err := ReadMessagesJSON(in)
+	if err != nil {
+		return err
+	}
+
+	c := newChat(client, cfg, &session.ChatSession{Messages: messages, Cfg: cfg})
+	response, err := c.generateOnce()
+	if err != nil {
+		return err
+	}
+
+	if !withMessages {
+		_, err := fmt.Fprintln(out, response)
+		return err
+	}
+
+	messages = append(messages, model.Message{Role: model.RoleAssistant, Content: response, Timestamp: time.Now()})
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(messages)
+}
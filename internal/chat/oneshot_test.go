@@ -0,0 +1,105 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestRunOneShotJSON(t *testing.T) {
+	const input = `[
+		{"role": "user", "content": "Как зовут кота?"},
+		{"role": "assistant", "content": "Барсик."},
+		{"role": "user", "content": "А сколько ему лет?"}
+	]`
+	cfg := &config.Config{CtxSizeLimit: 10, ModelName: "test-model", PromptStyle: config.PromptStyleLabeled}
+
+	var captured *api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			captured = req
+			return fn(api.GenerateResponse{Response: "Три года."})
+		},
+	}
+
+	t.Run("response only", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := RunOneShotJSON(client, cfg, strings.NewReader(input), &out, false); err != nil {
+			t.Fatalf("RunOneShotJSON() error = %v", err)
+		}
+		if out.String() != "Три года.\n" {
+			t.Errorf("output = %q, want %q", out.String(), "Три года.\n")
+		}
+
+		for _, want := range []string{"Как зовут кота?", "Барсик.", "А сколько ему лет?"} {
+			if !strings.Contains(captured.Prompt, want) {
+				t.Errorf("prompt missing %q:\n%s", want, captured.Prompt)
+			}
+		}
+		if strings.Index(captured.Prompt, "Барсик.") > strings.Index(captured.Prompt, "А сколько ему лет?") {
+			t.Errorf("prompt history out of order:\n%s", captured.Prompt)
+		}
+	})
+
+	t.Run("updated messages", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := RunOneShotJSON(client, cfg, strings.NewReader(input), &out, true); err != nil {
+			t.Fatalf("RunOneShotJSON() error = %v", err)
+		}
+
+		var messages []model.Message
+		if err := json.Unmarshal(out.Bytes(), &messages); err != nil {
+			t.Fatalf("output is not a messages array: %v\n%s", err, out.String())
+		}
+		if len(messages) != 4 {
+			t.Fatalf("len(messages) = %d, want 4", len(messages))
+		}
+		last := messages[3]
+		if last.Role != model.RoleAssistant || last.Content != "Три года." {
+			t.Errorf("last message = %+v, want assistant reply", last)
+		}
+	})
+}
+
+func TestReadMessagesJSON_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"not json", `hello`, errors.ErrFileParse},
+		{"empty", `[]`, errors.ErrNoMessages},
+		{"bad role", `[{"role": "robot", "content": "hi"}]`, errors.ErrInvalidRole},
+		{"empty content", `[{"role": "user", "content": ""}]`, errors.ErrEmptyContent},
+		{"ends with assistant", `[{"role": "user", "content": "hi"}, {"role": "assistant", "content": "hello"}]`, errors.ErrInvalidMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadMessagesJSON(strings.NewReader(tt.input))
+			if !stderrors.Is(err, tt.wantErr) {
+				t.Errorf("ReadMessagesJSON() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadMessagesJSON_systemRole(t *testing.T) {
+	input := `[{"role": "system", "content": "Отвечай кратко."}, {"role": "user", "content": "Привет"}]`
+
+	messages, err := ReadMessagesJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadMessagesJSON() error = %v", err)
+	}
+	if len(messages) != 2 || !messages[0].IsSystem() {
+		t.Errorf("messages = %+v, want the system instruction kept", messages)
+	}
+}
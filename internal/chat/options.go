@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// inlineOptions сопоставляет короткие имена директив @key=value
+// с опциями Ollama и разбирает значение в нужный тип.
+var inlineOptions = map[string]struct {
+	option string
+	parse  func(string) (any, error)
+}{
+	"temp":  {"temperature", parseFloatOption},
+	"top_p": {"top_p", parseFloatOption},
+	"seed":  {"seed", parseIntOption},
+}
+
+// parseInlineOptions отделяет ведущие директивы вида «@temp=0.9» от текста
+// сообщения. Разбор останавливается на первом слове, не являющемся
+// известной директивой, так что «@имя привет» остаётся обычным текстом.
+func parseInlineOptions(input string) (string, map[string]any, error) {
+	rest := strings.TrimSpace(input)
+	var options map[string]any
+
+	for strings.HasPrefix(rest, "@") {
+		token, tail, _ := strings.Cut(rest, " ")
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(token, "@"), "=")
+		spec, known := inlineOptions[strings.ToLower(name)]
+		if !ok || !known {
+			break
+		}
+
+		parsed, err := spec.parse(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w %s: %q", errors.ErrInvalidOption, name, value)
+		}
+
+		if options == nil {
+			options = make(map[string]any)
+		}
+		options[spec.option] = parsed
+		rest = strings.TrimSpace(tail)
+	}
+
+	if rest == "" {
+		return "", nil, errors.ErrEmptyInput
+	}
+	return rest, options, nil
+}
+
+// noThinkPrefix в начале сообщения отключает размышления только для него.
+const noThinkPrefix = "!nothink"
+
+// cutNoThink отделяет префикс !nothink и сообщает, был ли он указан.
+func cutNoThink(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	token, rest, _ := strings.Cut(input, " ")
+	if !strings.EqualFold(token, noThinkPrefix) {
+		return input, false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+func parseFloatOption(value string) (any, error) {
+	return strconv.ParseFloat(value, 64)
+}
+
+func parseIntOption(value string) (any, error) {
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,181 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"context"
+	stderrors "errors"
+	"reflect"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestParseInlineOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantContent string
+		wantOptions map[string]any
+		wantErr     error
+	}{
+		{
+			name:        "no directives",
+			input:       "write a poem",
+			wantContent: "write a poem",
+		},
+		{
+			name:        "temperature",
+			input:       "@temp=0.9 write a poem",
+			wantContent: "write a poem",
+			wantOptions: map[string]any{"temperature": 0.9},
+		},
+		{
+			name:        "several directives",
+			input:       "@top_p=0.5 @seed=42 @TEMP=1 hi",
+			wantContent: "hi",
+			wantOptions: map[string]any{"top_p": 0.5, "seed": 42, "temperature": 1.0},
+		},
+		{
+			name:        "unknown directive is content",
+			input:       "@ivan привет",
+			wantContent: "@ivan привет",
+		},
+		{
+			name:        "parsing stops at first plain word",
+			input:       "@seed=1 text @temp=0.1",
+			wantContent: "text @temp=0.1",
+			wantOptions: map[string]any{"seed": 1},
+		},
+		{
+			name:    "invalid value",
+			input:   "@temp=hot write",
+			wantErr: errors.ErrInvalidOption,
+		},
+		{
+			name:    "only directives",
+			input:   "@temp=0.2",
+			wantErr: errors.ErrEmptyInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, options, err := parseInlineOptions(tt.input)
+
+			if tt.wantErr != nil {
+				if !stderrors.Is(err, tt.wantErr) {
+					t.Errorf("parseInlineOptions() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInlineOptions() unexpected error: %v", err)
+			}
+
+			if content != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+			if !reflect.DeepEqual(options, tt.wantOptions) {
+				t.Errorf("options = %v, want %v", options, tt.wantOptions)
+			}
+		})
+	}
+}
+
+func TestChat_processUserInput_inlineOptions(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Temperature: 0.1}
+
+	var requests []*api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			requests = append(requests, req)
+			fn(api.GenerateResponse{Response: "OK"})
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+
+	if err := chat.processUserInput("@temp=0.9 write a poem"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+	if err := chat.processUserInput("and another one"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+
+	if got := requests[0].Options["temperature"]; got != 0.9 {
+		t.Errorf("first request temperature = %v, want 0.9", got)
+	}
+	if got := requests[1].Options["temperature"]; got != 0.1 {
+		t.Errorf("second request temperature = %v, want configured 0.1", got)
+	}
+
+	if saved := chat.session.Messages[0].Content; saved != "write a poem" {
+		t.Errorf("saved content = %q, want directive stripped", saved)
+	}
+	if containsString(requests[0].Prompt, "@temp") {
+		t.Errorf("prompt should not contain the directive, got %q", requests[0].Prompt)
+	}
+	if chat.session.Messages[0].Role != model.RoleUser {
+		t.Errorf("first message role = %q, want user", chat.session.Messages[0].Role)
+	}
+}
+
+func TestCutNoThink(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantContent string
+		wantNoThink bool
+	}{
+		{"!nothink сколько будет 2+2?", "сколько будет 2+2?", true},
+		{"!NoThink  вопрос", "вопрос", true},
+		{"обычный вопрос", "обычный вопрос", false},
+		{"!nothinking вопрос", "!nothinking вопрос", false},
+		{"!nothink", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			content, noThink := cutNoThink(tt.input)
+			if content != tt.wantContent || noThink != tt.wantNoThink {
+				t.Errorf("cutNoThink(%q) = %q, %v; want %q, %v", tt.input, content, noThink, tt.wantContent, tt.wantNoThink)
+			}
+		})
+	}
+}
+
+func TestChat_processUserInput_noThink(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, ThinkValue: &api.ThinkValue{Value: true}}
+
+	var requests []*api.GenerateRequest
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			requests = append(requests, req)
+			return fn(api.GenerateResponse{Response: "4"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+
+	if err := chat.processUserInput("!nothink сколько будет 2+2?"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+	if err := chat.processUserInput("а теперь подумай"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+
+	if requests[0].Think == nil || requests[0].Think.Bool() {
+		t.Errorf("flagged request Think = %v, want false", requests[0].Think)
+	}
+	if !requests[1].Think.Bool() {
+		t.Errorf("next request Think = %v, want configured true", requests[1].Think)
+	}
+	if !cfg.ThinkValue.Bool() {
+		t.Error("configured think value must not change")
+	}
+	if saved := chat.session.Messages[0].Content; saved != "сколько будет 2+2?" {
+		t.Errorf("saved content = %q, want prefix stripped", saved)
+	}
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// profilePrefix — начало имени файла профиля: /save-profile work пишет
+// .env.work рядом с .env.
+const profilePrefix = ".env."
+
+// saveProfile сохраняет действующие настройки, включая изменения /set,
+// в файл профиля для следующих запусков с --env.
+func (c *Chat) saveProfile(args string) error {
+	if args == "" {
+		return fmt.Errorf("%w: использование /save-profile <имя или путь>", errors.ErrInvalidOption)
+	}
+
+	path := profilePath(args)
+	if _, err := os.Stat(path); err == nil && !c.confirm(fmt.Sprintf("💾 Профиль %s уже существует. Перезаписать", path)) {
+		fmt.Fprintln(c.output(), "Отменено")
+		return nil
+	}
+
+	if err := c.cfg.WriteProfile(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.output(), "💾 Настройки сохранены в %s, загрузить их: --env %s\n", path, path)
+	return nil
+}
+
+// profilePath превращает имя профиля в путь .env.<имя>. Аргумент, похожий
+// на путь (с разделителем каталогов или точкой), используется как есть.
+func profilePath(name string) string {
+	if strings.ContainsAny(name, `/\.`) {
+		return name
+	}
+	return profilePrefix + name
+}
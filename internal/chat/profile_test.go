@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_saveProfile(t *testing.T) {
+	cfg := &config.Config{ModelName: "llama3", Temperature: 0.1, ThinkValue: &api.ThinkValue{Value: false}}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	path := filepath.Join(t.TempDir(), ".env.work")
+	if _, err := chat.handleCommand("/set temp 0.9"); err != nil {
+		t.Fatalf("/set temp error = %v", err)
+	}
+	if handled, err := chat.handleCommand("/save-profile " + path); !handled || err != nil {
+		t.Fatalf("handleCommand(/save-profile) = %v, %v", handled, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("profile not written: %v", err)
+	}
+	if !strings.Contains(string(data), "\nTEMPERATURE=0.9\n") || !strings.Contains(string(data), "\nMODEL_NAME=llama3\n") {
+		t.Errorf("profile = %q, want runtime overrides", data)
+	}
+
+	// Существующий профиль без подтверждения не перезаписывается
+	cfg.ModelName = "other"
+	if err := chat.saveProfile(path); err != nil {
+		t.Fatalf("saveProfile() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "MODEL_NAME=other") {
+		t.Error("existing profile must not be overwritten without confirmation")
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	tests := map[string]string{
+		"work":             ".env.work",
+		".env.local":       ".env.local",
+		"profiles/dev.env": "profiles/dev.env",
+	}
+	for name, want := range tests {
+		if got := profilePath(name); got != want {
+			t.Errorf("profilePath(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// slowContextThreshold — время сборки контекста, после которого пользователю
+// предлагается сократить историю.
+const slowContextThreshold = 250 * time.Millisecond
+
+// assembleContext собирает промпт и предупреждает, если на очень длинной
+// истории это заняло больше slowContextThreshold.
+func (c *Chat) assembleContext(messages []model.Message) string {
+	start := c.now()
+	prompt := c.buildContextPrompt(messages)
+	if c.cfg.ShowContext && !c.cfg.Bare {
+		c.reportContext(messages)
+	}
+
+	if elapsed := c.now().Sub(start); elapsed > slowContextThreshold && !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "⚠️  Сборка контекста из %d сообщений заняла %v. Сократите историю командой /prune или начните новый чат\n",
+			len(messages), elapsed.Round(time.Millisecond))
+	}
+	return prompt
+}
+
+func (c *Chat) buildContextPrompt(messages []model.Message) string {
+	if len(messages) == 0 {
+		return ""
+	}
+
+	last := len(messages) - 1
+	history := c.contextHistory(messages)
+	current := messages[last]
+
+	switch c.cfg.PromptStyle {
+	case config.PromptStyleChatML:
+		return buildChatMLPrompt(history, current)
+	case config.PromptStyleMinimal:
+		return buildMinimalPrompt(history, current)
+	default:
+		return buildLabeledPrompt(history, current)
+	}
+}
+
+// historyStart возвращает индекс первого сообщения истории, попадающего
+// в контекст. CTX_SIZE_LIMIT — число сообщений истории, CTX_CHAR_LIMIT —
+// их суммарная длина в символах; текущий вопрос в лимиты не входит.
+// MIN_RECENT_TURNS последних обменов остаются в контексте, даже если ради
+// этого лимиты будут превышены.
+func (c *Chat) historyStart(messages []model.Message) int {
+	start := c.calculateStartIndex(len(messages)-1, c.cfg.CtxSizeLimit)
+	if c.cfg.CtxCharLimit > 0 {
+		start = max(start, charBudgetStart(messages, c.cfg.CtxCharLimit))
+	}
+	if c.cfg.PreserveTurns {
+		start = alignToTurnStart(messages, start)
+	}
+	if c.cfg.MinRecentTurns > 0 {
+		start = min(start, recentTurnsStart(messages, c.cfg.MinRecentTurns))
+	}
+	return start
+}
+
+// recentTurnsStart возвращает индекс вопроса, с которого начинаются turns
+// последних обменов репликами в истории (без текущего вопроса). Если
+// обменов меньше, возвращает 0.
+func recentTurnsStart(messages []model.Message, turns int) int {
+	for i := len(messages) - 2; i >= 0; i-- {
+		if messages[i].IsUser() {
+			if turns--; turns == 0 {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// charBudgetStart идёт по истории (без текущего вопроса) от новых сообщений
+// к старым и возвращает индекс самого старого сообщения, после которого
+// суммарная длина истории ещё не превышает limit символов. Слишком длинное
+// сообщение отсекает и его, и всё, что было раньше.
+func charBudgetStart(messages []model.Message, limit int) int {
+	used := 0
+	for i := len(messages) - 2; i >= 0; i-- {
+		used += utf8.RuneCountInString(messages[i].Content)
+		if used > limit {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// contextHistory возвращает сообщения истории, попадающие в контекст.
+// При KEEP_FIRST_MESSAGE первый вопрос пользователя остаётся в начале
+// истории, даже если лимит его отбросил: он обычно задаёт тему разговора.
+// Системные сообщения сессии не отбрасываются лимитами никогда и идут
+// в самом начале. При STATELESS история в контекст не попадает, кроме
+// вопроса и короткого ответа при уточнении (MIN_RESPONSE_LEN).
+func (c *Chat) contextHistory(messages []model.Message) []model.Message {
+	if c.cfg.Stateless {
+		if c.elaborating && len(messages) >= 3 {
+			return messages[len(messages)-3 : len(messages)-1]
+		}
+		return nil
+	}
+
+	start := c.historyStart(messages)
+	history := c.withReminders(messages, start)
+
+	if first := firstUserIndex(messages); c.cfg.KeepFirstMessage && first >= 0 && first < start {
+		history = append([]model.Message{messages[first]}, history...)
+	}
+	return append(pinnedSystem(messages[:start]), history...)
+}
+
+// pinnedSystem возвращает системные сообщения, отброшенные лимитами.
+func pinnedSystem(dropped []model.Message) []model.Message {
+	var pinned []model.Message
+	for _, msg := range dropped {
+		if msg.IsSystem() {
+			pinned = append(pinned, msg)
+		}
+	}
+	return pinned
+}
+
+// firstUserIndex возвращает индекс первого сообщения пользователя или -1.
+func firstUserIndex(messages []model.Message) int {
+	for i, msg := range messages {
+		if msg.IsUser() {
+			return i
+		}
+	}
+	return -1
+}
+
+// reportContext печатает, сколько сообщений истории вошло в контекст
+// и какие были отброшены из-за лимита (SHOW_CONTEXT).
+func (c *Chat) reportContext(messages []model.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	if c.cfg.Stateless {
+		fmt.Fprintf(c.output(), "🔎 Контекст: без истории (STATELESS), в сессии %d сообщений\n", len(messages)-1)
+		return
+	}
+
+	dropped := c.historyStart(messages)
+	included := len(messages) - 1 - dropped
+	if dropped == 0 {
+		fmt.Fprintf(c.output(), "🔎 Контекст: %d сообщений истории, ничего не отброшено\n", included)
+		return
+	}
+	limits := fmt.Sprintf("CTX_SIZE_LIMIT=%d", c.cfg.CtxSizeLimit)
+	if c.cfg.CtxCharLimit > 0 {
+		limits += fmt.Sprintf(", CTX_CHAR_LIMIT=%d", c.cfg.CtxCharLimit)
+	}
+	fmt.Fprintf(c.output(), "🔎 Контекст: %d сообщений истории, отброшено %d (№1–%d) из-за лимитов %s\n",
+		included, dropped, dropped, limits)
+	if c.cfg.KeepFirstMessage {
+		fmt.Fprintln(c.output(), "📌 Первый вопрос сохранён в контексте (KEEP_FIRST_MESSAGE)")
+	}
+}
+
+// alignToTurnStart сдвигает начало окна вперёд до ближайшего сообщения
+// пользователя, чтобы в контекст не попал ответ без своего вопроса.
+func alignToTurnStart(messages []model.Message, start int) int {
+	last := len(messages) - 1
+	for start < last && !messages[start].IsUser() {
+		start++
+	}
+	return start
+}
+
+// buildLabeledPrompt — исходный формат с русскими метками ролей.
+func buildLabeledPrompt(history []model.Message, current model.Message) string {
+	var builder strings.Builder
+
+	if len(history) > 0 {
+		builder.WriteString("Предыдущий контекст беседы:\n")
+	}
+	for _, msg := range history {
+		if msg.IsUser() {
+			builder.WriteString(fmt.Sprintf("Пользователь: %s\n", msg.Content))
+		} else if msg.Role == reminderRole {
+			builder.WriteString(fmt.Sprintf("Напоминание: %s\n", msg.Content))
+		} else if msg.IsSystem() {
+			builder.WriteString(fmt.Sprintf("Система: %s\n", msg.Content))
+		} else {
+			builder.WriteString(fmt.Sprintf("Ассистент: %s\n", msg.Content))
+		}
+	}
+
+	if len(history) > 0 {
+		builder.WriteString("\n")
+	}
+	builder.WriteString(fmt.Sprintf("Текущий вопрос: %s", current.Content))
+
+	return builder.String()
+}
+
+// buildChatMLPrompt оформляет диалог блоками <|im_start|>role ... <|im_end|>
+// и оставляет открытый блок ассистента для продолжения.
+func buildChatMLPrompt(history []model.Message, current model.Message) string {
+	var builder strings.Builder
+
+	for _, msg := range history {
+		role := msg.Role
+		if role == reminderRole {
+			role = model.RoleSystem
+		}
+		builder.WriteString(fmt.Sprintf("<|im_start|>%s\n%s<|im_end|>\n", role, msg.Content))
+	}
+	builder.WriteString(fmt.Sprintf("<|im_start|>%s\n%s<|im_end|>\n", current.Role, current.Content))
+	builder.WriteString("<|im_start|>" + model.RoleAssistant + "\n")
+
+	return builder.String()
+}
+
+// buildMinimalPrompt просто склеивает содержимое сообщений без меток.
+func buildMinimalPrompt(history []model.Message, current model.Message) string {
+	parts := make([]string, 0, len(history)+1)
+	for _, msg := range history {
+		parts = append(parts, msg.Content)
+	}
+	parts = append(parts, current.Content)
+
+	return strings.Join(parts, "\n\n")
+}
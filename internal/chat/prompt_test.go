@@ -0,0 +1,550 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func smallConversation() []model.Message {
+	return []model.Message{
+		{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Hello!", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "How are you?", Timestamp: time.Now()},
+	}
+}
+
+func TestChat_buildContextPrompt_styles(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{
+			name:  "default is labeled",
+			style: "",
+			want: "Предыдущий контекст беседы:\n" +
+				"Пользователь: Hi\n" +
+				"Ассистент: Hello!\n" +
+				"\nТекущий вопрос: How are you?",
+		},
+		{
+			name:  "labeled",
+			style: config.PromptStyleLabeled,
+			want: "Предыдущий контекст беседы:\n" +
+				"Пользователь: Hi\n" +
+				"Ассистент: Hello!\n" +
+				"\nТекущий вопрос: How are you?",
+		},
+		{
+			name:  "chatml",
+			style: config.PromptStyleChatML,
+			want: "<|im_start|>user\nHi<|im_end|>\n" +
+				"<|im_start|>assistant\nHello!<|im_end|>\n" +
+				"<|im_start|>user\nHow are you?<|im_end|>\n" +
+				"<|im_start|>assistant\n",
+		},
+		{
+			name:  "minimal",
+			style: config.PromptStyleMinimal,
+			want:  "Hi\n\nHello!\n\nHow are you?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{CtxSizeLimit: 10, PromptStyle: tt.style}}
+
+			got := c.buildContextPrompt(smallConversation())
+			if got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_preservesTurns(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A1", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Q2", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "A2", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Q3", Timestamp: time.Now()},
+	}
+
+	tests := []struct {
+		name          string
+		preserveTurns bool
+		want          string
+	}{
+		{
+			// Наивное окно из 3 сообщений истории начинается с ответа A1
+			name:          "naive trim starts mid-turn",
+			preserveTurns: false,
+			want:          "A1\n\nQ2\n\nA2\n\nQ3",
+		},
+		{
+			name:          "window aligned to user message",
+			preserveTurns: true,
+			want:          "Q2\n\nA2\n\nQ3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{
+				CtxSizeLimit:  3,
+				PromptStyle:   config.PromptStyleMinimal,
+				PreserveTurns: tt.preserveTurns,
+			}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_exactLimit(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+		{Role: model.RoleAssistant, Content: "A2"},
+		{Role: model.RoleUser, Content: "Q3"},
+	}
+
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{0, "Q3"},
+		{1, "A2\n\nQ3"},
+		{2, "Q2\n\nA2\n\nQ3"},
+		{4, "Q1\n\nA1\n\nQ2\n\nA2\n\nQ3"},
+		{10, "Q1\n\nA1\n\nQ2\n\nA2\n\nQ3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit=%d", tt.limit), func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{CtxSizeLimit: tt.limit, PromptStyle: config.PromptStyleMinimal}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_keepFirstMessage(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+		{Role: model.RoleAssistant, Content: "A2"},
+		{Role: model.RoleUser, Content: "Q3"},
+		{Role: model.RoleAssistant, Content: "A3"},
+		{Role: model.RoleUser, Content: "Q4"},
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		keep  bool
+		want  string
+	}{
+		{"disabled drops the opening question", 2, false, "Q3\n\nA3\n\nQ4"},
+		{"middle trimmed, first kept", 2, true, "Q1\n\nQ3\n\nA3\n\nQ4"},
+		{"zero limit still keeps first", 0, true, "Q1\n\nQ4"},
+		{"no trimming, no duplicate", 10, true, "Q1\n\nA1\n\nQ2\n\nA2\n\nQ3\n\nA3\n\nQ4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{
+				CtxSizeLimit:     tt.limit,
+				PromptStyle:      config.PromptStyleMinimal,
+				KeepFirstMessage: tt.keep,
+			}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_stateless(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+	}
+
+	for _, style := range []string{config.PromptStyleLabeled, config.PromptStyleChatML, config.PromptStyleMinimal} {
+		t.Run(style, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{
+				CtxSizeLimit:     10,
+				PromptStyle:      style,
+				KeepFirstMessage: true,
+				RemindEvery:      1,
+				Stateless:        true,
+			}}
+
+			got := c.buildContextPrompt(messages)
+			if !strings.Contains(got, "Q2") {
+				t.Errorf("buildContextPrompt() = %q, want the current question", got)
+			}
+			for _, old := range []string{"Q1", "A1", "Напоминание"} {
+				if strings.Contains(got, old) {
+					t.Errorf("buildContextPrompt() = %q, must not contain history %q", got, old)
+				}
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_minRecentTurns(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+		{Role: model.RoleAssistant, Content: "A2"},
+		{Role: model.RoleUser, Content: "Q3"},
+		{Role: model.RoleAssistant, Content: "A3"},
+		{Role: model.RoleUser, Content: "Q4"},
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		minRecent int
+		want      string
+	}{
+		{"no guarantee", 1, 0, "A3\n\nQ4"},
+		{"budget below minimum keeps two turns", 1, 2, "Q2\n\nA2\n\nQ3\n\nA3\n\nQ4"},
+		{"zero budget keeps one turn", 0, 1, "Q3\n\nA3\n\nQ4"},
+		{"budget above minimum is unchanged", 4, 1, "Q2\n\nA2\n\nQ3\n\nA3\n\nQ4"},
+		{"minimum above history keeps everything", 0, 10, "Q1\n\nA1\n\nQ2\n\nA2\n\nQ3\n\nA3\n\nQ4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{
+				CtxSizeLimit:   tt.limit,
+				PromptStyle:    config.PromptStyleMinimal,
+				MinRecentTurns: tt.minRecent,
+			}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_charLimit(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	messages := []model.Message{
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Покажи лог"},
+		{Role: model.RoleAssistant, Content: long},
+		{Role: model.RoleUser, Content: "Коротко?"},
+		{Role: model.RoleAssistant, Content: "Да"},
+		{Role: model.RoleUser, Content: "Текущий"},
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		minRecent int
+		want      string
+	}{
+		{"disabled", 0, 0, "Q1\n\nA1\n\nПокажи лог\n\n" + long + "\n\nКоротко?\n\nДа\n\nТекущий"},
+		{"long message dropped", 50, 0, "Коротко?\n\nДа\n\nТекущий"},
+		{"counts runes, not bytes", 10, 0, "Коротко?\n\nДа\n\nТекущий"},
+		{"nothing fits keeps current question", 1, 0, "Текущий"},
+		{"everything fits", 1000, 0, "Q1\n\nA1\n\nПокажи лог\n\n" + long + "\n\nКоротко?\n\nДа\n\nТекущий"},
+		{"minimum turns override the budget", 1, 2, "Покажи лог\n\n" + long + "\n\nКоротко?\n\nДа\n\nТекущий"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{
+				CtxSizeLimit:   100,
+				CtxCharLimit:   tt.limit,
+				PromptStyle:    config.PromptStyleMinimal,
+				PreserveTurns:  true,
+				MinRecentTurns: tt.minRecent,
+			}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildLabeledPrompt_noHistory(t *testing.T) {
+	current := model.Message{Role: model.RoleUser, Content: "Привет"}
+
+	if got := buildLabeledPrompt(nil, current); got != "Текущий вопрос: Привет" {
+		t.Errorf("buildLabeledPrompt() = %q, want only the current question", got)
+	}
+}
+
+func TestChat_buildContextPrompt_systemMessages(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleSystem, Content: "Отвечай по-русски"},
+		{Role: model.RoleUser, Content: "Q1"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+		{Role: model.RoleAssistant, Content: "A2"},
+		{Role: model.RoleUser, Content: "Q3"},
+	}
+
+	tests := []struct {
+		name  string
+		style string
+		limit int
+		want  string
+	}{
+		{
+			name:  "labeled prefix",
+			limit: 10,
+			want: "Предыдущий контекст беседы:\n" +
+				"Система: Отвечай по-русски\n" +
+				"Пользователь: Q1\nАссистент: A1\nПользователь: Q2\nАссистент: A2\n" +
+				"\nТекущий вопрос: Q3",
+		},
+		{
+			name:  "kept when trimmed away",
+			limit: 2,
+			want: "Предыдущий контекст беседы:\n" +
+				"Система: Отвечай по-русски\n" +
+				"Пользователь: Q2\nАссистент: A2\n" +
+				"\nТекущий вопрос: Q3",
+		},
+		{
+			name:  "kept with zero budget",
+			limit: 0,
+			want: "Предыдущий контекст беседы:\n" +
+				"Система: Отвечай по-русски\n" +
+				"\nТекущий вопрос: Q3",
+		},
+		{
+			name:  "chatml role",
+			style: config.PromptStyleChatML,
+			limit: 0,
+			want: "<|im_start|>system\nОтвечай по-русски<|im_end|>\n" +
+				"<|im_start|>user\nQ3<|im_end|>\n" +
+				"<|im_start|>assistant\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{CtxSizeLimit: tt.limit, PromptStyle: tt.style}}
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlignToTurnStart(t *testing.T) {
+	messages := []model.Message{
+		{Role: model.RoleAssistant, Content: "A0"},
+		{Role: model.RoleAssistant, Content: "A1"},
+		{Role: model.RoleUser, Content: "Q2"},
+	}
+
+	tests := []struct {
+		start int
+		want  int
+	}{
+		{0, 2},
+		{1, 2},
+		{2, 2},
+	}
+
+	for _, tt := range tests {
+		if got := alignToTurnStart(messages, tt.start); got != tt.want {
+			t.Errorf("alignToTurnStart(%d) = %d, want %d", tt.start, got, tt.want)
+		}
+	}
+}
+
+func largeConversation(n int) []model.Message {
+	messages := make([]model.Message, 0, n)
+	for i := 0; i < n; i++ {
+		role := model.RoleUser
+		if i%2 == 1 {
+			role = model.RoleAssistant
+		}
+		messages = append(messages, model.Message{Role: role, Content: strings.Repeat("слово ", 50), Timestamp: time.Now()})
+	}
+	return messages
+}
+
+func TestChat_assembleContext_warnsWhenSlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     time.Duration
+		wantWarn bool
+	}{
+		{"fast", time.Millisecond, false},
+		{"slow", time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: 10000})
+
+			// Каждый вызов часов сдвигает время на step
+			clock := time.Now()
+			chat.now = func() time.Time {
+				clock = clock.Add(tt.step)
+				return clock
+			}
+
+			var prompt string
+			output := captureStdout(t, func() {
+				prompt = chat.assembleContext(largeConversation(5000))
+			})
+
+			if prompt == "" {
+				t.Error("assembleContext() returned empty prompt")
+			}
+			if got := strings.Contains(output, "Сборка контекста"); got != tt.wantWarn {
+				t.Errorf("warning printed = %v, want %v (output %q)", got, tt.wantWarn, output)
+			}
+		})
+	}
+}
+
+func BenchmarkChat_buildContextPrompt(b *testing.B) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: 10000})
+	messages := largeConversation(10000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chat.buildContextPrompt(messages)
+	}
+}
+
+func TestChat_reportContext(t *testing.T) {
+	messages := largeConversation(9) // 8 сообщений истории + текущий вопрос
+
+	tests := []struct {
+		name  string
+		limit int
+		want  string
+	}{
+		{"history exceeds limit", 3, "3 сообщений истории, отброшено 5 (№1–5)"},
+		{"history fits", 20, "8 сообщений истории, ничего не отброшено"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: tt.limit, ShowContext: true})
+			chat.SetOutput(&buf)
+
+			chat.assembleContext(messages)
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("report = %q, want it to contain %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_remindEvery(t *testing.T) {
+	var messages []model.Message
+	for i := 1; i <= 5; i++ {
+		messages = append(messages, model.Message{Role: model.RoleUser, Content: fmt.Sprintf("Q%d", i)})
+		if i < 5 {
+			messages = append(messages, model.Message{Role: model.RoleAssistant, Content: fmt.Sprintf("A%d", i)})
+		}
+	}
+
+	tests := []struct {
+		name        string
+		remindEvery int
+		limit       int
+		want        string
+	}{
+		{
+			name:        "disabled",
+			remindEvery: 0,
+			limit:       10,
+			want: "Предыдущий контекст беседы:\n" +
+				"Пользователь: Q1\nАссистент: A1\nПользователь: Q2\nАссистент: A2\n" +
+				"Пользователь: Q3\nАссистент: A3\nПользователь: Q4\nАссистент: A4\n" +
+				"\nТекущий вопрос: Q5",
+		},
+		{
+			name:        "every two turns",
+			remindEvery: 2,
+			limit:       10,
+			want: "Предыдущий контекст беседы:\n" +
+				"Пользователь: Q1\nАссистент: A1\nПользователь: Q2\nАссистент: A2\n" +
+				"Напоминание: Будь краток.\n" +
+				"Пользователь: Q3\nАссистент: A3\nПользователь: Q4\nАссистент: A4\n" +
+				"Напоминание: Будь краток.\n" +
+				"\nТекущий вопрос: Q5",
+		},
+		{
+			name:        "every three turns",
+			remindEvery: 3,
+			limit:       10,
+			want: "Предыдущий контекст беседы:\n" +
+				"Пользователь: Q1\nАссистент: A1\nПользователь: Q2\nАссистент: A2\n" +
+				"Пользователь: Q3\nАссистент: A3\n" +
+				"Напоминание: Будь краток.\n" +
+				"Пользователь: Q4\nАссистент: A4\n" +
+				"\nТекущий вопрос: Q5",
+		},
+		{
+			name:        "turns counted from the start of the chat",
+			remindEvery: 3,
+			limit:       3,
+			want: "Предыдущий контекст беседы:\n" +
+				"Ассистент: A3\n" +
+				"Напоминание: Будь краток.\n" +
+				"Пользователь: Q4\nАссистент: A4\n" +
+				"\nТекущий вопрос: Q5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: tt.limit, RemindEvery: tt.remindEvery, SystemPrompt: "Будь краток."}
+			c := newTestChat(&mockAIClient{}, cfg)
+
+			if got := c.buildContextPrompt(messages); got != tt.want {
+				t.Errorf("buildContextPrompt() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_buildContextPrompt_reminderText(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, RemindEvery: 1, SystemPrompt: "Длинный промпт", ReminderText: "Помни о стиле."}
+	c := newTestChat(&mockAIClient{}, cfg)
+
+	got := c.buildContextPrompt(smallConversation())
+	if !strings.Contains(got, "Напоминание: Помни о стиле.\n") {
+		t.Errorf("prompt should contain REMINDER_TEXT, got %q", got)
+	}
+	if strings.Contains(got, "Длинный промпт") {
+		t.Errorf("prompt should not repeat the system prompt when REMINDER_TEXT is set, got %q", got)
+	}
+}
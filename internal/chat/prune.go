@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// prune удаляет из сессии сообщения старше указанного срока и сохраняет её.
+// Перед удалением спрашивает подтверждение.
+func (c *Chat) prune(args string) error {
+	age, err := parseAge(args)
+	if err != nil {
+		return err
+	}
+
+	if !c.confirm(fmt.Sprintf("🧹 Удалить сообщения старше %s", args)) {
+		fmt.Fprintln(c.output(), "Отменено")
+		return nil
+	}
+
+	removed := c.session.PruneBefore(time.Now().Add(-age))
+	if removed == 0 {
+		fmt.Fprintln(c.output(), "🧹 Нет сообщений старше указанного срока")
+		return nil
+	}
+
+	fmt.Fprintf(c.output(), "🧹 Удалено сообщений: %d\n", removed)
+	return c.saveSession()
+}
+
+// parseAge разбирает срок вида «30d», «12h» или «1d12h». Кроме единиц
+// time.ParseDuration поддерживаются дни (d).
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("%w: укажите срок, например /prune 30d", errors.ErrInvalidAge)
+	}
+
+	var days time.Duration
+	if before, after, ok := strings.Cut(value, "d"); ok {
+		n, err := strconv.Atoi(before)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%w: %q", errors.ErrInvalidAge, value)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		value = after
+	}
+
+	var rest time.Duration
+	if value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("%w: %q", errors.ErrInvalidAge, value)
+		}
+		rest = d
+	}
+
+	return days + rest, nil
+}
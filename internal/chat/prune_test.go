@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	stderrors "errors"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"", 0, true},
+		{"xd", 0, true},
+		{"week", 0, true},
+		{"-5h", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseAge(tt.input)
+			if tt.wantErr {
+				if !stderrors.Is(err, errors.ErrInvalidAge) {
+					t.Errorf("parseAge(%q) error = %v, want ErrInvalidAge", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAge(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseAge(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_prune(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+
+	now := time.Now()
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "год назад", Timestamp: now.AddDate(-1, 0, 0)},
+		{Role: model.RoleAssistant, Content: "40 дней назад", Timestamp: now.AddDate(0, 0, -40)},
+		{Role: model.RoleUser, Content: "неделю назад", Timestamp: now.AddDate(0, 0, -7)},
+		{Role: model.RoleAssistant, Content: "сейчас", Timestamp: now},
+	}
+
+	handled, err := chat.handleCommand("/prune! 30d")
+	if !handled || err != nil {
+		t.Fatalf("handleCommand(/prune! 30d) = %v, %v", handled, err)
+	}
+
+	want := []string{"неделю назад", "сейчас"}
+	if len(chat.session.Messages) != len(want) {
+		t.Fatalf("messages after prune = %d, want %d", len(chat.session.Messages), len(want))
+	}
+	for i, content := range want {
+		if chat.session.Messages[i].Content != content {
+			t.Errorf("message[%d] = %q, want %q", i, chat.session.Messages[i].Content, content)
+		}
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if len(loaded.Messages) != len(want) {
+		t.Errorf("persisted messages = %d, want %d", len(loaded.Messages), len(want))
+	}
+}
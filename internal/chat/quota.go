@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"agent/internal/session"
+	"fmt"
+	"strings"
+)
+
+// confirmQuota спрашивает, можно ли удалить самые старые сессии, чтобы
+// сохранить новую при MAX_SESSIONS и SESSION_QUOTA_POLICY=delete_oldest.
+// С --yes удаляет без вопроса. При завершении по сигналу ввод читает
+// основной цикл, поэтому без --yes сохранение отклоняется.
+func (c *Chat) confirmQuota(oldest []session.SessionInfo) bool {
+	c.genMu.Lock()
+	closing := c.closing
+	c.genMu.Unlock()
+	if closing && !c.cfg.AssumeYes {
+		return false
+	}
+
+	names := make([]string, len(oldest))
+	for i, info := range oldest {
+		names[i] = info.Name
+	}
+	list := strings.Join(names, ", ")
+
+	if !c.confirm(fmt.Sprintf("🗑️  Достигнут лимит сессий (MAX_SESSIONS=%d). Удалить самые старые: %s", c.cfg.MaxSessions, list)) {
+		return false
+	}
+	if !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "🗑️  Удаляем старые сессии: %s\n", list)
+	}
+	return true
+}
+
+// warnTrimmed сообщает, сколько старых сообщений удалено при сохранении,
+// чтобы сессия уложилась в MAX_SESSION_BYTES (SESSION_SIZE_POLICY=trim).
+func (c *Chat) warnTrimmed(removed int) {
+	fmt.Fprintf(c.output(), "⚠️  Сессия превысила MAX_SESSION_BYTES=%d, удалено старых сообщений: %d\n", c.cfg.MaxSessionBytes, removed)
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"agent/internal/session"
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_confirmQuota(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		assumeYes   bool
+		wantDeleted bool
+	}{
+		{"confirmed", "y\n", false, true},
+		{"declined", "n\n", false, false},
+		{"assume yes", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10,
+				MaxSessions: 1, QuotaPolicy: config.QuotaDeleteOldest, AssumeYes: tt.assumeYes}
+
+			old, err := session.NewChatSession("old", cfg)
+			if err != nil {
+				t.Fatalf("NewChatSession() error = %v", err)
+			}
+			old.Messages = []model.Message{{Role: model.RoleUser, Content: "Привет", Timestamp: time.Now()}}
+			if err := old.SaveSession(old); err != nil {
+				t.Fatalf("SaveSession() error = %v", err)
+			}
+
+			chatSession, err := session.NewChatSession("new", cfg)
+			if err != nil {
+				t.Fatalf("NewChatSession() error = %v", err)
+			}
+			if !session.SessionExists("old", cfg) {
+				t.Fatal("opening a new session must not delete old ones")
+			}
+
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					return fn(api.GenerateResponse{Response: "Ответ"})
+				},
+			}
+			chat := newChat(client, cfg, chatSession)
+			var output strings.Builder
+			chat.SetOutput(&output)
+			chat.input = bufio.NewScanner(strings.NewReader(tt.input))
+
+			if err := chat.processUserInput("Вопрос"); err != nil {
+				t.Fatalf("processUserInput() error = %v", err)
+			}
+
+			if deleted := !session.SessionExists("old", cfg); deleted != tt.wantDeleted {
+				t.Errorf("old session deleted = %t, want %t; output:\n%s", deleted, tt.wantDeleted, output.String())
+			}
+			if saved := session.SessionExists("new", cfg); saved != tt.wantDeleted {
+				t.Errorf("new session saved = %t, want %t", saved, tt.wantDeleted)
+			}
+			if !tt.wantDeleted && !strings.Contains(output.String(), "превышено максимальное количество сессий") {
+				t.Errorf("output = %q, want the quota error", output.String())
+			}
+		})
+	}
+}
+
+func TestChat_warnTrimmed(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10,
+		MaxSessionBytes: 600, SessionSizePolicy: config.SizeTrim}
+
+	chatSession, err := session.NewChatSession("user", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	for range 6 {
+		chatSession.Messages = append(chatSession.Messages,
+			model.Message{Role: model.RoleUser, Content: strings.Repeat("слово ", 20), Timestamp: time.Now()})
+	}
+
+	chat := newChat(&mockAIClient{}, cfg, chatSession)
+	var output strings.Builder
+	chat.SetOutput(&output)
+	if err := chat.saveSession(); err != nil {
+		t.Fatalf("saveSession() error = %v", err)
+	}
+
+	if !strings.Contains(output.String(), "MAX_SESSION_BYTES=600, удалено старых сообщений") {
+		t.Errorf("output = %q, want the trim notice", output.String())
+	}
+}
@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"agent/internal/model"
+	"fmt"
+	"strconv"
+)
+
+// regen заново генерирует ответ ассистента с номером n (как в истории,
+// с 1), используя только контекст до вопроса перед ним. Остальные сообщения,
+// в том числе более поздние, не меняются.
+func (c *Chat) regen(args string) error {
+	messages := c.session.Messages
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 2 || n > len(messages) || messages[n-1].IsUser() || !messages[n-2].IsUser() {
+		return fmt.Errorf("%w: /regen %s, укажите номер ответа ассистента после вопроса (1–%d)",
+			errors.ErrInvalidOption, args, len(messages))
+	}
+
+	prefix := append([]model.Message(nil), messages[:n-1]...)
+	later := messages[n:]
+	if len(later) > 0 {
+		fmt.Fprintf(c.output(), "⚠️  После этого ответа в истории ещё %d сообщений: они писались с учётом старого ответа и могут ему больше не соответствовать\n", len(later))
+	}
+
+	// Сессия временно укорочена до вопроса, поэтому автосохранение отключено,
+	// чтобы на диск не попала обрезанная история
+	c.beginRegeneration(messages)
+	c.session.Messages = prefix
+	err = c.sendMessage(prefix)
+	c.endRegeneration()
+
+	if err != nil || len(c.session.Messages) != len(prefix)+1 {
+		c.session.Messages = messages
+		return err
+	}
+
+	c.session.Messages = append(c.session.Messages, later...)
+	return c.saveSession()
+}
+
+// beginRegeneration отключает автосохранение и запоминает полную историю
+// перед тем, как сессия будет временно укорочена: если сигнал завершения
+// придёт на середине, Close сохранит её, а не обрезанную.
+func (c *Chat) beginRegeneration(full []model.Message) {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	c.regenerating = true
+	c.fullHistory = full
+}
+
+// endRegeneration снова включает автосохранение после /regen или /replay.
+func (c *Chat) endRegeneration() {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	c.regenerating = false
+	c.fullHistory = nil
+}
@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"context"
+	stderrors "errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_regen(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var prompt string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompt = req.Prompt
+			return fn(api.GenerateResponse{Response: "Новый ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Первый вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Первый ответ", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Второй вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Второй ответ", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Третий вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Третий ответ", Timestamp: time.Now()},
+	}
+
+	var output strings.Builder
+	chat.SetOutput(&output)
+	if handled, err := chat.handleCommand("/regen 4"); !handled || err != nil {
+		t.Fatalf("handleCommand(/regen 4) = %v, %v", handled, err)
+	}
+
+	want := []string{"Первый вопрос", "Первый ответ", "Второй вопрос", "Новый ответ", "Третий вопрос", "Третий ответ"}
+	if len(chat.session.Messages) != len(want) {
+		t.Fatalf("session messages = %+v, want %d messages", chat.session.Messages, len(want))
+	}
+	for i, content := range want {
+		if chat.session.Messages[i].Content != content {
+			t.Errorf("message %d = %q, want %q", i+1, chat.session.Messages[i].Content, content)
+		}
+	}
+
+	if !strings.Contains(prompt, "Второй вопрос") || strings.Contains(prompt, "Третий") || strings.Contains(prompt, "Второй ответ") {
+		t.Errorf("regen prompt must contain only the context up to the question, got %q", prompt)
+	}
+	if !strings.Contains(output.String(), "ещё 2 сообщений") {
+		t.Errorf("regen should warn about later messages, got %q", output.String())
+	}
+}
+
+func TestChat_regen_invalidIndex(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{CtxSizeLimit: 10})
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Ответ", Timestamp: time.Now()},
+	}
+
+	for _, args := range []string{"", "abc", "0", "1", "3"} {
+		if err := chat.regen(args); !stderrors.Is(err, errors.ErrInvalidOption) {
+			t.Errorf("regen(%q) error = %v, want ErrInvalidOption", args, err)
+		}
+	}
+	if len(chat.session.Messages) != 2 || chat.session.Messages[1].Content != "Ответ" {
+		t.Errorf("invalid regen must not change the session, got %+v", chat.session.Messages)
+	}
+}
+
+func TestChat_regen_closeKeepsFullHistory(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var chat *Chat
+	var saved []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			// Сигнал завершения приходит, пока история укорочена до вопроса
+			if err := chat.Close(); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+			filepath.WalkDir(cfg.CtxDir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					data, _ := os.ReadFile(path)
+					saved = append(saved, string(data))
+				}
+				return nil
+			})
+			return fn(api.GenerateResponse{Response: "Новый ответ"})
+		},
+	}
+
+	chat = newTestChat(client, cfg)
+	chat.SetOutput(&strings.Builder{})
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Первый вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Первый ответ", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Второй вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Второй ответ", Timestamp: time.Now()},
+	}
+	if err := chat.regen("2"); err != nil {
+		t.Fatalf("regen(2) error = %v", err)
+	}
+
+	if len(saved) != 1 {
+		t.Fatalf("saved files = %d, want 1", len(saved))
+	}
+	if !strings.Contains(saved[0], "Второй ответ") {
+		t.Errorf("Close during /regen must save the full history, got %s", saved[0])
+	}
+}
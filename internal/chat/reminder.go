@@ -0,0 +1,55 @@
+package chat
+
+import "agent/internal/model"
+
+// reminderRole — роль напоминания в собранном контексте. В сессии такие
+// сообщения не хранятся; модели они передаются как системные.
+const reminderRole = "reminder"
+
+// withReminders возвращает историю контекста messages[start:last] и при
+// REMIND_EVERY=N вставляет напоминание об инструкциях после каждого N-го хода
+// пользователя. Ходы считаются от начала разговора, поэтому напоминания стоят
+// на тех же местах независимо от того, какая часть истории вошла в контекст.
+// Если текущий вопрос открывает очередной интервал, напоминание идёт
+// последним в истории.
+func (c *Chat) withReminders(messages []model.Message, start int) []model.Message {
+	last := len(messages) - 1
+	history := messages[start:last]
+
+	if c.cfg.RemindEvery <= 0 {
+		return history
+	}
+	reminder := c.reminderText()
+	if reminder == "" {
+		return history
+	}
+
+	turn := 0
+	for _, msg := range messages[:start] {
+		if msg.IsUser() {
+			turn++
+		}
+	}
+
+	// Текущий вопрос проходит через цикл, чтобы перед ним встало
+	// напоминание, и затем отрезается: его добавляет сборщик промпта.
+	result := make([]model.Message, 0, len(history)+len(history)/c.cfg.RemindEvery+2)
+	for _, msg := range messages[start:] {
+		if msg.IsUser() {
+			if turn > 0 && turn%c.cfg.RemindEvery == 0 {
+				result = append(result, model.Message{Role: reminderRole, Content: reminder})
+			}
+			turn++
+		}
+		result = append(result, msg)
+	}
+	return result[:len(result)-1]
+}
+
+// reminderText возвращает REMINDER_TEXT или, если он пуст, системный промпт.
+func (c *Chat) reminderText() string {
+	if c.cfg.ReminderText != "" {
+		return c.cfg.ReminderText
+	}
+	return c.expandSystemPrompt(c.systemPrompt())
+}
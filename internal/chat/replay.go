@@ -0,0 +1,64 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"agent/internal/model"
+	"fmt"
+	"time"
+)
+
+// replay заново генерирует все ответы сессии по порядку с текущими
+// настройками: вопросы пользователя и закреплённые системные инструкции
+// сохраняются на своих местах, ответы ассистента заменяются новыми. Перед
+// запуском спрашивает подтверждение.
+func (c *Chat) replay() error {
+	var kept []model.Message
+	questions := 0
+	for _, msg := range c.session.Messages {
+		if msg.IsUser() || msg.IsSystem() {
+			kept = append(kept, msg)
+		}
+		if msg.IsUser() {
+			questions++
+		}
+	}
+	if questions == 0 {
+		return errors.ErrNothingToRetry
+	}
+
+	prompt := fmt.Sprintf("🔁 Перегенерировать ответы на %d вопросов? Текущие ответы будут заменены", questions)
+	if !c.confirm(prompt) {
+		fmt.Fprintln(c.output(), "Отменено")
+		return nil
+	}
+
+	// История пересобирается по одному ответу, поэтому автосохранение
+	// отключено, а при ошибке на полпути возвращается исходная история
+	original := c.session.Messages
+	c.beginRegeneration(original)
+	defer c.endRegeneration()
+
+	c.session.Messages = nil
+	asked := 0
+	for _, msg := range kept {
+		c.session.Messages = append(c.session.Messages, msg)
+		if msg.IsSystem() {
+			continue
+		}
+
+		asked++
+		fmt.Fprintf(c.output(), "\n[%d/%d] Вы: %s\n", asked, questions, msg.Content)
+
+		c.session.Updated = time.Now()
+		want := len(c.session.Messages) + 1
+		if err := c.sendMessage(c.session.Messages); err != nil || len(c.session.Messages) != want {
+			c.session.Messages = original
+			if err == nil {
+				err = fmt.Errorf("%w: вопрос %d из %d", errors.ErrNoResponse, asked, questions)
+			}
+			return err
+		}
+		fmt.Fprintln(c.output())
+	}
+	return c.saveSession()
+}
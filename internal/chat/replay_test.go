@@ -0,0 +1,141 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bufio"
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_replay(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	var prompts []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompts = append(prompts, req.Prompt)
+			return fn(api.GenerateResponse{Response: fmt.Sprintf("новый ответ %d", len(prompts))})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	chat.input = bufio.NewScanner(strings.NewReader("y\n"))
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "вопрос 1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ 1", Timestamp: time.Now()},
+		{Role: model.RoleSystem, Content: "отвечай кратко", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "вопрос 2", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ 2", Timestamp: time.Now()},
+	}
+
+	if err := chat.replay(); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("Generate called %d times, want 2", len(prompts))
+	}
+	if strings.Contains(prompts[0], "вопрос 2") || strings.Contains(prompts[0], "старый ответ") {
+		t.Errorf("first regeneration should see only the first question:\n%s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "новый ответ 1") || strings.Contains(prompts[1], "старый ответ") {
+		t.Errorf("second regeneration should see the replayed first answer:\n%s", prompts[1])
+	}
+
+	if !strings.Contains(prompts[1], "отвечай кратко") {
+		t.Errorf("second regeneration should see the pinned system instruction:\n%s", prompts[1])
+	}
+
+	want := []string{"вопрос 1", "новый ответ 1", "отвечай кратко", "вопрос 2", "новый ответ 2"}
+	messages := chat.session.Messages
+	if len(messages) != len(want) {
+		t.Fatalf("len(Messages) = %d, want %d", len(messages), len(want))
+	}
+	for i, content := range want {
+		if messages[i].Content != content {
+			t.Errorf("Messages[%d] = %q, want %q", i, messages[i].Content, content)
+		}
+	}
+}
+
+func TestChat_replay_declined(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10}
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			t.Error("Generate should not be called when replay is declined")
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	chat.input = bufio.NewScanner(strings.NewReader("n\n"))
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "вопрос", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "ответ", Timestamp: time.Now()},
+	}
+
+	if err := chat.replay(); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if len(chat.session.Messages) != 2 || chat.session.Messages[1].Content != "ответ" {
+		t.Errorf("Messages changed after declined replay: %+v", chat.session.Messages)
+	}
+}
+
+func TestChat_replay_failureKeepsHistory(t *testing.T) {
+	cfg := &config.Config{CtxSizeLimit: 10, CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	calls := 0
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			calls++
+			if calls == 2 {
+				return stderrors.New("connection refused")
+			}
+			return fn(api.GenerateResponse{Response: "новый ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+	chat.input = bufio.NewScanner(strings.NewReader("y\n"))
+	original := []model.Message{
+		{Role: model.RoleUser, Content: "вопрос 1", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ 1", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "вопрос 2", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ 2", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "вопрос 3", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "старый ответ 3", Timestamp: time.Now()},
+	}
+	chat.session.Messages = append([]model.Message(nil), original...)
+
+	if err := chat.replay(); err == nil {
+		t.Fatal("replay() error = nil, want the failed request error")
+	}
+
+	messages := chat.session.Messages
+	if len(messages) != len(original) {
+		t.Fatalf("len(Messages) = %d, want the original %d", len(messages), len(original))
+	}
+	for i := range original {
+		if messages[i].Content != original[i].Content {
+			t.Errorf("Messages[%d] = %q, want %q", i, messages[i].Content, original[i].Content)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(cfg.CtxDir, "*"))
+	if len(matches) != 0 {
+		t.Errorf("replay saved a partial history: %v", matches)
+	}
+}
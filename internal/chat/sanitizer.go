@@ -0,0 +1,32 @@
+package chat
+
+// Sanitizer применяет политики встраивающего приложения к тексту, который
+// уходит модели: вырезает персональные данные, блокирует запрещённое
+// содержимое и т. п. Ошибка отклоняет ввод или запрос целиком.
+type Sanitizer interface {
+	// SanitizeInput обрабатывает сообщение пользователя до того, как оно
+	// попадёт в сессию.
+	SanitizeInput(input string) (string, error)
+	// SanitizePrompt обрабатывает собранный промпт перед отправкой модели.
+	SanitizePrompt(prompt string) (string, error)
+}
+
+// NoopSanitizer пропускает текст без изменений — поведение по умолчанию.
+type NoopSanitizer struct{}
+
+func (NoopSanitizer) SanitizeInput(input string) (string, error) { return input, nil }
+
+func (NoopSanitizer) SanitizePrompt(prompt string) (string, error) { return prompt, nil }
+
+// SetSanitizer задаёт политику обработки ввода и промптов; nil возвращает
+// NoopSanitizer.
+func (c *Chat) SetSanitizer(s Sanitizer) {
+	c.sanitize = s
+}
+
+func (c *Chat) sanitizer() Sanitizer {
+	if c.sanitize != nil {
+		return c.sanitize
+	}
+	return NoopSanitizer{}
+}
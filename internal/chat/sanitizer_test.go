@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"context"
+	stderrors "errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+// fakeSanitizer маскирует адреса почты во вводе, помечает промпт и
+// отклоняет ввод со словом «секрет».
+type fakeSanitizer struct {
+	inputs, prompts int
+}
+
+var emailPattern = regexp.MustCompile(`\S+@\S+`)
+
+func (s *fakeSanitizer) SanitizeInput(input string) (string, error) {
+	s.inputs++
+	if strings.Contains(input, "секрет") {
+		return "", stderrors.New("запрещённое слово")
+	}
+	return emailPattern.ReplaceAllString(input, "[email]"), nil
+}
+
+func (s *fakeSanitizer) SanitizePrompt(prompt string) (string, error) {
+	s.prompts++
+	return "[checked] " + prompt, nil
+}
+
+func TestChat_sanitizer(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+
+	var prompts []string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompts = append(prompts, req.Prompt)
+			return fn(api.GenerateResponse{Response: "Ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	sanitizer := &fakeSanitizer{}
+	chat.SetSanitizer(sanitizer)
+
+	captureStdout(t, func() {
+		if err := chat.processUserInput("Напиши на ivan@example.com"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+	})
+
+	if sanitizer.inputs != 1 || sanitizer.prompts != 1 {
+		t.Errorf("sanitizer calls = %d inputs, %d prompts, want 1 and 1", sanitizer.inputs, sanitizer.prompts)
+	}
+	if got := chat.session.Messages[0].Content; got != "Напиши на [email]" {
+		t.Errorf("saved question = %q, want sanitized input", got)
+	}
+	if len(prompts) != 1 || !strings.HasPrefix(prompts[0], "[checked] ") || strings.Contains(prompts[0], "ivan@example.com") {
+		t.Errorf("request prompt = %q, want sanitized prompt", prompts)
+	}
+
+	err := chat.processUserInput("Вот секрет")
+	if !stderrors.Is(err, errors.ErrInputRejected) {
+		t.Errorf("rejected input error = %v, want ErrInputRejected", err)
+	}
+	if len(chat.session.Messages) != 2 || len(prompts) != 1 {
+		t.Errorf("rejected input must not reach the session or the model, got %d messages, %d requests",
+			len(chat.session.Messages), len(prompts))
+	}
+}
+
+func TestNoopSanitizer(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+	if _, ok := chat.sanitizer().(NoopSanitizer); !ok {
+		t.Fatalf("default sanitizer = %T, want NoopSanitizer", chat.sanitizer())
+	}
+
+	text := "почта ivan@example.com"
+	if got, err := chat.sanitizer().SanitizeInput(text); got != text || err != nil {
+		t.Errorf("SanitizeInput() = %q, %v", got, err)
+	}
+	if got, err := chat.sanitizer().SanitizePrompt(text); got != text || err != nil {
+		t.Errorf("SanitizePrompt() = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"agent/internal/session"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// sessions выводит список сохранённых сессий, а с номером — переключается
+// на выбранную, предварительно сохранив текущую.
+func (c *Chat) sessions(args string) error {
+	list, err := session.ListSessions(c.cfg)
+	if err != nil {
+		return err
+	}
+
+	if args == "" {
+		c.printSessions(list)
+		return nil
+	}
+
+	n, err := strconv.Atoi(args)
+	if err != nil || n < 1 || n > len(list) {
+		return fmt.Errorf("%w: номер сессии %q, доступно %d", errors.ErrInvalidOption, args, len(list))
+	}
+	return c.switchSession(list[n-1])
+}
+
+func (c *Chat) printSessions(list []session.SessionInfo) {
+	PrintSessions(c.output(), list, c.session.FilePath())
+	if len(list) > 0 {
+		fmt.Fprintln(c.output(), "Введите /sessions <номер>, чтобы переключиться")
+	}
+}
+
+// PrintSessions выводит пронумерованный список сессий; сессия по пути
+// current отмечается звёздочкой.
+func PrintSessions(w io.Writer, list []session.SessionInfo, current string) {
+	if len(list) == 0 {
+		fmt.Fprintln(w, "📭 Сохранённых сессий нет")
+		return
+	}
+
+	fmt.Fprintln(w, "🗂️  Сессии:")
+	for i, info := range list {
+		marker := " "
+		if info.Path == current {
+			marker = "*"
+		}
+		category := ""
+		if info.Category != "" {
+			category = " [" + info.Category + "]"
+		}
+		fmt.Fprintf(w, " %s %2d. %s%s (%s)\n", marker, i+1, info.Name, category, info.ModTime.Format("2006-01-02 15:04"))
+	}
+}
+
+// switchSession сохраняет текущую сессию и открывает выбранную.
+func (c *Chat) switchSession(info session.SessionInfo) error {
+	if info.Path == c.session.FilePath() {
+		fmt.Fprintf(c.output(), "📂 Сессия %s уже открыта\n", info.Name)
+		return nil
+	}
+
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	next, err := session.OpenSessionFile(info.Path, c.cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrSessionInit, err)
+	}
+
+	c.session = next
+	c.lastResponse = ""
+	fmt.Fprintf(c.output(), "📂 Переключились на сессию %s (%d сообщений)\n", next.UserName, len(next.Messages))
+	return nil
+}
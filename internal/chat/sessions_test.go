@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"agent/internal/session"
+	"bytes"
+	stderrors "errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_sessions_switch(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	other, err := session.NewChatSession("other", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	other.Messages = []model.Message{{Role: model.RoleUser, Content: "из другой сессии", Timestamp: time.Now()}}
+	if err := other.SaveSession(other); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(other.FilePath(), old, old); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "несохранённое", Timestamp: time.Now()}}
+	if err := chat.session.SaveSession(chat.session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	chat.session.Messages = append(chat.session.Messages, model.Message{Role: model.RoleAssistant, Content: "ответ", Timestamp: time.Now()})
+
+	// 1 — текущая (изменена только что), 2 — other
+	if handled, err := chat.handleCommand("/sessions 2"); !handled || err != nil {
+		t.Fatalf("handleCommand(/sessions 2) = %v, %v", handled, err)
+	}
+
+	if chat.session.UserName != "other" || len(chat.session.Messages) != 1 {
+		t.Errorf("switched session = %s with %d messages, want other with 1", chat.session.UserName, len(chat.session.Messages))
+	}
+
+	saved, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading previous session: %v", err)
+	}
+	if len(saved.Messages) != 2 {
+		t.Errorf("previous session saved with %d messages, want 2", len(saved.Messages))
+	}
+}
+
+func TestChat_sessions_invalidIndex(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"})
+
+	for _, args := range []string{"/sessions 0", "/sessions 5", "/sessions abc"} {
+		if _, err := chat.handleCommand(args); !stderrors.Is(err, errors.ErrInvalidOption) {
+			t.Errorf("handleCommand(%q) error = %v, want ErrInvalidOption", args, err)
+		}
+	}
+}
+
+func TestChat_category(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+
+	output := captureStdout(t, func() {
+		if handled, err := chat.handleCommand("/category work"); !handled || err != nil {
+			t.Fatalf("handleCommand(/category work) = %v, %v", handled, err)
+		}
+	})
+	if !strings.Contains(output, "work") {
+		t.Errorf("/category work printed %q", output)
+	}
+
+	list, err := session.ListSessions(cfg)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(list) != 1 || list[0].Category != "work" {
+		t.Fatalf("ListSessions() = %+v, want one session in category work", list)
+	}
+
+	var buf bytes.Buffer
+	PrintSessions(&buf, list, "")
+	if !strings.Contains(buf.String(), "testuser [work]") {
+		t.Errorf("PrintSessions() = %q, want the category next to the name", buf.String())
+	}
+
+	captureStdout(t, func() {
+		chat.handleCommand("/category clear")
+	})
+	if chat.session.Category != "" {
+		t.Errorf("Category after clear = %q, want empty", chat.session.Category)
+	}
+}
@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+)
+
+// Close сохраняет текущую сессию. Вызывается при завершении работы; если в
+// этот момент идёт автосохранение, Close дожидается его окончания. Во время
+// /regen и /replay сохраняется полная история, а не временно укороченная.
+func (c *Chat) Close() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	session := c.session
+	if c.regenerating {
+		snapshot := *c.session
+		snapshot.Messages = c.fullHistory
+		session = &snapshot
+	}
+	if len(session.Messages) == 0 {
+		return nil
+	}
+	return c.session.SaveSession(session)
+}
+
+// saveSession сохраняет сессию под saveMu, чтобы запись команды не
+// пересеклась с сохранением при завершении по сигналу.
+func (c *Chat) saveSession() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+	return c.session.SaveSession(c.session)
+}
+
+// HandleShutdown ждёт сигнал завершения (SIGINT/SIGTERM), сохраняет сессию
+// и вызывает exit. Нужен для контейнеров, где процесс останавливают
+// SIGTERM: без него сессия могла быть потеряна на середине записи.
+// Ctrl+C во время генерации только прерывает ответ; повторный Ctrl+C
+// в течение interruptWindow завершает программу.
+func (c *Chat) HandleShutdown(signals <-chan os.Signal, exit func(code int)) {
+	sig, ok := <-signals
+	for ok && sig == os.Interrupt && c.interruptGeneration() {
+		sig, ok = <-signals
+	}
+	if !ok {
+		return
+	}
+
+	c.genMu.Lock()
+	c.closing = true
+	c.genMu.Unlock()
+
+	fmt.Printf("\n🛑 Получен сигнал %v, сохраняем сессию...\n", sig)
+	if err := c.Close(); err != nil {
+		fmt.Printf("⚠️  Ошибка сохранения сессии: %v\n", err)
+		exit(1)
+		return
+	}
+	exit(0)
+}
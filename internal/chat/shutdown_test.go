@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_HandleShutdown_savesSession(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Hello", Timestamp: time.Now()},
+	}
+
+	signals := make(chan os.Signal, 1)
+	exitCode := make(chan int, 1)
+
+	go chat.HandleShutdown(signals, func(code int) { exitCode <- code })
+	signals <- syscall.SIGTERM
+
+	select {
+	case code := <-exitCode:
+		if code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("HandleShutdown did not exit after the signal")
+	}
+
+	if _, err := os.Stat(chat.session.FilePath()); err != nil {
+		t.Errorf("session should be saved on shutdown: %v", err)
+	}
+}
+
+func TestChat_HandleShutdown_closedChannel(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+
+	signals := make(chan os.Signal)
+	close(signals)
+
+	chat.HandleShutdown(signals, func(code int) {
+		t.Errorf("exit should not be called when the channel is closed, got code %d", code)
+	})
+}
+
+func TestChat_HandleShutdown_waitsForInFlightSave(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Hello", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Hi", Timestamp: time.Now()},
+	}
+
+	// Медленное автосохранение: держит блокировку, пока не закончит запись
+	saving := make(chan struct{})
+	var saved atomic.Bool
+	go func() {
+		chat.saveMu.Lock()
+		defer chat.saveMu.Unlock()
+		close(saving)
+		time.Sleep(100 * time.Millisecond)
+		if err := chat.session.AutoSave(); err != nil {
+			t.Errorf("AutoSave() error = %v", err)
+		}
+		saved.Store(true)
+	}()
+	<-saving
+
+	signals := make(chan os.Signal, 1)
+	exitCode := make(chan int, 1)
+	go chat.HandleShutdown(signals, func(code int) {
+		if !saved.Load() {
+			t.Error("shutdown exited before the in-flight save finished")
+		}
+		exitCode <- code
+	})
+	signals <- syscall.SIGTERM
+
+	select {
+	case code := <-exitCode:
+		if code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("HandleShutdown did not exit after the signal")
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(cfg.CtxDir, "*.tmp-*"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left after save: %v", matches)
+	}
+}
+
+func TestChat_HandleShutdown_interruptsGeneration(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+	started := make(chan struct{})
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Длинный ответ, "})
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	signals := make(chan os.Signal, 1)
+	defer close(signals)
+	go chat.HandleShutdown(signals, func(code int) {
+		t.Errorf("Ctrl+C during generation should not exit, got code %d", code)
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- chat.processUserInput("Расскажи всё") }()
+	<-started
+	signals <- os.Interrupt
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("processUserInput() error = %v, want nil after interruption", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("generation was not interrupted")
+	}
+
+	if !strings.Contains(output.String(), "⏹️  Генерация прервана") {
+		t.Errorf("output = %q, want the interruption notice", output.String())
+	}
+	if len(chat.session.Messages) != 2 {
+		t.Fatalf("messages = %d, want the question and the partial answer", len(chat.session.Messages))
+	}
+	answer := chat.session.Messages[1]
+	if answer.Content != "Длинный ответ, " || answer.Truncated != model.TruncatedInterrupted {
+		t.Errorf("answer = %q (truncated %q), want the partial answer marked interrupted", answer.Content, answer.Truncated)
+	}
+}
+
+func TestChat_HandleShutdown_secondInterruptExits(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			close(started)
+			<-release // сервер не реагирует на отмену
+			return ctx.Err()
+		},
+	}
+	chat := newTestChat(client, cfg)
+	chat.SetOutput(&strings.Builder{})
+
+	signals := make(chan os.Signal, 2)
+	exitCode := make(chan int, 1)
+	go chat.HandleShutdown(signals, func(code int) { exitCode <- code })
+
+	go chat.processUserInput("Hi")
+	<-started
+	signals <- os.Interrupt
+	signals <- os.Interrupt
+
+	select {
+	case code := <-exitCode:
+		if code != 0 {
+			t.Errorf("exit code = %d, want 0", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second Ctrl+C did not exit")
+	}
+}
+
+func TestChat_interruptGeneration_newGeneration(t *testing.T) {
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	defer cancelFirst()
+	chat.setGeneration(cancelFirst)
+	if !chat.interruptGeneration() || first.Err() == nil {
+		t.Fatal("first Ctrl+C did not cancel the generation")
+	}
+	chat.setGeneration(nil)
+
+	// Следующий вопрос задан сразу, в пределах interruptWindow
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	chat.setGeneration(cancelSecond)
+	if !chat.interruptGeneration() || second.Err() == nil {
+		t.Fatal("Ctrl+C on a new generation exited instead of cancelling it")
+	}
+	if chat.interruptGeneration() {
+		t.Error("repeated Ctrl+C on the same generation should exit")
+	}
+}
@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinner показывает анимацию «модель печатает», пока не пришёл первый
+// фрагмент ответа. Каждый кадр затирается возвратом курсора, поэтому
+// после Stop в выводе не остаётся следов.
+type spinner struct {
+	out      io.Writer
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newSpinner(out io.Writer, interval time.Duration) *spinner {
+	return &spinner{
+		out:      out,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start запускает анимацию в отдельной горутине.
+func (s *spinner) Start() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for frame := 0; ; frame++ {
+			fmt.Fprint(s.out, spinnerFrames[frame%len(spinnerFrames)]+"\b")
+			select {
+			case <-s.stop:
+				fmt.Fprint(s.out, " \b")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop останавливает анимацию и ждёт, пока кадр будет стёрт. Повторные
+// вызовы безопасны; вызывать можно только после Start.
+func (s *spinner) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
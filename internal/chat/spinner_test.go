@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer — потокобезопасный буфер для вывода спиннера.
+type syncBuffer struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sb.String()
+}
+
+func TestSpinner_startsAndStopsOnFirstOutput(t *testing.T) {
+	var out syncBuffer
+	s := newSpinner(&out, time.Millisecond)
+	s.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for strings.Count(out.String(), "\b") < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("spinner did not animate, output %q", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Первый фрагмент ответа останавливает спиннер
+	s.Stop()
+	stopped := out.String()
+	if !strings.HasSuffix(stopped, " \b") {
+		t.Errorf("spinner should erase its last frame, output ends with %q", stopped[len(stopped)-4:])
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if out.String() != stopped {
+		t.Error("spinner kept writing after Stop")
+	}
+
+	s.Stop() // повторный вызов не должен паниковать
+}
@@ -0,0 +1,95 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stallWatchdog отменяет запрос, если от модели не приходит ни одного
+// фрагмента дольше timeout. Каждый полученный фрагмент продлевает срок,
+// поэтому длинные, но непрерывные ответы не обрываются.
+type stallWatchdog struct {
+	timer    *time.Timer
+	timeout  time.Duration
+	stalled  atomic.Bool
+	received atomic.Bool // пришёл хотя бы один фрагмент
+
+	hintTimer *time.Timer
+	hintMu    sync.Mutex // Stop дожидается, пока допечатается подсказка
+	stopped   bool
+}
+
+// newStallWatchdog запускает сторожевой таймер. При timeout <= 0
+// ожидание не ограничено.
+func newStallWatchdog(timeout time.Duration, cancel context.CancelFunc) *stallWatchdog {
+	w := &stallWatchdog{timeout: timeout}
+	if timeout > 0 {
+		w.timer = time.AfterFunc(timeout, func() {
+			w.stalled.Store(true)
+			cancel()
+		})
+	}
+	return w
+}
+
+// HintAfter вызывает hint, если за after не пришло ни одного фрагмента:
+// сервер принял запрос, но, например, ещё загружает модель. При after <= 0
+// подсказки нет.
+func (w *stallWatchdog) HintAfter(after time.Duration, hint func()) {
+	if after <= 0 {
+		return
+	}
+	w.hintTimer = time.AfterFunc(after, func() {
+		w.hintMu.Lock()
+		defer w.hintMu.Unlock()
+		if !w.stopped && !w.received.Load() && !w.stalled.Load() {
+			hint()
+		}
+	})
+}
+
+// Reset отмечает полученный фрагмент и откладывает срабатывание ещё на
+// timeout.
+func (w *stallWatchdog) Reset() {
+	if w.received.CompareAndSwap(false, true) && w.hintTimer != nil {
+		w.hintTimer.Stop()
+	}
+	if w.timer != nil && !w.stalled.Load() {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *stallWatchdog) Stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.hintMu.Lock()
+	defer w.hintMu.Unlock()
+	w.stopped = true
+	if w.hintTimer != nil {
+		w.hintTimer.Stop()
+	}
+}
+
+// Stalled сообщает, был ли запрос отменён из-за простоя.
+func (w *stallWatchdog) Stalled() bool {
+	return w.stalled.Load()
+}
+
+// Received сообщает, прислал ли сервер хотя бы один фрагмент.
+func (w *stallWatchdog) Received() bool {
+	return w.received.Load()
+}
+
+// stallHint сообщает, что запрос принят, но ответа пока нет.
+func (c *Chat) stallHint() {
+	fmt.Fprintf(c.output(), "\n⏳ Сервер думает… Ответа нет уже %v: возможно, модель ещё загружается", c.cfg.StallHint)
+	if c.cfg.StallTimeout > 0 {
+		fmt.Fprintf(c.output(), ". Без данных запрос будет прерван через %v (STALL_TIMEOUT)", c.cfg.StallTimeout)
+	}
+	fmt.Fprintln(c.output())
+}
@@ -0,0 +1,180 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"context"
+	stderrors "errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_sendMessage_slowSteadyStreamDoesNotTimeout(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		StallTimeout: 80 * time.Millisecond,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			// Общая длительность ~200ms превышает таймаут, но паузы между фрагментами короче
+			for i := 0; i < 8; i++ {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(25 * time.Millisecond):
+				}
+				fn(api.GenerateResponse{Response: "."})
+			}
+			return nil
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+	if err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+
+	if got := chat.session.Messages[0].Content; got != "........" {
+		t.Errorf("saved response = %q, want %q", got, "........")
+	}
+}
+
+func TestChat_sendMessage_stalledStreamTimesOut(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		StallTimeout: 50 * time.Millisecond,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Начинаю..."})
+			<-ctx.Done() // сервер завис и больше ничего не присылает
+			return ctx.Err()
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+
+	if !stderrors.Is(err, errors.ErrStreamStalled) {
+		t.Fatalf("sendMessage() error = %v, want ErrStreamStalled", err)
+	}
+	if len(chat.session.Messages) != 0 {
+		t.Errorf("no messages should be saved on stall, got %d", len(chat.session.Messages))
+	}
+}
+
+func TestChat_sendMessage_noResponseShowsHintAndFails(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		StallHint:    20 * time.Millisecond,
+		StallTimeout: 100 * time.Millisecond,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			<-ctx.Done() // соединение принято, но модель так и не начала отвечать
+			return ctx.Err()
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+	if !stderrors.Is(err, errors.ErrNoResponse) {
+		t.Fatalf("sendMessage() error = %v, want ErrNoResponse", err)
+	}
+	if stderrors.Is(err, errors.ErrStreamStalled) {
+		t.Errorf("no response must be distinguished from a stalled stream, got %v", err)
+	}
+	if !strings.Contains(output.String(), "Сервер думает") {
+		t.Errorf("output = %q, want the waiting hint", output.String())
+	}
+}
+
+func TestChat_sendMessage_noHintOnceStreaming(t *testing.T) {
+	cfg := &config.Config{
+		CtxSizeLimit: 10,
+		StallHint:    30 * time.Millisecond,
+		StallTimeout: time.Second,
+	}
+
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			fn(api.GenerateResponse{Response: "Начинаю"})
+			time.Sleep(60 * time.Millisecond) // пауза после первого фрагмента — не повод для подсказки
+			return fn(api.GenerateResponse{Response: " отвечать"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	var output strings.Builder
+	chat.SetOutput(&output)
+
+	if err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}}); err != nil {
+		t.Fatalf("sendMessage() unexpected error: %v", err)
+	}
+	if strings.Contains(output.String(), "Сервер думает") {
+		t.Errorf("hint must not be shown after the first chunk, output %q", output.String())
+	}
+}
+
+func TestChat_sendMessage_requestTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{"limited", 50 * time.Millisecond, true},
+		{"zero means no limit", 0, false},
+		{"negative means no limit", -time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: 10, RequestTimeout: tt.timeout}
+
+			client := &mockAIClient{
+				generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+					if _, ok := ctx.Deadline(); !ok {
+						return fn(api.GenerateResponse{Response: "OK", Done: true})
+					}
+					// модель отвечает без пауз, но слишком долго
+					for {
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case <-time.After(5 * time.Millisecond):
+							fn(api.GenerateResponse{Response: "."})
+						}
+					}
+				},
+			}
+
+			chat := newTestChat(client, cfg)
+			chat.SetOutput(&strings.Builder{})
+			err := chat.sendMessage([]model.Message{{Role: model.RoleUser, Content: "Hi", Timestamp: time.Now()}})
+
+			if !tt.wantDeadline {
+				if err != nil {
+					t.Fatalf("sendMessage() unexpected error: %v", err)
+				}
+				return
+			}
+			if !stderrors.Is(err, errors.ErrMessageSend) || !stderrors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("sendMessage() error = %v, want ErrMessageSend with DeadlineExceeded", err)
+			}
+			if !strings.Contains(err.Error(), "REQUEST_TIMEOUT_SECONDS") {
+				t.Errorf("error %q should name REQUEST_TIMEOUT_SECONDS", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StartupChoice — решение пользователя при запуске, если сессия уже существует.
+type StartupChoice int
+
+const (
+	StartupResume StartupChoice = iota // продолжить существующий чат
+	StartupFresh                       // архивировать старый чат и начать новый
+	StartupOther                       // открыть сессию под другим именем
+)
+
+// AskStartupChoice спрашивает, что делать с существующей сессией userName.
+// Для StartupOther также возвращает введённое имя другой сессии.
+// Если ввод закончился, чат продолжается как раньше.
+func AskStartupChoice(in io.Reader, out io.Writer, userName string) (StartupChoice, string) {
+	scanner := bufio.NewScanner(in)
+
+	fmt.Fprintf(out, "📂 Найден сохранённый чат %s.\n", userName)
+	fmt.Fprint(out, "  [1] продолжить, [2] начать заново (старый будет архивирован), [3] другая сессия: ")
+
+	for scanner.Scan() {
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "", "1", "c", "continue", "продолжить":
+			return StartupResume, ""
+		case "2", "n", "new", "заново":
+			return StartupFresh, ""
+		case "3", "o", "other", "другая":
+			if name := askSessionName(scanner, out); name != "" {
+				return StartupOther, name
+			}
+			return StartupResume, ""
+		}
+		fmt.Fprint(out, "❌ Введите 1, 2 или 3: ")
+	}
+
+	return StartupResume, ""
+}
+
+func askSessionName(scanner *bufio.Scanner, out io.Writer) string {
+	fmt.Fprint(out, "👤 Имя сессии: ")
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			return name
+		}
+		fmt.Fprint(out, "❌ Имя не может быть пустым. Попробуйте еще раз: ")
+	}
+	return ""
+}
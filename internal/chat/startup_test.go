@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAskStartupChoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     StartupChoice
+		wantName string
+	}{
+		{"resume", "1\n", StartupResume, ""},
+		{"enter resumes", "\n", StartupResume, ""},
+		{"fresh", "2\n", StartupFresh, ""},
+		{"other session", "3\nalice\n", StartupOther, "alice"},
+		{"other skips empty name", "other\n\n bob \n", StartupOther, "bob"},
+		{"invalid then fresh", "maybe\n2\n", StartupFresh, ""},
+		{"eof resumes", "", StartupResume, ""},
+		{"eof while asking name resumes", "3\n", StartupResume, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, name := AskStartupChoice(strings.NewReader(tt.input), io.Discard, "testuser")
+			if got != tt.want || name != tt.wantName {
+				t.Errorf("AskStartupChoice(%q) = %v, %q; want %v, %q", tt.input, got, name, tt.want, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package chat
+
+import "fmt"
+
+// maxRampTemperature — потолок, выше которого наращивание TEMPERATURE_STEP
+// температуру не поднимает: дальше ответы становятся бессвязными.
+const maxRampTemperature = 2.0
+
+// temperature возвращает температуру для запроса: TEMPERATURE плюс
+// TEMPERATURE_STEP за каждый повтор подряд на текущий вопрос.
+func (c *Chat) temperature() float64 {
+	if c.cfg.TemperatureStep <= 0 || c.retries == 0 {
+		return c.cfg.Temperature
+	}
+	ramped := c.cfg.Temperature + float64(c.retries)*c.cfg.TemperatureStep
+	return min(ramped, max(c.cfg.Temperature, maxRampTemperature))
+}
+
+// rampTemperature засчитывает очередной повтор и сообщает новую температуру.
+// Счётчик сбрасывается в processUserInput с новым вопросом.
+func (c *Chat) rampTemperature() {
+	c.retries++
+	if c.cfg.TemperatureStep > 0 && !c.cfg.Bare {
+		fmt.Fprintf(c.output(), "🌡️  Температура повтора: %.2f\n", c.temperature())
+	}
+}
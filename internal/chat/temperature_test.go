@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_retry_temperatureRamp(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Temperature: 0.5, TemperatureStep: 0.25}
+
+	var temperatures []float64
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			temperatures = append(temperatures, req.Options["temperature"].(float64))
+			return fn(api.GenerateResponse{Response: "Ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	captureStdout(t, func() {
+		if err := chat.processUserInput("Вопрос"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+		for _, command := range []string{"/retry", "/regenerate", "/retry"} {
+			if handled, err := chat.handleCommand(command); !handled || err != nil {
+				t.Fatalf("handleCommand(%s) = %v, %v", command, handled, err)
+			}
+		}
+		if err := chat.processUserInput("Новый вопрос"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+		if _, err := chat.handleCommand("/retry"); err != nil {
+			t.Fatalf("/retry after new question error = %v", err)
+		}
+	})
+
+	want := []float64{0.5, 0.75, 1.0, 1.25, 0.5, 0.75}
+	if len(temperatures) != len(want) {
+		t.Fatalf("requests = %d, want %d", len(temperatures), len(want))
+	}
+	for i := range want {
+		if math.Abs(temperatures[i]-want[i]) > 1e-9 {
+			t.Errorf("request %d temperature = %v, want %v", i+1, temperatures[i], want[i])
+		}
+	}
+	if cfg.Temperature != 0.5 {
+		t.Errorf("configured TEMPERATURE changed to %v", cfg.Temperature)
+	}
+}
+
+func TestChat_skipThink_keepsTemperature(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, Temperature: 0.5, TemperatureStep: 0.25}
+
+	var temperatures []float64
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			temperatures = append(temperatures, req.Options["temperature"].(float64))
+			return fn(api.GenerateResponse{Response: "Ответ"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	captureStdout(t, func() {
+		if err := chat.processUserInput("Вопрос"); err != nil {
+			t.Fatalf("processUserInput() unexpected error: %v", err)
+		}
+		for _, command := range []string{"/skip-think", "/skip-think"} {
+			if handled, err := chat.handleCommand(command); !handled || err != nil {
+				t.Fatalf("handleCommand(%s) = %v, %v", command, handled, err)
+			}
+		}
+	})
+
+	for i, temperature := range temperatures {
+		if temperature != 0.5 {
+			t.Errorf("request %d temperature = %v, want 0.5", i+1, temperature)
+		}
+	}
+	if len(temperatures) != 3 || chat.retries != 0 {
+		t.Errorf("requests = %d, retries = %d, want 3 and 0", len(temperatures), chat.retries)
+	}
+}
+
+func TestChat_temperature(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    float64
+		step    float64
+		retries int
+		want    float64
+	}{
+		{"ramping disabled", 0.7, 0, 3, 0.7},
+		{"first generation", 0.7, 0.5, 0, 0.7},
+		{"stepped", 0.2, 0.5, 2, 1.2},
+		{"capped", 0.2, 0.5, 10, maxRampTemperature},
+		{"base above cap is kept", 2.5, 0.5, 1, 2.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Chat{cfg: &config.Config{Temperature: tt.base, TemperatureStep: tt.step}, retries: tt.retries}
+			if got := c.temperature(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("temperature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const userToken = "{{user}}"
+
+var templateToken = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// expandSystemPrompt подставляет в системный промпт значения токенов
+// {{date}}, {{user}} и {{env:VAR}}. Неизвестные токены остаются как есть,
+// о них сообщается в отладочном выводе.
+func (c *Chat) expandSystemPrompt(prompt string) string {
+	if !strings.Contains(prompt, "{{") {
+		return prompt
+	}
+
+	return templateToken.ReplaceAllStringFunc(prompt, func(token string) string {
+		name := templateToken.FindStringSubmatch(token)[1]
+
+		switch {
+		case name == "date":
+			return c.now().Format("2006-01-02")
+		case name == "user":
+			return c.session.UserName
+		case strings.HasPrefix(name, "env:"):
+			return os.Getenv(strings.TrimPrefix(name, "env:"))
+		}
+
+		c.debugf("неизвестный токен %s в системном промпте оставлен без изменений", token)
+		return token
+	})
+}
+
+// requestSystemPrompt возвращает системный промпт для запроса: разовый из
+// /once-system или промпт сессии. При INCLUDE_USERNAME имя пользователя
+// сообщается модели, даже если в промпте нет токена {{user}};
+// RESPONSE_LANGUAGE добавляет требование языка ответа.
+func (c *Chat) requestSystemPrompt() string {
+	prompt := c.systemPrompt()
+	if c.onceSystem != "" {
+		prompt = c.onceSystem
+	}
+	if c.cfg.IncludeUserName && c.session.UserName != "" && !hasUserToken(prompt) {
+		prompt = "Пользователя зовут " + userToken + ".\n" + prompt
+	}
+	prompt = c.expandSystemPrompt(prompt)
+
+	if c.cfg.ResponseLanguage != "" {
+		prompt += "\n" + languageInstruction(c.cfg.ResponseLanguage)
+	}
+	return prompt
+}
+
+func languageInstruction(language string) string {
+	return fmt.Sprintf("Всегда отвечай на языке: %s, даже если вопрос задан на другом языке.", language)
+}
+
+func hasUserToken(prompt string) bool {
+	for _, match := range templateToken.FindAllStringSubmatch(prompt, -1) {
+		if match[1] == "user" {
+			return true
+		}
+	}
+	return false
+}
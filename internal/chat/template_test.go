@@ -0,0 +1,115 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_expandSystemPrompt(t *testing.T) {
+	t.Setenv("AGENT_TEST_HOST", "build-01")
+
+	chat := newTestChat(&mockAIClient{}, &config.Config{})
+	chat.now = func() time.Time { return time.Date(2025, 12, 15, 10, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"no tokens", "Ты умный помощник.", "Ты умный помощник."},
+		{"date", "Сегодня {{date}}.", "Сегодня 2025-12-15."},
+		{"user", "Собеседник: {{user}}", "Собеседник: testuser"},
+		{"env", "Хост {{env:AGENT_TEST_HOST}}", "Хост build-01"},
+		{"unset env", "[{{env:AGENT_TEST_UNSET}}]", "[]"},
+		{"spaces inside braces", "{{ date }}", "2025-12-15"},
+		{"unknown token kept", "{{weather}} и {{user}}", "{{weather}} и testuser"},
+		{"single braces untouched", "{date} {user}", "{date} {user}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chat.expandSystemPrompt(tt.prompt); got != tt.want {
+				t.Errorf("expandSystemPrompt(%q) = %q, want %q", tt.prompt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChat_sendMessage_expandsSystemPrompt(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, SystemPrompt: "Помогай {{user}}"}
+
+	var system string
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			system = req.System
+			return fn(api.GenerateResponse{Response: "OK"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	if err := chat.processUserInput("Привет"); err != nil {
+		t.Fatalf("processUserInput() unexpected error: %v", err)
+	}
+	if system != "Помогай testuser" {
+		t.Errorf("request System = %q, want %q", system, "Помогай testuser")
+	}
+	if cfg.SystemPrompt != "Помогай {{user}}" {
+		t.Error("configured system prompt must keep its tokens")
+	}
+}
+
+func TestChat_requestSystemPrompt_includeUserName(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		prompt  string
+		want    string
+	}{
+		{"disabled", false, "Ты помощник.", "Ты помощник."},
+		{"enabled prepends name", true, "Ты помощник.", "Пользователя зовут testuser.\nТы помощник."},
+		{"enabled with token not duplicated", true, "Помогай {{ user }}.", "Помогай testuser."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: 10, SystemPrompt: tt.prompt, IncludeUserName: tt.enabled}
+			chat := newTestChat(&mockAIClient{}, cfg)
+
+			req := chat.buildRequest(chat.buildContextPrompt([]model.Message{{Role: model.RoleUser, Content: "Привет"}}))
+			if req.System != tt.want {
+				t.Errorf("request System = %q, want %q", req.System, tt.want)
+			}
+			if got := strings.Contains(req.System+req.Prompt, "testuser"); got != (tt.enabled || strings.Contains(tt.prompt, "user")) {
+				t.Errorf("user name in request = %v, enabled = %v", got, tt.enabled)
+			}
+		})
+	}
+}
+
+func TestChat_buildRequest_responseLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		want     string
+	}{
+		{"not set", "", "Ты помощник."},
+		{"set", "английский", "Ты помощник.\nВсегда отвечай на языке: английский, даже если вопрос задан на другом языке."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxSizeLimit: 10, SystemPrompt: "Ты помощник.", ResponseLanguage: tt.language}
+			chat := newTestChat(&mockAIClient{}, cfg)
+
+			if req := chat.buildRequest("Привет"); req.System != tt.want {
+				t.Errorf("request System = %q, want %q", req.System, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package chat
+
+import "os"
+
+const clearScreenSeq = "\033[H\033[2J"
+
+// isTerminal сообщает, подключён ли файл к интерактивному терминалу.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// StdinIsTerminal сообщает, вводит ли пользователь данные интерактивно,
+// а не через перенаправленный поток.
+func StdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// colorsEnabled учитывает соглашение NO_COLOR и отсутствие TTY.
+func colorsEnabled() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
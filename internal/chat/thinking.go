@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"agent/internal/model"
+	"fmt"
+	"strings"
+)
+
+// thinkingAsAnswer сохраняет размышления как ответ, когда сам ответ пуст
+// (THINKING_ONLY_REPLY=thinking).
+func (c *Chat) thinkingAsAnswer(thinking string) string {
+	if !c.cfg.Bare {
+		fmt.Fprintln(c.output(), "💭 Модель ответила только размышлениями, сохраняем их как ответ")
+	}
+	return strings.TrimSpace(thinking)
+}
+
+// retryWithoutThinking повторяет запрос с отключёнными размышлениями
+// (THINKING_ONLY_REPLY=retry). Повтор выполняется один раз.
+func (c *Chat) retryWithoutThinking(messages []model.Message) error {
+	if !c.cfg.Bare {
+		fmt.Fprintln(c.output(), "💭 Модель ответила только размышлениями, повторяем запрос без них")
+	}
+
+	c.turnNoThink = true
+	defer func() { c.turnNoThink = false }()
+	return c.sendMessage(messages)
+}
+
+// warnThinkingOnly сообщает о пустом ответе; в историю он не попадает.
+func (c *Chat) warnThinkingOnly() {
+	fmt.Fprintln(c.output(), "⚠️  Модель выдала только размышления без ответа, ответ не сохранён. Попробуйте /skip-think или /retry")
+}
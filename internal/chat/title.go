@@ -0,0 +1,96 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// titleHistoryLimit — сколько последних сообщений показывается модели
+// при генерации заголовка.
+const titleHistoryLimit = 10
+
+// title показывает заголовок сессии, задаёт его вручную или, с аргументом
+// «regen», заново генерирует по истории с помощью модели.
+func (c *Chat) title(args string) error {
+	switch {
+	case args == "":
+		if c.session.Title == "" {
+			fmt.Fprintln(c.output(), "🏷️  У сессии пока нет заголовка")
+		} else {
+			fmt.Fprintf(c.output(), "🏷️  %s\n", c.session.Title)
+		}
+		return nil
+	case strings.EqualFold(args, "regen"):
+		title, err := c.generateTitle()
+		if err != nil {
+			return err
+		}
+		c.session.Title = title
+	default:
+		c.session.Title = args
+	}
+
+	c.session.Updated = time.Now()
+	fmt.Fprintf(c.output(), "🏷️  Заголовок: %s\n", c.session.Title)
+	return c.saveSession()
+}
+
+// generateTitle просит модель кратко озаглавить разговор. Запрос не
+// добавляется в историю.
+func (c *Chat) generateTitle() (string, error) {
+	if len(c.session.Messages) == 0 {
+		return "", errors.ErrNoMessages
+	}
+
+	start := c.calculateStartIndex(len(c.session.Messages), titleHistoryLimit)
+	var builder strings.Builder
+	builder.WriteString("Придумай короткий заголовок (до шести слов) для этого разговора. Ответь только заголовком.\n\n")
+	for _, msg := range c.session.Messages[start:] {
+		// Системные сообщения — указания модели, а не тема разговора
+		if msg.IsSystem() {
+			continue
+		}
+		label := "Ассистент"
+		if msg.IsUser() {
+			label = "Пользователь"
+		}
+		builder.WriteString(fmt.Sprintf("%s: %s\n", label, msg.Content))
+	}
+
+	req := &api.GenerateRequest{
+		Model:     c.cfg.ModelName,
+		Prompt:    builder.String(),
+		Stream:    &[]bool{false}[0],
+		Think:     &api.ThinkValue{Value: false},
+		KeepAlive: c.cfg.KeepAlive,
+	}
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+
+	var response strings.Builder
+	err := c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
+		response.WriteString(resp.Response)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errors.ErrMessageSend, err)
+	}
+
+	title := cleanTitle(response.String())
+	if title == "" {
+		return "", fmt.Errorf("%w: модель вернула пустой заголовок", errors.ErrMessageSend)
+	}
+	return title, nil
+}
+
+// cleanTitle оставляет первую строку ответа без кавычек и точки в конце.
+func cleanTitle(response string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(response), "\n")
+	line = strings.TrimSuffix(strings.TrimSpace(line), ".")
+	return strings.TrimSpace(strings.Trim(line, "\"«»'`*"))
+}
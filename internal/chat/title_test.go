@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"agent/internal/session"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestChat_title_setAndShow(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+
+	output := captureStdout(t, func() {
+		if handled, err := chat.handleCommand("/title"); !handled || err != nil {
+			t.Fatalf("handleCommand(/title) = %v, %v", handled, err)
+		}
+	})
+	if !strings.Contains(output, "нет заголовка") {
+		t.Errorf("/title without a title printed %q", output)
+	}
+
+	if handled, err := chat.handleCommand("/title Планирование отпуска"); !handled || err != nil {
+		t.Fatalf("handleCommand(/title <text>) = %v, %v", handled, err)
+	}
+
+	output = captureStdout(t, func() {
+		chat.handleCommand("/title")
+	})
+	if !strings.Contains(output, "Планирование отпуска") {
+		t.Errorf("/title printed %q, want the manual title", output)
+	}
+
+	loaded, err := session.NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("reloading session: %v", err)
+	}
+	if loaded.Title != "Планирование отпуска" {
+		t.Errorf("persisted title = %q, want %q", loaded.Title, "Планирование отпуска")
+	}
+}
+
+func TestChat_title_regen(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", ModelName: "test-model", RequestTimeout: time.Minute}
+
+	var prompt string
+	var hasDeadline bool
+	client := &mockAIClient{
+		generateFunc: func(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+			prompt = req.Prompt
+			_, hasDeadline = ctx.Deadline()
+			return fn(api.GenerateResponse{Response: "«Рецепт борща».\nПояснение"})
+		},
+	}
+
+	chat := newTestChat(client, cfg)
+	chat.session.Title = "Старый заголовок"
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleSystem, Content: "Отвечай кратко.", Timestamp: time.Now()},
+		{Role: model.RoleUser, Content: "Как сварить борщ?", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Нужна свёкла.", Timestamp: time.Now()},
+	}
+
+	if handled, err := chat.handleCommand("/title regen"); !handled || err != nil {
+		t.Fatalf("handleCommand(/title regen) = %v, %v", handled, err)
+	}
+
+	if chat.session.Title != "Рецепт борща" {
+		t.Errorf("title = %q, want %q", chat.session.Title, "Рецепт борща")
+	}
+	if !strings.Contains(prompt, "Как сварить борщ?") {
+		t.Errorf("title prompt should include history, got %q", prompt)
+	}
+	if strings.Contains(prompt, "Отвечай кратко") {
+		t.Errorf("title prompt must skip system messages, got %q", prompt)
+	}
+	if !hasDeadline {
+		t.Error("title request should be limited by REQUEST_TIMEOUT_SECONDS")
+	}
+	if len(chat.session.Messages) != 3 {
+		t.Errorf("messages = %d, title generation must not change history", len(chat.session.Messages))
+	}
+}
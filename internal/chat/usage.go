@@ -0,0 +1,69 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/session"
+	"fmt"
+	"io"
+)
+
+// DiskUsage — сводка по размеру файлов сессий.
+type DiskUsage struct {
+	Sessions []session.SessionInfo
+	Total    int64
+	Largest  session.SessionInfo
+}
+
+// CollectUsage суммирует размеры всех сессий в CTX_DIR.
+func CollectUsage(cfg *config.Config) (DiskUsage, error) {
+	list, err := session.ListSessions(cfg)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+
+	usage := DiskUsage{Sessions: list}
+	for _, info := range list {
+		usage.Total += info.Size
+		if info.Size > usage.Largest.Size {
+			usage.Largest = info
+		}
+	}
+	return usage, nil
+}
+
+// PrintUsage выводит размер каждой сессии, общий объём и отмечает самую большую.
+func PrintUsage(w io.Writer, usage DiskUsage) {
+	if len(usage.Sessions) == 0 {
+		fmt.Fprintln(w, "📭 Сохранённых сессий нет")
+		return
+	}
+
+	fmt.Fprintln(w, "💽 Место на диске:")
+	for _, info := range usage.Sessions {
+		marker := ""
+		if info.Path == usage.Largest.Path {
+			marker = "  ⬅️ самая большая"
+		}
+		fmt.Fprintf(w, "  %-24s %10s%s\n", info.Name, formatBytes(info.Size), marker)
+	}
+	fmt.Fprintf(w, "  Всего: %s в %d сессиях\n", formatBytes(usage.Total), len(usage.Sessions))
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f КБ", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d Б", n)
+}
+
+func (c *Chat) usage() error {
+	usage, err := CollectUsage(c.cfg)
+	if err != nil {
+		return err
+	}
+	PrintUsage(c.output(), usage)
+	return nil
+}
@@ -0,0 +1,54 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectUsage(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	sizes := map[string]int{"alice.json": 100, "bob.json": 2048, "carol.json": 300}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(cfg.CtxDir, name), bytes.Repeat([]byte("x"), size), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	// Резервные копии не считаются сессиями
+	if err := os.WriteFile(filepath.Join(cfg.CtxDir, "bob.json.bak.1"), make([]byte, 5000), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	usage, err := CollectUsage(cfg)
+	if err != nil {
+		t.Fatalf("CollectUsage() error = %v", err)
+	}
+
+	if len(usage.Sessions) != 3 {
+		t.Errorf("sessions = %d, want 3", len(usage.Sessions))
+	}
+	if usage.Total != 2448 {
+		t.Errorf("Total = %d, want 2448", usage.Total)
+	}
+	if usage.Largest.Name != "bob" {
+		t.Errorf("Largest = %q, want bob", usage.Largest.Name)
+	}
+
+	var buf bytes.Buffer
+	PrintUsage(&buf, usage)
+	out := buf.String()
+	for _, want := range []string{"alice", "100 Б", "2.0 КБ", "Всего: 2.4 КБ в 3 сессиях"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PrintUsage() output missing %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "самая большая") && !strings.Contains(line, "bob") {
+			t.Errorf("largest marker on the wrong line: %q", line)
+		}
+	}
+}
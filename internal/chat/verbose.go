@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// verboseSettings — диагностические настройки, которые переключает /verbose:
+// отладочный вывод с запросами, заполнение контекста и состав истории.
+var verboseSettings = []string{"DEBUG", "SHOW_BUDGET", "SHOW_CONTEXT"}
+
+// verbose обрабатывает «/verbose on|off»; без аргумента переключает режим.
+// Изменения действуют до конца работы, как и после /set.
+func (c *Chat) verbose(args string) error {
+	var on bool
+	switch strings.ToLower(args) {
+	case "":
+		on = !c.cfg.Debug
+	case "on", "вкл":
+		on = true
+	case "off", "выкл":
+		on = false
+	default:
+		return fmt.Errorf("%w: /verbose %s, ожидается on или off", errors.ErrInvalidOption, args)
+	}
+
+	for _, key := range verboseSettings {
+		if err := c.cfg.Set(key, strconv.FormatBool(on)); err != nil {
+			return err
+		}
+	}
+
+	if on {
+		fmt.Fprintln(c.output(), "🐞 Подробный вывод включён: запросы к модели, заполнение и состав контекста")
+	} else {
+		fmt.Fprintln(c.output(), "🔇 Подробный вывод выключен")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_verbose(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CtxSizeLimit: 10, ModelName: "llama3"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.SetOutput(&bytes.Buffer{})
+
+	send := func() string {
+		chat.session.Messages = []model.Message{{Role: model.RoleUser, Content: "Привет", Timestamp: time.Now()}}
+		return captureStderr(t, func() {
+			if err := chat.sendMessage(chat.session.Messages); err != nil {
+				t.Fatalf("sendMessage() error = %v", err)
+			}
+		})
+	}
+
+	if got := send(); got != "" {
+		t.Errorf("debug output before /verbose on = %q, want none", got)
+	}
+
+	steps := []struct {
+		command   string
+		wantDebug bool
+	}{
+		{"/verbose on", true},
+		{"/verbose off", false},
+		{"/verbose", true},
+		{"/verbose", false},
+	}
+	for _, step := range steps {
+		if handled, err := chat.handleCommand(step.command); !handled || err != nil {
+			t.Fatalf("handleCommand(%q) = %v, %v", step.command, handled, err)
+		}
+		if cfg.Debug != step.wantDebug || cfg.ShowBudget != step.wantDebug || cfg.ShowContext != step.wantDebug {
+			t.Fatalf("after %q Debug/ShowBudget/ShowContext = %v/%v/%v, want %v",
+				step.command, cfg.Debug, cfg.ShowBudget, cfg.ShowContext, step.wantDebug)
+		}
+
+		got := send()
+		if hasDump := strings.Contains(got, "запрос: модель llama3"); hasDump != step.wantDebug {
+			t.Errorf("after %q request dump printed = %v, want %v (stderr %q)", step.command, hasDump, step.wantDebug, got)
+		}
+	}
+
+	if _, err := chat.handleCommand("/verbose loud"); err == nil {
+		t.Error("/verbose with an unknown argument should fail")
+	}
+}
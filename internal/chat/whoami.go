@@ -0,0 +1,28 @@
+package chat
+
+import "fmt"
+
+// whoami показывает, с какой сессией сейчас идёт работа: имя, файл,
+// размер истории, модель и ближайшее автосохранение.
+func (c *Chat) whoami() {
+	out := c.output()
+	fmt.Fprintf(out, "👤 Пользователь: %s\n", c.session.UserName)
+	fmt.Fprintf(out, "📄 Файл сессии: %s\n", c.session.FilePath())
+	fmt.Fprintf(out, "💬 Сообщений в истории: %d\n", len(c.session.Messages))
+	fmt.Fprintf(out, "🤖 Модель: %s\n", c.cfg.ModelName)
+	fmt.Fprintf(out, "💾 Автосохранение: %s\n", c.autoSaveStatus())
+}
+
+// autoSaveStatus описывает, когда сессия будет сохранена в следующий раз,
+// по тем же правилам, что autoSave и Close.
+func (c *Chat) autoSaveStatus() string {
+	if c.regenerating {
+		return "отключено до конца перегенерации"
+	}
+
+	next := len(c.session.Messages) + 1
+	for !c.autoSaveDue(next) {
+		next++
+	}
+	return fmt.Sprintf("следующее при %d сообщениях в истории, а также при выходе", next)
+}
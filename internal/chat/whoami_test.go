@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat_whoami(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", ModelName: "llama3"}
+	chat := newTestChat(&mockAIClient{}, cfg)
+	chat.session.UserName = "Анна: тест"
+	chat.session.Messages = []model.Message{
+		{Role: model.RoleUser, Content: "Привет", Timestamp: time.Now()},
+		{Role: model.RoleAssistant, Content: "Здравствуйте", Timestamp: time.Now()},
+	}
+
+	var buf bytes.Buffer
+	chat.SetOutput(&buf)
+	if handled, err := chat.handleCommand("/whoami"); !handled || err != nil {
+		t.Fatalf("handleCommand(/whoami) = %v, %v", handled, err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{
+		"Анна: тест",
+		filepath.Join(cfg.CtxDir, "Анна__тест.json"),
+		"Сообщений в истории: 2",
+		"llama3",
+		"следующее при 4 сообщениях",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("/whoami output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestChat_autoSaveStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages int
+		cleared  bool
+		regen    bool
+		want     string
+	}{
+		{"new session", 0, false, false, "следующее при 2 сообщениях"},
+		{"after first exchange", 2, false, false, "следующее при 4 сообщениях"},
+		{"after clear", 0, true, false, "следующее при 4 сообщениях"},
+		{"during regen", 4, false, true, "отключено до конца перегенерации"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := newTestChat(&mockAIClient{}, &config.Config{})
+			chat.session.Messages = make([]model.Message, tt.messages)
+			chat.cleared = tt.cleared
+			chat.regenerating = tt.regen
+
+			if got := chat.autoSaveStatus(); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("autoSaveStatus() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wrapWriter переносит потоковый ответ по словам на ширину width. Слово
+// копится, пока не придёт разделитель, поэтому перенос не разрывает слова,
+// пришедшие несколькими фрагментами. Слова длиннее width при breakWords
+// разрываются по ширине, иначе выводятся целиком за край строки.
+type wrapWriter struct {
+	w          io.Writer
+	width      int // 0 — текст выводится без изменений
+	breakWords bool
+	col        int    // символов в текущей строке
+	partial    []byte // начало символа UTF-8, разрезанного между фрагментами
+	spaces     int    // пробелы перед словом: печатаются, только если слово не переносится
+	word       []rune // недописанное слово
+}
+
+func newWrapWriter(w io.Writer, width int, breakWords bool) *wrapWriter {
+	return &wrapWriter{w: w, width: width, breakWords: breakWords}
+}
+
+// responseWriter возвращает вывод потокового ответа с учётом WRAP_WIDTH.
+func (c *Chat) responseWriter() *wrapWriter {
+	return newWrapWriter(c.streamOutput(), c.cfg.WrapWidth, c.cfg.BreakLongWords)
+}
+
+func (ww *wrapWriter) Write(p []byte) (int, error) {
+	if ww.width <= 0 {
+		return ww.w.Write(p)
+	}
+
+	var out strings.Builder
+	text := string(append(ww.partial, p...))
+	ww.partial = nil
+	for text != "" {
+		if !utf8.FullRuneInString(text) {
+			ww.partial = []byte(text)
+			break
+		}
+		r, size := utf8.DecodeRuneInString(text)
+		text = text[size:]
+
+		switch {
+		case r == '\n':
+			ww.flushWord(&out)
+			out.WriteByte('\n')
+			ww.col, ww.spaces = 0, 0
+		case unicode.IsSpace(r):
+			ww.flushWord(&out)
+			ww.spaces++
+		default:
+			ww.word = append(ww.word, r)
+		}
+	}
+
+	if _, err := io.WriteString(ww.w, out.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush выводит недописанное слово в конце ответа.
+func (ww *wrapWriter) Flush() error {
+	if ww.width <= 0 {
+		return nil
+	}
+	var out strings.Builder
+	ww.flushWord(&out)
+	_, err := io.WriteString(ww.w, out.String())
+	return err
+}
+
+func (ww *wrapWriter) flushWord(out *strings.Builder) {
+	if len(ww.word) == 0 {
+		return
+	}
+
+	if ww.col > 0 && ww.col+ww.spaces+len(ww.word) > ww.width {
+		out.WriteByte('\n')
+		ww.col = 0
+	} else {
+		// При разрыве слов отступ шире строки (например, в коде)
+		// обрезается до её края
+		spaces := ww.spaces
+		if ww.breakWords {
+			spaces = min(spaces, max(ww.width-ww.col, 0))
+		}
+		out.WriteString(strings.Repeat(" ", spaces))
+		ww.col += spaces
+	}
+	ww.spaces = 0
+	if ww.breakWords && ww.col >= ww.width {
+		out.WriteByte('\n')
+		ww.col = 0
+	}
+
+	word := ww.word
+	ww.word = ww.word[:0]
+	for ww.breakWords && ww.col+len(word) > ww.width {
+		n := ww.width - ww.col
+		out.WriteString(string(word[:n]))
+		out.WriteByte('\n')
+		word, ww.col = word[n:], 0
+	}
+	out.WriteString(string(word))
+	ww.col += len(word)
+}
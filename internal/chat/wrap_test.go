@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// wrapChunks пропускает фрагменты через wrapWriter, как потоковый ответ.
+func wrapChunks(width int, breakWords bool, chunks ...string) string {
+	var builder strings.Builder
+	ww := newWrapWriter(&builder, width, breakWords)
+	for _, chunk := range chunks {
+		ww.Write([]byte(chunk))
+	}
+	ww.Flush()
+	return builder.String()
+}
+
+func TestWrapWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		width      int
+		breakWords bool
+		chunks     []string
+		want       string
+	}{
+		{"disabled", 0, true, []string{"один два три"}, "один два три"},
+		{"wraps at word boundary", 10, true, []string{"один два три четыре"}, "один два\nтри четыре"},
+		{"word split across chunks", 10, true, []string{"один дв", "а три че", "тыре"}, "один два\nтри четыре"},
+		{"keeps newlines", 10, true, []string{"раз\nдва три"}, "раз\nдва три"},
+		{"over-width word is broken", 5, true, []string{"ab abcdefghijkl"}, "ab\nabcde\nfghij\nkl"},
+		{"over-width word overflows when disabled", 5, false, []string{"ab abcdefghijkl cd"}, "ab\nabcdefghijkl\ncd"},
+		{"rune split between chunks", 4, true, []string{"яя\xd1", "\x8fя"}, "яяяя"},
+		{"indent wider than line", 4, true, []string{"      code here\n"}, "    \ncode\nhere\n"},
+		{"indent wider than line, no breaking", 4, false, []string{"      code\n"}, "      code\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapChunks(tt.width, tt.breakWords, tt.chunks...); got != tt.want {
+				t.Errorf("wrapped = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapWriter_longTokenKeepsAllCharacters(t *testing.T) {
+	const width = 20
+	url := "https://example.com/" + strings.Repeat("a1b2c3", 10)
+	got := wrapChunks(width, true, "Ссылка: "+url+" готово")
+
+	lines := strings.Split(got, "\n")
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			t.Errorf("line %q has %d characters, want at most %d", line, n, width)
+		}
+	}
+	if joined := strings.Join(lines[1:len(lines)-1], ""); joined != url {
+		t.Errorf("broken token = %q, want %q", joined, url)
+	}
+	if lines[0] != "Ссылка:" || lines[len(lines)-1] != "готово" {
+		t.Errorf("wrapped = %q", got)
+	}
+}
@@ -4,59 +4,316 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ollama/ollama/api"
 )
 
+const (
+	PromptStyleLabeled = "labeled"
+	PromptStyleChatML  = "chatml"
+	PromptStyleMinimal = "minimal"
+)
+
+// Что делать с подряд идущими сообщениями одной роли при загрузке сессии.
+const (
+	RoleRunsFlag  = "flag"  // оставить как есть и предупредить
+	RoleRunsMerge = "merge" // объединить в одно сообщение
+)
+
+// DefaultPrefillInstruction — формулировка просьбы начать ответ с префилла;
+// {{prefill}} заменяется на ASSISTANT_PREFILL.
+const DefaultPrefillInstruction = "Начни свой ответ с фразы: {{prefill}}"
+
+// Что делать, если новая сессия превысит MAX_SESSIONS.
+const (
+	QuotaRefuse       = "refuse"        // отказать в создании
+	QuotaDeleteOldest = "delete_oldest" // удалить самые старые сессии
+)
+
+// Как дополнять слишком короткий ответ (MIN_RESPONSE_LEN).
+const (
+	ElaborateAppend  = "append"  // дописать уточнение к короткому ответу
+	ElaborateReplace = "replace" // заменить короткий ответ развёрнутым
+)
+
+// Что делать, если файл сессии превысит MAX_SESSION_BYTES.
+const (
+	SizeTrim   = "trim"   // удалить самые старые сообщения
+	SizeRefuse = "refuse" // не сохранять
+)
+
+// Что делать, если модель выдала только размышления без ответа.
+const (
+	ThinkingOnlyWarn  = "warn"     // предупредить и не сохранять пустой ответ
+	ThinkingOnlySave  = "thinking" // сохранить размышления как ответ
+	ThinkingOnlyRetry = "retry"    // повторить запрос без размышлений
+)
+
+// ThinkLevels — уровни размышления, которые принимает MODEL_THINK_VALUE
+// помимо true/false.
+var ThinkLevels = []string{"low", "medium", "high"}
+
+// Формат ответа модели (FORMAT).
+const (
+	FormatText = ""     // свободный текст без проверки
+	FormatJSON = "json" // только корректный JSON
+)
+
+// Сервер, к которому обращается агент (BACKEND).
+const (
+	BackendOllama = "ollama" // Ollama API
+	BackendOpenAI = "openai" // OpenAI-совместимый /v1/chat/completions
+)
+
+// DefaultOpenAIBaseURL — адрес API по умолчанию для BACKEND=openai.
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// Формат вывода ответов (--json).
+const (
+	OutputText = ""     // поток текста в терминал
+	OutputJSON = "json" // один JSON-объект на ответ
+)
+
+// Порядок вывода истории (DISPLAY_ORDER).
+const (
+	DisplayOldest = "oldest" // сначала старые сообщения
+	DisplayNewest = "newest" // сначала новые сообщения
+)
+
 type Config struct {
 	ModelName           string
+	Backend             string // ollama или openai
+	OpenAIBaseURL       string // адрес OpenAI-совместимого API, например http://localhost:8000/v1
+	OpenAIAPIKey        string // ключ API для BACKEND=openai, пусто — без авторизации
 	Temperature         float64
+	TemperatureStep     float64 // прибавка температуры при каждом повторе /retry, 0 — без наращивания
 	ThinkValue          *api.ThinkValue
 	CtxDir              string
 	CtxSizeLimit        int
+	CtxCharLimit        int // наибольшая длина истории в контексте в символах, 0 — без ограничения
 	CtxFileExt          string
 	SystemPrompt        string
 	AssistantPrefill    string
 	UseAssistantPrefill bool
+	PrefillInstruction  string // шаблон просьбы о префилле с плейсхолдером {{prefill}}
 	StopSequences       []string
 	MaxResponseSize     int
+	PromptStyle         string
+	BackupCount         int
+	OnResponseCmd       string
+	DetectLoops         bool
+	NormalizeUnicode    bool
+	PreserveTurns       bool
+	StallTimeout        time.Duration
+	StallHint           time.Duration // через сколько показать подсказку, если ответа ещё нет, 0 — не показывать
+	RequestTimeout      time.Duration // наибольшая длительность всего запроса к модели, 0 или меньше — без ограничения
+	NumCtx              int
+	ShowBudget          bool
+	TrimStopSequences   bool
+	KeepAlive           *api.Duration
+	RoleRuns            string
+	AutoResume          bool
+	TurnSeparator       string // строка между обменами репликами, пусто — без разделителя
+	StartupBanner       string // баннер при запуске: текст или путь к файлу, пусто — без баннера
+	DisplayOrder        string // порядок вывода истории: oldest или newest
+	WrapWidth           int    // ширина переноса строк ответа в терминале, 0 — без переноса
+	BreakLongWords      bool   // разрывать слова длиннее WRAP_WIDTH вместо выхода за край
+	Debug               bool
+	Spinner             bool
+	NormalizeWhitespace bool
+	MaxSessions         int
+	QuotaPolicy         string
+	ShowContext         bool
+	NumThread           int // потоков CPU для инференса, 0 — решает Ollama
+	NumGPU              int // слоёв модели на GPU, 0 — решает Ollama
+	MinResponseLen      int // минимальная длина ответа в символах, 0 — без проверки
+	MinResponseMode     string
+	StopOnBlankLine     bool   // останавливать генерацию на первой пустой строке
+	SessionCategory     string // категория для новых сессий
+	ResponseCacheDir    string // директория кэша ответов, пусто — кэш отключён
+	KeepFirstMessage    bool   // не отбрасывать первый вопрос при обрезке истории
+	MinRecentTurns      int    // сколько последних обменов репликами оставлять в контексте при любой обрезке
+	Stateless           bool   // отправлять модели только текущий вопрос, без истории
+	MaxTurns            int    // завершить работу после N обменов репликами, 0 — без ограничений
+	RemindEvery         int    // напоминать инструкции каждые N ходов, 0 — не напоминать
+	ReminderText        string // текст напоминания, пусто — системный промпт
+	IncludeUserName     bool   // сообщать модели имя пользователя в системном промпте
+	ResponseLanguage    string // язык ответов, пусто — не требовать
+	CompactAutosave     bool   // автосохранение без отступов в JSON
+	ThinkingOnly        string // ответ из одних размышлений: warn, thinking или retry
+	SaveThinking        bool   // сохранять размышления модели вместе с ответом
+	Format              string // формат ответа: пусто или json
+	FormatRetries       int    // сколько раз переспрашивать ответ в неверном формате
+	OutputFormat        string // формат вывода ответов: пусто (текст) или json (--json)
+	MaxSessionBytes     int64  // наибольший размер файла сессии, 0 — без ограничений
+	SessionSizePolicy   string
+	Bare                bool // только текст ответа, без меток и служебных сообщений (--bare)
+	AssumeYes           bool // не спрашивать подтверждение разрушительных команд (--yes)
+
+	sources configSource
+}
+
+// warnOutput — куда печатаются предупреждения о переменных окружения.
+var warnOutput io.Writer = os.Stdout
+
+// SetWarningOutput перенаправляет предупреждения при загрузке конфигурации,
+// например в stderr, чтобы не засорять stdout в режиме --bare.
+func SetWarningOutput(w io.Writer) {
+	warnOutput = w
+}
+
+func warnf(format string, args ...any) {
+	fmt.Fprintf(warnOutput, format, args...)
+}
+
+// NewConfig загружает настройки из env-файлов по порядку: значения из
+// более поздних файлов перекрывают более ранние. Без аргументов список берётся
+// из ENV_FILES (через запятую), а если она не задана — используется .env.
+func NewConfig(envFiles ...string) *Config {
+	if len(envFiles) == 0 {
+		envFiles = defaultEnvFiles()
+	}
+	return loadConfig(envFiles...)
+}
+
+func defaultEnvFiles() []string {
+	var files []string
+	for _, file := range strings.Split(os.Getenv("ENV_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return []string{".env"}
+	}
+	return files
 }
 
-func NewConfig() *Config {
-	loadEnvFile(".env")
+func loadConfig(envFiles ...string) *Config {
+	fileKeys := make(map[string]bool)
+	for _, envFile := range envFiles {
+		for key := range loadEnvFile(envFile) {
+			fileKeys[key] = true
+		}
+	}
 
 	config := &Config{
 		ModelName:           getEnvString("MODEL_NAME", "deepseek-r1:8b"),
+		Backend:             getEnvChoice("BACKEND", BackendOllama, BackendOllama, BackendOpenAI),
+		OpenAIBaseURL:       getEnvString("OPENAI_BASE_URL", DefaultOpenAIBaseURL),
+		OpenAIAPIKey:        getEnvString("OPENAI_API_KEY", ""),
 		Temperature:         getEnvFloat("TEMPERATURE", 0.1), // 0 для детерминированных ответов
+		TemperatureStep:     getEnvFloat("TEMPERATURE_STEP", 0),
 		ThinkValue:          &api.ThinkValue{Value: getEnvThinkValue("MODEL_THINK_VALUE", false)},
 		CtxDir:              getEnvString("CTX_DIR", "chats"),
 		CtxSizeLimit:        getEnvInt("CTX_SIZE_LIMIT", 10000),
+		CtxCharLimit:        getEnvInt("CTX_CHAR_LIMIT", 12000), // ~3000 токенов: история помещается в окно 4096 вместе с промптом и ответом
 		CtxFileExt:          getEnvString("CTX_FILE_EXT", ".json"),
 		SystemPrompt:        getEnvString("SYSTEM_PROMPT", "Ты - умный помощник, который помогает пользователю в его задачах."),
 		AssistantPrefill:    getEnvString("ASSISTANT_PREFILL", "Хорошо, давайте разберем ваш вопрос. "),
 		UseAssistantPrefill: getEnvBool("USE_ASSISTANT_PREFILL", true),
+		PrefillInstruction:  getEnvString("PREFILL_INSTRUCTION", DefaultPrefillInstruction),
 		StopSequences:       getEnvStringArray("STOP_SEQUENCES", []string{"Human:", "User:", "Пользователь:"}),
 		MaxResponseSize:     getEnvInt("MAX_RESPONSE_SIZE", 0),
+		PromptStyle:         getEnvChoice("PROMPT_STYLE", PromptStyleLabeled, PromptStyleLabeled, PromptStyleChatML, PromptStyleMinimal),
+		BackupCount:         getEnvInt("BACKUP_COUNT", 3),
+		OnResponseCmd:       getEnvString("ON_RESPONSE_CMD", ""),
+		DetectLoops:         getEnvBool("DETECT_LOOPS", false),
+		NormalizeUnicode:    getEnvBool("NORMALIZE_UNICODE", false),
+		PreserveTurns:       getEnvBool("PRESERVE_TURNS", true),
+		StallTimeout:        getEnvSeconds("STALL_TIMEOUT", 180),
+		StallHint:           getEnvSeconds("STALL_HINT", 10),
+		RequestTimeout:      getEnvSeconds("REQUEST_TIMEOUT_SECONDS", 180),
+		NumCtx:              getEnvInt("NUM_CTX", 0),
+		ShowBudget:          getEnvBool("SHOW_BUDGET", false),
+		TrimStopSequences:   getEnvBool("TRIM_STOP_SEQUENCES", true),
+		KeepAlive:           getEnvKeepAlive("KEEP_ALIVE"),
+		RoleRuns:            getEnvChoice("CONSECUTIVE_ROLES", RoleRunsFlag, RoleRunsFlag, RoleRunsMerge),
+		AutoResume:          getEnvBool("AUTO_RESUME", false),
+		TurnSeparator:       os.Getenv("TURN_SEPARATOR"),
+		StartupBanner:       os.Getenv("STARTUP_BANNER"),
+		DisplayOrder:        getEnvChoice("DISPLAY_ORDER", DisplayOldest, DisplayOldest, DisplayNewest),
+		WrapWidth:           getEnvInt("WRAP_WIDTH", 0),
+		BreakLongWords:      getEnvBool("BREAK_LONG_WORDS", true),
+		Debug:               getEnvBool("DEBUG", false),
+		Spinner:             getEnvBool("SPINNER", false),
+		NormalizeWhitespace: getEnvBool("NORMALIZE_WHITESPACE", true),
+		MaxSessions:         getEnvInt("MAX_SESSIONS", 0),
+		QuotaPolicy:         getEnvChoice("SESSION_QUOTA_POLICY", QuotaRefuse, QuotaRefuse, QuotaDeleteOldest),
+		ShowContext:         getEnvBool("SHOW_CONTEXT", false),
+		NumThread:           getEnvInt("NUM_THREAD", 0),
+		NumGPU:              getEnvInt("NUM_GPU", 0),
+		MinResponseLen:      getEnvInt("MIN_RESPONSE_LEN", 0),
+		MinResponseMode:     getEnvChoice("MIN_RESPONSE_MODE", ElaborateAppend, ElaborateAppend, ElaborateReplace),
+		StopOnBlankLine:     getEnvBool("STOP_ON_BLANK_LINE", false),
+		SessionCategory:     getEnvString("SESSION_CATEGORY", ""),
+		ResponseCacheDir:    getEnvString("RESPONSE_CACHE_DIR", ""),
+		KeepFirstMessage:    getEnvBool("KEEP_FIRST_MESSAGE", false),
+		MinRecentTurns:      getEnvInt("MIN_RECENT_TURNS", 0),
+		Stateless:           getEnvBool("STATELESS", false),
+		MaxTurns:            getEnvInt("MAX_TURNS", 0),
+		RemindEvery:         getEnvInt("REMIND_EVERY", 0),
+		ReminderText:        getEnvString("REMINDER_TEXT", ""),
+		IncludeUserName:     getEnvBool("INCLUDE_USERNAME", false),
+		ResponseLanguage:    getEnvString("RESPONSE_LANGUAGE", ""),
+		CompactAutosave:     getEnvBool("COMPACT_AUTOSAVE", false),
+		MaxSessionBytes:     int64(getEnvInt("MAX_SESSION_BYTES", 0)),
+		SessionSizePolicy:   getEnvChoice("SESSION_SIZE_POLICY", SizeTrim, SizeTrim, SizeRefuse),
+		ThinkingOnly:        getEnvChoice("THINKING_ONLY_REPLY", ThinkingOnlyWarn, ThinkingOnlyWarn, ThinkingOnlySave, ThinkingOnlyRetry),
+		SaveThinking:        getEnvBool("SAVE_THINKING", false),
+		Format:              getEnvChoice("FORMAT", FormatText, FormatText, FormatJSON),
+		FormatRetries:       getEnvInt("FORMAT_RETRIES", 1),
 	}
 
+	config.sources = detectSources(fileKeys)
 	return config
 }
 
 func (c *Config) DisplayConfig() {
 	fmt.Println("📋 Текущие настройки:")
 	fmt.Printf("  🤖 Модель: %s\n", c.ModelName)
+	if c.Backend == BackendOpenAI {
+		fmt.Printf("  🔌 Сервер: OpenAI-совместимый API %s\n", c.OpenAIBaseURL)
+	}
 	fmt.Printf("  🌡️  Температура: %.1f\n", c.Temperature)
 	fmt.Printf("  📁 Директория чатов: %s\n", c.CtxDir)
-	fmt.Printf("  📏 Лимит контекста: %d символов\n", c.CtxSizeLimit)
+	fmt.Printf("  📏 Лимит контекста: %d сообщений истории\n", c.CtxSizeLimit)
+	if c.CtxCharLimit > 0 {
+		fmt.Printf("  📏 Лимит длины истории: %d символов\n", c.CtxCharLimit)
+	}
 	if c.MaxResponseSize > 0 {
 		fmt.Printf("  📐 Лимит ответа: %d символов\n", c.MaxResponseSize)
 	} else {
 		fmt.Printf("  📐 Лимит ответа: без ограничений\n")
 	}
+	if c.NumCtx > 0 {
+		fmt.Printf("  🪟 Окно контекста модели: %d токенов\n", c.NumCtx)
+	}
+	if c.KeepAlive != nil {
+		if c.KeepAlive.Duration < 0 {
+			fmt.Printf("  🔥 Держать модель в памяти: всегда\n")
+		} else {
+			fmt.Printf("  🔥 Держать модель в памяти: %v\n", c.KeepAlive.Duration)
+		}
+	}
 	fmt.Printf("  📄 Расширение файлов: %s\n", c.CtxFileExt)
+	if c.StallTimeout > 0 {
+		fmt.Printf("  ⏳ Таймаут простоя потока: %v\n", c.StallTimeout)
+	} else {
+		fmt.Printf("  ⏳ Таймаут простоя потока: без ограничений\n")
+	}
+	if c.RequestTimeout > 0 {
+		fmt.Printf("  ⏱️  Таймаут запроса: %v\n", c.RequestTimeout)
+	} else {
+		fmt.Printf("  ⏱️  Таймаут запроса: без ограничений\n")
+	}
+	fmt.Printf("  🗂️  Резервных копий сессии: %d\n", c.BackupCount)
 	fmt.Printf("  🎯 Использовать префилл: %t\n", c.UseAssistantPrefill)
 	if c.UseAssistantPrefill {
 		fmt.Printf("  💬 Префилл: %s\n", c.AssistantPrefill)
@@ -67,6 +324,11 @@ func (c *Config) DisplayConfig() {
 	}
 	fmt.Printf("  🧠 Режим размышления: %s\n", thinkStatus)
 	fmt.Printf("  🛑 Стоп-последовательности: %v\n", c.StopSequences)
+	fmt.Printf("  🔁 Детектор зацикливания: %t\n", c.DetectLoops)
+	fmt.Printf("  🧩 Формат промпта: %s\n", c.PromptStyle)
+	if c.OnResponseCmd != "" {
+		fmt.Printf("  🪝 Команда после ответа: %s\n", c.OnResponseCmd)
+	}
 	fmt.Println()
 }
 
@@ -75,21 +337,50 @@ func getEnvString(key, defaultValue string) string {
 		return value
 	}
 
-	fmt.Printf("Переменная окружения %s не установлена, используем значение по умолчанию: %s\n", key, defaultValue)
+	warnf("Переменная окружения %s не установлена, используем значение по умолчанию: %s\n", key, defaultValue)
 	return defaultValue
 }
 
+// getEnvStringArray читает список в формате JSON (["a", "b"]) или, если это
+// не JSON, как значения через запятую (a, b). Пустые элементы отбрасываются.
 func getEnvStringArray(key string, defaultValue []string) []string {
-	if value := os.Getenv(key); value != "" {
-		value = strings.Trim(value, "\"")
+	if result, ok := parseStringList(os.Getenv(key)); ok {
+		return result
+	}
+	warnf("Переменная окружения %s не установлена, используем значение по умолчанию\n", key)
+	return defaultValue
+}
+
+// parseStringList разбирает JSON-массив строк или значения через запятую.
+// ok = false, если в значении нет ни одного элемента и это не JSON.
+func parseStringList(value string) ([]string, bool) {
+	value = strings.Trim(strings.TrimSpace(value), "\"")
+	if value == "" {
+		return nil, false
+	}
 
-		var result []string
-		if err := json.Unmarshal([]byte(value), &result); err == nil {
-			return result
+	var result []string
+	if err := json.Unmarshal([]byte(value), &result); err == nil {
+		return result, true
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
 		}
-		fmt.Printf("Переменная окружения %s имеет некорректный JSON формат, используем значение по умолчанию\n", key)
 	}
-	fmt.Printf("Переменная окружения %s не установлена, используем значение по умолчанию\n", key)
+	return result, len(result) > 0
+}
+
+// getEnvChoice читает значение, которое должно входить в список allowed
+// (без учёта регистра); иначе возвращает defaultValue.
+func getEnvChoice(key, defaultValue string, allowed ...string) string {
+	value := strings.ToLower(getEnvString(key, defaultValue))
+	if slices.Contains(allowed, value) {
+		return value
+	}
+
+	warnf("Переменная окружения %s имеет неизвестное значение %q, используем значение по умолчанию: %s\n", key, value, defaultValue)
 	return defaultValue
 }
 
@@ -100,22 +391,37 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 		}
 	}
 
-	fmt.Printf("Переменная окружения %s не установлена или некорректна, используем значение по умолчанию: %.2f\n", key, defaultValue)
+	warnf("Переменная окружения %s не установлена или некорректна, используем значение по умолчанию: %.2f\n", key, defaultValue)
 	return defaultValue
 }
 
 func getEnvThinkValue(key string, defaultValue any) any {
 	value := os.Getenv(key)
 	if value == "" {
-		fmt.Printf("Переменная окружения %s не установлена, используем значение по умолчанию: %v\n", key, defaultValue)
+		warnf("Переменная окружения %s не установлена, используем значение по умолчанию: %v\n", key, defaultValue)
 		return defaultValue
 	}
 
-	if b, err := strconv.ParseBool(value); err == nil {
-		return b
+	if think, ok := parseThinkValue(value); ok {
+		return think
 	}
 
-	return value
+	warnf("Переменная окружения %s имеет неизвестное значение %q (ожидается true, false или уровень %s), используем значение по умолчанию: %v\n",
+		key, value, strings.Join(ThinkLevels, ", "), defaultValue)
+	return defaultValue
+}
+
+// parseThinkValue разбирает MODEL_THINK_VALUE: логическое значение или
+// уровень размышления из ThinkLevels (без учёта регистра и пробелов).
+func parseThinkValue(value string) (any, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b, true
+	}
+	if slices.Contains(ThinkLevels, value) {
+		return value, true
+	}
+	return nil, false
 }
 
 func getEnvInt(key string, defaultValue int) int {
@@ -128,24 +434,66 @@ func getEnvInt(key string, defaultValue int) int {
 		return intValue
 	}
 
-	fmt.Printf("Переменная окружения %s некорректна (%q), используем значение по умолчанию: %d\n", key, value, defaultValue)
+	warnf("Переменная окружения %s некорректна (%q), используем значение по умолчанию: %d\n", key, value, defaultValue)
 	return defaultValue
 }
 
+// getEnvKeepAlive разбирает keep_alive для Ollama: длительность вида "5m",
+// число секунд или отрицательное значение ("-1") — держать модель всегда.
+// Пустое или некорректное значение означает умолчание сервера (nil).
+func getEnvKeepAlive(key string) *api.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return &api.Duration{Duration: time.Duration(seconds) * time.Second}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return &api.Duration{Duration: d}
+	}
+
+	warnf("Переменная окружения %s некорректна (%q), используем значение сервера по умолчанию\n", key, value)
+	return nil
+}
+
+// getEnvSeconds читает целое число секунд и возвращает его как time.Duration.
+func getEnvSeconds(key string, defaultSeconds int) time.Duration {
+	return time.Duration(getEnvInt(key, defaultSeconds)) * time.Second
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
 			return boolValue
 		}
 	}
-	fmt.Printf("Переменная окружения %s не установлена или некорректна, используем значение по умолчанию: %t\n", key, defaultValue)
+	warnf("Переменная окружения %s не установлена или некорректна, используем значение по умолчанию: %t\n", key, defaultValue)
 	return defaultValue
 }
 
-func loadEnvFile(filename string) {
+// unquoteEnvValue снимает кавычки со значения в двойных кавычках, чтобы в
+// env-файле можно было сохранить пробелы по краям и переводы строк
+// («\n»). Остальные значения возвращаются как есть.
+func unquoteEnvValue(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value
+}
+
+// loadEnvFile переносит переменные из файла в окружение процесса
+// и возвращает множество установленных ключей.
+func loadEnvFile(filename string) map[string]bool {
+	keys := make(map[string]bool)
+
 	file, err := os.Open(filename)
 	if err != nil {
-		return
+		return keys
 	}
 	defer file.Close()
 
@@ -156,7 +504,10 @@ func loadEnvFile(filename string) {
 			continue
 		}
 		if key, value, ok := strings.Cut(line, "="); ok {
-			os.Setenv(strings.TrimSpace(key), strings.TrimSpace(value))
+			key = strings.TrimSpace(key)
+			os.Setenv(key, unquoteEnvValue(strings.TrimSpace(value)))
+			keys[key] = true
 		}
 	}
+	return keys
 }
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestGetEnvString(t *testing.T) {
@@ -272,11 +274,43 @@ func TestGetEnvStringArray(t *testing.T) {
 			want:         []string{"x", "y"},
 		},
 		{
-			name:         "returns default when invalid json",
+			name:         "non-json value is a single item",
 			key:          "TEST_ARR_3",
 			envValue:     "not_json",
 			defaultValue: []string{"fallback"},
 			setEnv:       true,
+			want:         []string{"not_json"},
+		},
+		{
+			name:         "comma separated",
+			key:          "TEST_ARR_4",
+			envValue:     "Human:, User: ,Пользователь:",
+			defaultValue: []string{"fallback"},
+			setEnv:       true,
+			want:         []string{"Human:", "User:", "Пользователь:"},
+		},
+		{
+			name:         "comma separated skips empty items",
+			key:          "TEST_ARR_5",
+			envValue:     "a,,b,",
+			defaultValue: []string{"fallback"},
+			setEnv:       true,
+			want:         []string{"a", "b"},
+		},
+		{
+			name:         "returns default for whitespace",
+			key:          "TEST_ARR_6",
+			envValue:     "   ",
+			defaultValue: []string{"fallback"},
+			setEnv:       true,
+			want:         []string{"fallback"},
+		},
+		{
+			name:         "returns default for only commas",
+			key:          "TEST_ARR_7",
+			envValue:     " , ,",
+			defaultValue: []string{"fallback"},
+			setEnv:       true,
 			want:         []string{"fallback"},
 		},
 	}
@@ -336,14 +370,32 @@ func TestGetEnvThinkValue(t *testing.T) {
 			expectBool:   true,
 		},
 		{
-			name:         "returns string when not bool",
+			name:         "returns level string",
 			key:          "TEST_THINK_3",
-			envValue:     "custom_value",
+			envValue:     "high",
+			defaultValue: false,
+			setEnv:       true,
+			wantString:   "high",
+			expectBool:   false,
+		},
+		{
+			name:         "normalizes level string",
+			key:          "TEST_THINK_5",
+			envValue:     " Low ",
 			defaultValue: false,
 			setEnv:       true,
-			wantString:   "custom_value",
+			wantString:   "low",
 			expectBool:   false,
 		},
+		{
+			name:         "falls back to default for unknown string",
+			key:          "TEST_THINK_6",
+			envValue:     "custom_value",
+			defaultValue: false,
+			setEnv:       true,
+			wantBool:     false,
+			expectBool:   true,
+		},
 		{
 			name:         "returns default when not set",
 			key:          "TEST_THINK_4",
@@ -386,3 +438,151 @@ func TestGetEnvThinkValue(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvChoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		want     string
+	}{
+		{"returns default when not set", "", PromptStyleLabeled},
+		{"accepts chatml", "chatml", PromptStyleChatML},
+		{"accepts minimal in upper case", "MINIMAL", PromptStyleMinimal},
+		{"returns default when unknown", "xml", PromptStyleLabeled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Unsetenv("TEST_PROMPT_STYLE")
+
+			if tt.envValue != "" {
+				os.Setenv("TEST_PROMPT_STYLE", tt.envValue)
+				defer os.Unsetenv("TEST_PROMPT_STYLE")
+			}
+
+			got := getEnvChoice("TEST_PROMPT_STYLE", PromptStyleLabeled, PromptStyleLabeled, PromptStyleChatML, PromptStyleMinimal)
+			if got != tt.want {
+				t.Errorf("getEnvChoice() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvSeconds(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		want     time.Duration
+	}{
+		{"returns default when not set", "", 180 * time.Second},
+		{"parses seconds", "30", 30 * time.Second},
+		{"zero disables", "0", 0},
+		{"returns default when invalid", "soon", 180 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECONDS", tt.envValue)
+
+			if got := getEnvSeconds("TEST_SECONDS", 180); got != tt.want {
+				t.Errorf("getEnvSeconds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvKeepAlive(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		wantNil  bool
+		want     time.Duration
+	}{
+		{name: "unset means server default", envValue: "", wantNil: true},
+		{name: "duration string", envValue: "5m", want: 5 * time.Minute},
+		{name: "seconds", envValue: "300", want: 300 * time.Second},
+		{name: "negative keeps forever", envValue: "-1", want: -time.Second},
+		{name: "invalid falls back to default", envValue: "forever", wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_KEEP_ALIVE", tt.envValue)
+
+			got := getEnvKeepAlive("TEST_KEEP_ALIVE")
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("getEnvKeepAlive() = %v, want nil", got.Duration)
+				}
+				return
+			}
+			if got == nil || got.Duration != tt.want {
+				t.Errorf("getEnvKeepAlive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_performanceOptions(t *testing.T) {
+	t.Setenv("NUM_THREAD", "8")
+	t.Setenv("NUM_GPU", "20")
+
+	cfg := loadConfig(filepath.Join(t.TempDir(), "missing.env"))
+
+	if cfg.NumThread != 8 {
+		t.Errorf("NumThread = %d, want 8", cfg.NumThread)
+	}
+	if cfg.NumGPU != 20 {
+		t.Errorf("NumGPU = %d, want 20", cfg.NumGPU)
+	}
+
+	t.Setenv("NUM_THREAD", "")
+	t.Setenv("NUM_GPU", "many")
+
+	cfg = loadConfig(filepath.Join(t.TempDir(), "missing.env"))
+	if cfg.NumThread != 0 || cfg.NumGPU != 0 {
+		t.Errorf("NumThread, NumGPU = %d, %d; want 0, 0 for unset or invalid values", cfg.NumThread, cfg.NumGPU)
+	}
+}
+
+func TestLoadConfig_ctxCharLimit(t *testing.T) {
+	tests := []struct {
+		envValue string
+		want     int
+	}{
+		{"", 12000},
+		{"500", 500},
+		{"0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("CTX_CHAR_LIMIT", tt.envValue)
+
+		cfg := loadConfig(filepath.Join(t.TempDir(), "missing.env"))
+		if cfg.CtxCharLimit != tt.want {
+			t.Errorf("CTX_CHAR_LIMIT=%q: CtxCharLimit = %d, want %d", tt.envValue, cfg.CtxCharLimit, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_requestTimeout(t *testing.T) {
+	tests := []struct {
+		envValue string
+		want     time.Duration
+	}{
+		{"", 180 * time.Second},
+		{"300", 300 * time.Second},
+		{"0", 0},
+		{"-1", -time.Second},
+		{"long", 180 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("REQUEST_TIMEOUT_SECONDS", tt.envValue)
+
+		cfg := loadConfig(filepath.Join(t.TempDir(), "missing.env"))
+		if cfg.RequestTimeout != tt.want {
+			t.Errorf("REQUEST_TIMEOUT_SECONDS=%q: RequestTimeout = %v, want %v", tt.envValue, cfg.RequestTimeout, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"agent/internal/errors"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteProfile сохраняет текущие значения всех настроек, включая изменённые
+// командой /set, в env-файл. Профиль загружается флагом --env или через
+// ENV_FILES.
+func (c *Config) WriteProfile(path string) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# Профиль настроек, сохранён %s\n", time.Now().Format("02.01.2006 15:04"))
+	for _, f := range fields {
+		fmt.Fprintf(&builder, "%s=%s\n", f.key, profileValue(c, f))
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrProfileSave, err)
+	}
+	return nil
+}
+
+// profileValue форматирует значение так, чтобы loadEnvFile прочитал его
+// обратно без изменений: списки — JSON-массивом, строки с пробелами по
+// краям, кавычками или переводами строк — в кавычках.
+func profileValue(c *Config, f field) string {
+	if f.key == "STOP_SEQUENCES" {
+		data, _ := json.Marshal(c.StopSequences)
+		return string(data)
+	}
+
+	value := f.get(c)
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "\"\n\r") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_WriteProfile_roundTrip(t *testing.T) {
+	// loadEnvFile пишет значения в окружение процесса: t.Setenv вернёт его
+	// в исходное состояние после теста
+	for _, f := range fields {
+		t.Setenv(f.key, "")
+	}
+	SetWarningOutput(io.Discard)
+	defer SetWarningOutput(os.Stdout)
+
+	cfg := loadConfig(filepath.Join(t.TempDir(), "missing.env"))
+	overrides := map[string]string{
+		"model":             "llama3",
+		"temp":              "0.75",
+		"MODEL_THINK_VALUE": "high",
+		"system":            "Отвечай кратко.\nБез приветствий.",
+		"prefill":           `Итак, "коротко": `,
+		"stop":              `["Вопрос:", "User, again:"]`,
+		"STALL_TIMEOUT":     "45",
+		"STATELESS":         "true",
+		"DISPLAY_ORDER":     "newest",
+	}
+	for key, value := range overrides {
+		if err := cfg.Set(key, value); err != nil {
+			t.Fatalf("Set(%q, %q) error = %v", key, value, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), ".env.work")
+	if err := cfg.WriteProfile(path); err != nil {
+		t.Fatalf("WriteProfile() error = %v", err)
+	}
+
+	for _, f := range fields {
+		os.Unsetenv(f.key)
+	}
+	reloaded := loadConfig(path)
+
+	for _, f := range fields {
+		if got, want := f.get(reloaded), f.get(cfg); got != want {
+			t.Errorf("%s after reload = %q, want %q", f.key, got, want)
+		}
+	}
+	if reloaded.Source("MODEL_NAME") != SourceEnvFile {
+		t.Errorf("MODEL_NAME source = %q, want %q", reloaded.Source("MODEL_NAME"), SourceEnvFile)
+	}
+}
+
+func TestUnquoteEnvValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{`"Отвечаю четко: "`, "Отвечаю четко: "},
+		{`"две\nстроки"`, "две\nстроки"},
+		{`["Human:", "User:"]`, `["Human:", "User:"]`},
+		{`"незакрытая`, `"незакрытая`},
+		{`"`, `"`},
+	}
+
+	for _, tt := range tests {
+		if got := unquoteEnvValue(tt.value); got != tt.want {
+			t.Errorf("unquoteEnvValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestProfileValue(t *testing.T) {
+	cfg := &Config{StopSequences: []string{"a,b", "c"}, SystemPrompt: " отступ"}
+	for _, f := range fields {
+		switch f.key {
+		case "STOP_SEQUENCES":
+			if got := profileValue(cfg, f); got != `["a,b","c"]` {
+				t.Errorf("STOP_SEQUENCES = %s, want a JSON array", got)
+			}
+		case "SYSTEM_PROMPT":
+			if got := profileValue(cfg, f); !strings.HasPrefix(got, `"`) {
+				t.Errorf("SYSTEM_PROMPT = %s, want a quoted value", got)
+			}
+		}
+	}
+}
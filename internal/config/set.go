@@ -0,0 +1,221 @@
+package config
+
+import (
+	"agent/internal/errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// SourceRuntime — значение изменено командой /set во время работы.
+const SourceRuntime = "/set"
+
+// setter разбирает и проверяет значение, затем записывает его в Config.
+type setter func(c *Config, value string) error
+
+// setters перечисляет настройки, которые можно менять во время работы.
+// Пути к файлам сессий (CTX_DIR, CTX_FILE_EXT и т. п.) сюда не входят:
+// их смена посреди разговора сломала бы сохранение.
+var setters = map[string]setter{
+	"MODEL_NAME":              setNonEmpty(func(c *Config) *string { return &c.ModelName }),
+	"TEMPERATURE":             setNonNegativeFloat(func(c *Config) *float64 { return &c.Temperature }),
+	"TEMPERATURE_STEP":        setNonNegativeFloat(func(c *Config) *float64 { return &c.TemperatureStep }),
+	"MODEL_THINK_VALUE":       setThink,
+	"THINKING_ONLY_REPLY":     setChoice(func(c *Config) *string { return &c.ThinkingOnly }, ThinkingOnlyWarn, ThinkingOnlySave, ThinkingOnlyRetry),
+	"SAVE_THINKING":           setBool(func(c *Config) *bool { return &c.SaveThinking }),
+	"FORMAT":                  setChoice(func(c *Config) *string { return &c.Format }, FormatText, FormatJSON),
+	"FORMAT_RETRIES":          setNonNegative(func(c *Config) *int { return &c.FormatRetries }),
+	"CTX_SIZE_LIMIT":          setNonNegative(func(c *Config) *int { return &c.CtxSizeLimit }),
+	"CTX_CHAR_LIMIT":          setNonNegative(func(c *Config) *int { return &c.CtxCharLimit }),
+	"SYSTEM_PROMPT":           setString(func(c *Config) *string { return &c.SystemPrompt }),
+	"INCLUDE_USERNAME":        setBool(func(c *Config) *bool { return &c.IncludeUserName }),
+	"RESPONSE_LANGUAGE":       setString(func(c *Config) *string { return &c.ResponseLanguage }),
+	"ASSISTANT_PREFILL":       setString(func(c *Config) *string { return &c.AssistantPrefill }),
+	"USE_ASSISTANT_PREFILL":   setBool(func(c *Config) *bool { return &c.UseAssistantPrefill }),
+	"PREFILL_INSTRUCTION":     setNonEmpty(func(c *Config) *string { return &c.PrefillInstruction }),
+	"STOP_SEQUENCES":          setStopSequences,
+	"MAX_RESPONSE_SIZE":       setNonNegative(func(c *Config) *int { return &c.MaxResponseSize }),
+	"PROMPT_STYLE":            setChoice(func(c *Config) *string { return &c.PromptStyle }, PromptStyleLabeled, PromptStyleChatML, PromptStyleMinimal),
+	"DETECT_LOOPS":            setBool(func(c *Config) *bool { return &c.DetectLoops }),
+	"NORMALIZE_UNICODE":       setBool(func(c *Config) *bool { return &c.NormalizeUnicode }),
+	"PRESERVE_TURNS":          setBool(func(c *Config) *bool { return &c.PreserveTurns }),
+	"KEEP_FIRST_MESSAGE":      setBool(func(c *Config) *bool { return &c.KeepFirstMessage }),
+	"MIN_RECENT_TURNS":        setNonNegative(func(c *Config) *int { return &c.MinRecentTurns }),
+	"STATELESS":               setBool(func(c *Config) *bool { return &c.Stateless }),
+	"REMIND_EVERY":            setNonNegative(func(c *Config) *int { return &c.RemindEvery }),
+	"REMINDER_TEXT":           setString(func(c *Config) *string { return &c.ReminderText }),
+	"MAX_TURNS":               setNonNegative(func(c *Config) *int { return &c.MaxTurns }),
+	"STALL_TIMEOUT":           setSeconds(func(c *Config) *time.Duration { return &c.StallTimeout }),
+	"STALL_HINT":              setSeconds(func(c *Config) *time.Duration { return &c.StallHint }),
+	"REQUEST_TIMEOUT_SECONDS": setSeconds(func(c *Config) *time.Duration { return &c.RequestTimeout }),
+	"NUM_CTX":                 setNonNegative(func(c *Config) *int { return &c.NumCtx }),
+	"SHOW_BUDGET":             setBool(func(c *Config) *bool { return &c.ShowBudget }),
+	"TRIM_STOP_SEQUENCES":     setBool(func(c *Config) *bool { return &c.TrimStopSequences }),
+	"TURN_SEPARATOR":          setString(func(c *Config) *string { return &c.TurnSeparator }),
+	"DISPLAY_ORDER":           setChoice(func(c *Config) *string { return &c.DisplayOrder }, DisplayOldest, DisplayNewest),
+	"WRAP_WIDTH":              setNonNegative(func(c *Config) *int { return &c.WrapWidth }),
+	"BREAK_LONG_WORDS":        setBool(func(c *Config) *bool { return &c.BreakLongWords }),
+	"DEBUG":                   setBool(func(c *Config) *bool { return &c.Debug }),
+	"SPINNER":                 setBool(func(c *Config) *bool { return &c.Spinner }),
+	"NORMALIZE_WHITESPACE":    setBool(func(c *Config) *bool { return &c.NormalizeWhitespace }),
+	"SHOW_CONTEXT":            setBool(func(c *Config) *bool { return &c.ShowContext }),
+	"NUM_THREAD":              setNonNegative(func(c *Config) *int { return &c.NumThread }),
+	"NUM_GPU":                 setNonNegative(func(c *Config) *int { return &c.NumGPU }),
+	"MIN_RESPONSE_LEN":        setNonNegative(func(c *Config) *int { return &c.MinResponseLen }),
+	"MIN_RESPONSE_MODE":       setChoice(func(c *Config) *string { return &c.MinResponseMode }, ElaborateAppend, ElaborateReplace),
+	"STOP_ON_BLANK_LINE":      setBool(func(c *Config) *bool { return &c.StopOnBlankLine }),
+}
+
+// keyAliases — короткие имена для часто меняемых настроек.
+var keyAliases = map[string]string{
+	"model":       "MODEL_NAME",
+	"temp":        "TEMPERATURE",
+	"temperature": "TEMPERATURE",
+	"think":       "MODEL_THINK_VALUE",
+	"ctx":         "CTX_SIZE_LIMIT",
+	"system":      "SYSTEM_PROMPT",
+	"prefill":     "ASSISTANT_PREFILL",
+	"stop":        "STOP_SEQUENCES",
+	"style":       "PROMPT_STYLE",
+	"lang":        "RESPONSE_LANGUAGE",
+}
+
+// ResolveKey приводит псевдоним или имя переменной в любом регистре
+// к имени переменной окружения.
+func ResolveKey(key string) string {
+	if name, ok := keyAliases[strings.ToLower(key)]; ok {
+		return name
+	}
+	return strings.ToUpper(key)
+}
+
+// Set меняет настройку во время работы. key — имя переменной окружения
+// (без учёта регистра) или псевдоним вроде «model» и «temp».
+func (c *Config) Set(key, value string) error {
+	name := ResolveKey(key)
+	set, ok := setters[name]
+	if !ok {
+		if c.lookup(name) != nil {
+			return fmt.Errorf("%w: %s нельзя изменить во время работы", errors.ErrInvalidOption, name)
+		}
+		return fmt.Errorf("%w: неизвестная настройка %q", errors.ErrInvalidOption, key)
+	}
+
+	if err := set(c, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%w %s: %v", errors.ErrInvalidOption, name, err)
+	}
+	if c.sources == nil {
+		c.sources = make(configSource)
+	}
+	c.sources[name] = SourceRuntime
+	return nil
+}
+
+// Get возвращает текущее значение настройки по имени или псевдониму.
+func (c *Config) Get(key string) (string, error) {
+	f := c.lookup(ResolveKey(key))
+	if f == nil {
+		return "", fmt.Errorf("%w: неизвестная настройка %q", errors.ErrInvalidOption, key)
+	}
+	return f.get(c), nil
+}
+
+func (c *Config) lookup(name string) *field {
+	for i := range fields {
+		if fields[i].key == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func setString(target func(c *Config) *string) setter {
+	return func(c *Config, value string) error {
+		*target(c) = value
+		return nil
+	}
+}
+
+func setNonEmpty(target func(c *Config) *string) setter {
+	return func(c *Config, value string) error {
+		if value == "" {
+			return fmt.Errorf("значение не может быть пустым")
+		}
+		*target(c) = value
+		return nil
+	}
+}
+
+func setBool(target func(c *Config) *bool) setter {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("ожидается true или false, получено %q", value)
+		}
+		*target(c) = b
+		return nil
+	}
+}
+
+func setNonNegative(target func(c *Config) *int) setter {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("ожидается целое число не меньше 0, получено %q", value)
+		}
+		*target(c) = n
+		return nil
+	}
+}
+
+func setChoice(target func(c *Config) *string, allowed ...string) setter {
+	return func(c *Config, value string) error {
+		value = strings.ToLower(value)
+		if !slices.Contains(allowed, value) {
+			return fmt.Errorf("допустимые значения: %s", strings.Join(allowed, ", "))
+		}
+		*target(c) = value
+		return nil
+	}
+}
+
+func setNonNegativeFloat(target func(c *Config) *float64) setter {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("ожидается число не меньше 0, получено %q", value)
+		}
+		*target(c) = f
+		return nil
+	}
+}
+
+func setThink(c *Config, value string) error {
+	think, ok := parseThinkValue(value)
+	if !ok {
+		return fmt.Errorf("допустимые значения: true, false, %s", strings.Join(ThinkLevels, ", "))
+	}
+	c.ThinkValue = &api.ThinkValue{Value: think}
+	return nil
+}
+
+func setStopSequences(c *Config, value string) error {
+	stops, _ := parseStringList(value)
+	c.StopSequences = stops
+	return nil
+}
+
+func setSeconds(target func(c *Config) *time.Duration) setter {
+	return func(c *Config, value string) error {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("ожидается число секунд не меньше 0, получено %q", value)
+		}
+		*target(c) = time.Duration(seconds) * time.Second
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"agent/internal/errors"
+	stderrors "errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestConfig_Set(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		check func(c *Config) any
+		want  any
+	}{
+		{"model", "llama3", func(c *Config) any { return c.ModelName }, "llama3"},
+		{"temp", "0.7", func(c *Config) any { return c.Temperature }, 0.7},
+		{"TEMPERATURE", "0", func(c *Config) any { return c.Temperature }, 0.0},
+		{"ctx", "20", func(c *Config) any { return c.CtxSizeLimit }, 20},
+		{"system", "Ты — переводчик", func(c *Config) any { return c.SystemPrompt }, "Ты — переводчик"},
+		{"think", "true", func(c *Config) any { return c.ThinkValue.Value }, true},
+		{"think", "high", func(c *Config) any { return c.ThinkValue.Value }, "high"},
+		{"think", "Medium", func(c *Config) any { return c.ThinkValue.Value }, "medium"},
+		{"stop", "User:, Human:", func(c *Config) any { return c.StopSequences }, []string{"User:", "Human:"}},
+		{"style", "ChatML", func(c *Config) any { return c.PromptStyle }, PromptStyleChatML},
+		{"detect_loops", "true", func(c *Config) any { return c.DetectLoops }, true},
+		{"save_thinking", "true", func(c *Config) any { return c.SaveThinking }, true},
+		{"stall_timeout", "30", func(c *Config) any { return c.StallTimeout }, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			c := &Config{ThinkValue: &api.ThinkValue{Value: false}}
+			if err := c.Set(tt.key, tt.value); err != nil {
+				t.Fatalf("Set(%q, %q) error = %v", tt.key, tt.value, err)
+			}
+			if got := tt.check(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after Set(%q, %q) value = %#v, want %#v", tt.key, tt.value, got, tt.want)
+			}
+			if source := c.Source(ResolveKey(tt.key)); source != SourceRuntime {
+				t.Errorf("Source() = %q, want %q", source, SourceRuntime)
+			}
+		})
+	}
+}
+
+func TestConfig_Set_invalid(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{"temp", "hot"},
+		{"temp", "-1"},
+		{"ctx", "-5"},
+		{"ctx", "много"},
+		{"model", ""},
+		{"detect_loops", "maybe"},
+		{"style", "xml"},
+		{"think", "maximum"},
+		{"think", ""},
+		{"CTX_DIR", "/tmp"},
+		{"unknown_key", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			c := &Config{ModelName: "original", Temperature: 0.1, CtxSizeLimit: 10, PromptStyle: PromptStyleLabeled}
+			before := *c
+
+			err := c.Set(tt.key, tt.value)
+			if !stderrors.Is(err, errors.ErrInvalidOption) {
+				t.Errorf("Set(%q, %q) error = %v, want ErrInvalidOption", tt.key, tt.value, err)
+			}
+			if !reflect.DeepEqual(*c, before) {
+				t.Errorf("Set(%q, %q) changed the config on error", tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestConfig_Get(t *testing.T) {
+	c := &Config{ModelName: "llama3", Temperature: 0.5, ThinkValue: &api.ThinkValue{Value: false}}
+
+	if got, err := c.Get("model"); err != nil || got != "llama3" {
+		t.Errorf("Get(model) = %q, %v; want llama3", got, err)
+	}
+	if got, err := c.Get("temperature"); err != nil || got != "0.5" {
+		t.Errorf("Get(temperature) = %q, %v; want 0.5", got, err)
+	}
+	if _, err := c.Get("nope"); !stderrors.Is(err, errors.ErrInvalidOption) {
+		t.Errorf("Get(nope) error = %v, want ErrInvalidOption", err)
+	}
+}
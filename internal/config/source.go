@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Источники, из которых может быть получено значение настройки.
+const (
+	SourceEnv     = "env"
+	SourceEnvFile = ".env"
+	SourceDefault = "default"
+)
+
+// configSource хранит происхождение значения для каждой переменной окружения.
+type configSource map[string]string
+
+// field связывает переменную окружения с полем Config.
+type field struct {
+	key string
+	get func(c *Config) string
+}
+
+// fields перечисляет все настройки в порядке вывода.
+var fields = []field{
+	{"MODEL_NAME", func(c *Config) string { return c.ModelName }},
+	{"BACKEND", func(c *Config) string { return c.Backend }},
+	{"OPENAI_BASE_URL", func(c *Config) string { return c.OpenAIBaseURL }},
+	{"TEMPERATURE", func(c *Config) string { return strconv.FormatFloat(c.Temperature, 'f', -1, 64) }},
+	{"TEMPERATURE_STEP", func(c *Config) string { return strconv.FormatFloat(c.TemperatureStep, 'f', -1, 64) }},
+	{"MODEL_THINK_VALUE", func(c *Config) string { return fmt.Sprint(c.ThinkValue.Value) }},
+	{"THINKING_ONLY_REPLY", func(c *Config) string { return c.ThinkingOnly }},
+	{"SAVE_THINKING", func(c *Config) string { return strconv.FormatBool(c.SaveThinking) }},
+	{"FORMAT", func(c *Config) string { return c.Format }},
+	{"FORMAT_RETRIES", func(c *Config) string { return strconv.Itoa(c.FormatRetries) }},
+	{"CTX_DIR", func(c *Config) string { return c.CtxDir }},
+	{"CTX_SIZE_LIMIT", func(c *Config) string { return strconv.Itoa(c.CtxSizeLimit) }},
+	{"CTX_CHAR_LIMIT", func(c *Config) string { return strconv.Itoa(c.CtxCharLimit) }},
+	{"CTX_FILE_EXT", func(c *Config) string { return c.CtxFileExt }},
+	{"SYSTEM_PROMPT", func(c *Config) string { return c.SystemPrompt }},
+	{"INCLUDE_USERNAME", func(c *Config) string { return strconv.FormatBool(c.IncludeUserName) }},
+	{"RESPONSE_LANGUAGE", func(c *Config) string { return c.ResponseLanguage }},
+	{"ASSISTANT_PREFILL", func(c *Config) string { return c.AssistantPrefill }},
+	{"USE_ASSISTANT_PREFILL", func(c *Config) string { return strconv.FormatBool(c.UseAssistantPrefill) }},
+	{"PREFILL_INSTRUCTION", func(c *Config) string { return c.PrefillInstruction }},
+	{"STOP_SEQUENCES", func(c *Config) string { return fmt.Sprintf("%q", c.StopSequences) }},
+	{"MAX_RESPONSE_SIZE", func(c *Config) string { return strconv.Itoa(c.MaxResponseSize) }},
+	{"PROMPT_STYLE", func(c *Config) string { return c.PromptStyle }},
+	{"BACKUP_COUNT", func(c *Config) string { return strconv.Itoa(c.BackupCount) }},
+	{"COMPACT_AUTOSAVE", func(c *Config) string { return strconv.FormatBool(c.CompactAutosave) }},
+	{"ON_RESPONSE_CMD", func(c *Config) string { return c.OnResponseCmd }},
+	{"DETECT_LOOPS", func(c *Config) string { return strconv.FormatBool(c.DetectLoops) }},
+	{"NORMALIZE_UNICODE", func(c *Config) string { return strconv.FormatBool(c.NormalizeUnicode) }},
+	{"PRESERVE_TURNS", func(c *Config) string { return strconv.FormatBool(c.PreserveTurns) }},
+	{"KEEP_FIRST_MESSAGE", func(c *Config) string { return strconv.FormatBool(c.KeepFirstMessage) }},
+	{"MIN_RECENT_TURNS", func(c *Config) string { return strconv.Itoa(c.MinRecentTurns) }},
+	{"STATELESS", func(c *Config) string { return strconv.FormatBool(c.Stateless) }},
+	{"REMIND_EVERY", func(c *Config) string { return strconv.Itoa(c.RemindEvery) }},
+	{"REMINDER_TEXT", func(c *Config) string { return c.ReminderText }},
+	{"MAX_TURNS", func(c *Config) string { return strconv.Itoa(c.MaxTurns) }},
+	{"STALL_TIMEOUT", func(c *Config) string { return strconv.Itoa(int(c.StallTimeout.Seconds())) }},
+	{"STALL_HINT", func(c *Config) string { return strconv.Itoa(int(c.StallHint.Seconds())) }},
+	{"REQUEST_TIMEOUT_SECONDS", func(c *Config) string { return strconv.Itoa(int(c.RequestTimeout.Seconds())) }},
+	{"NUM_CTX", func(c *Config) string { return strconv.Itoa(c.NumCtx) }},
+	{"SHOW_BUDGET", func(c *Config) string { return strconv.FormatBool(c.ShowBudget) }},
+	{"TRIM_STOP_SEQUENCES", func(c *Config) string { return strconv.FormatBool(c.TrimStopSequences) }},
+	{"KEEP_ALIVE", func(c *Config) string { return formatKeepAlive(c.KeepAlive) }},
+	{"CONSECUTIVE_ROLES", func(c *Config) string { return c.RoleRuns }},
+	{"AUTO_RESUME", func(c *Config) string { return strconv.FormatBool(c.AutoResume) }},
+	{"TURN_SEPARATOR", func(c *Config) string { return c.TurnSeparator }},
+	{"STARTUP_BANNER", func(c *Config) string { return c.StartupBanner }},
+	{"DISPLAY_ORDER", func(c *Config) string { return c.DisplayOrder }},
+	{"WRAP_WIDTH", func(c *Config) string { return strconv.Itoa(c.WrapWidth) }},
+	{"BREAK_LONG_WORDS", func(c *Config) string { return strconv.FormatBool(c.BreakLongWords) }},
+	{"DEBUG", func(c *Config) string { return strconv.FormatBool(c.Debug) }},
+	{"SPINNER", func(c *Config) string { return strconv.FormatBool(c.Spinner) }},
+	{"NORMALIZE_WHITESPACE", func(c *Config) string { return strconv.FormatBool(c.NormalizeWhitespace) }},
+	{"MAX_SESSIONS", func(c *Config) string { return strconv.Itoa(c.MaxSessions) }},
+	{"SESSION_QUOTA_POLICY", func(c *Config) string { return c.QuotaPolicy }},
+	{"MAX_SESSION_BYTES", func(c *Config) string { return strconv.FormatInt(c.MaxSessionBytes, 10) }},
+	{"SESSION_SIZE_POLICY", func(c *Config) string { return c.SessionSizePolicy }},
+	{"SHOW_CONTEXT", func(c *Config) string { return strconv.FormatBool(c.ShowContext) }},
+	{"NUM_THREAD", func(c *Config) string { return strconv.Itoa(c.NumThread) }},
+	{"NUM_GPU", func(c *Config) string { return strconv.Itoa(c.NumGPU) }},
+	{"MIN_RESPONSE_LEN", func(c *Config) string { return strconv.Itoa(c.MinResponseLen) }},
+	{"MIN_RESPONSE_MODE", func(c *Config) string { return c.MinResponseMode }},
+	{"STOP_ON_BLANK_LINE", func(c *Config) string { return strconv.FormatBool(c.StopOnBlankLine) }},
+	{"SESSION_CATEGORY", func(c *Config) string { return c.SessionCategory }},
+	{"RESPONSE_CACHE_DIR", func(c *Config) string { return c.ResponseCacheDir }},
+}
+
+func detectSources(fileKeys map[string]bool) configSource {
+	sources := make(configSource, len(fields))
+	for _, f := range fields {
+		switch {
+		case os.Getenv(f.key) == "":
+			sources[f.key] = SourceDefault
+		case fileKeys[f.key]:
+			sources[f.key] = SourceEnvFile
+		default:
+			sources[f.key] = SourceEnv
+		}
+	}
+	return sources
+}
+
+// Source возвращает происхождение значения переменной key.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// DisplaySources печатает каждую настройку вместе с её источником.
+func (c *Config) DisplaySources() {
+	fmt.Println("🔎 Источники настроек:")
+	for _, f := range fields {
+		fmt.Printf("  %s = %s (%s)\n", f.key, f.get(c), c.Source(f.key))
+	}
+	fmt.Println()
+}
+
+func formatKeepAlive(d *api.Duration) string {
+	switch {
+	case d == nil:
+		return ""
+	case d.Duration < 0:
+		return "-1"
+	default:
+		return d.Duration.String()
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_recordsSources(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("# comment\nCTX_DIR=from_file\n"), 0644); err != nil {
+		t.Fatalf("writing env file: %v", err)
+	}
+
+	t.Setenv("MODEL_NAME", "llama3")
+	t.Setenv("TEMPERATURE", "")
+	t.Setenv("CTX_DIR", "")
+
+	cfg := loadConfig(envFile)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"MODEL_NAME", SourceEnv},
+		{"TEMPERATURE", SourceDefault},
+		{"CTX_DIR", SourceEnvFile},
+		{"UNKNOWN_KEY", SourceDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := cfg.Source(tt.key); got != tt.want {
+				t.Errorf("Source(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	if cfg.ModelName != "llama3" {
+		t.Errorf("ModelName = %q, want %q", cfg.ModelName, "llama3")
+	}
+	if cfg.CtxDir != "from_file" {
+		t.Errorf("CtxDir = %q, want %q", cfg.CtxDir, "from_file")
+	}
+}
+
+func TestLoadConfig_laterEnvFilesOverride(t *testing.T) {
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, ".env.defaults")
+	local := filepath.Join(dir, ".env.local")
+
+	if err := os.WriteFile(defaults, []byte("MODEL_NAME=llama3\nCTX_DIR=shared\n"), 0644); err != nil {
+		t.Fatalf("writing defaults: %v", err)
+	}
+	if err := os.WriteFile(local, []byte("MODEL_NAME=qwen3\n"), 0644); err != nil {
+		t.Fatalf("writing local: %v", err)
+	}
+
+	t.Setenv("MODEL_NAME", "")
+	t.Setenv("CTX_DIR", "")
+
+	cfg := loadConfig(defaults, local)
+
+	if cfg.ModelName != "qwen3" {
+		t.Errorf("ModelName = %q, want %q from the later file", cfg.ModelName, "qwen3")
+	}
+	if cfg.CtxDir != "shared" {
+		t.Errorf("CtxDir = %q, want %q from the earlier file", cfg.CtxDir, "shared")
+	}
+	for _, key := range []string{"MODEL_NAME", "CTX_DIR"} {
+		if got := cfg.Source(key); got != SourceEnvFile {
+			t.Errorf("Source(%q) = %q, want %q", key, got, SourceEnvFile)
+		}
+	}
+}
+
+func TestDefaultEnvFiles(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{".env"}},
+		{".env.defaults,.env.local", []string{".env.defaults", ".env.local"}},
+		{" .env.defaults , ,.env.local ", []string{".env.defaults", ".env.local"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("ENV_FILES", tt.value)
+			got := defaultEnvFiles()
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("defaultEnvFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
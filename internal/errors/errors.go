@@ -1,6 +1,9 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNoMessages     = errors.New("нет сообщений для отправки")
@@ -15,4 +18,37 @@ var (
 	ErrFileParse      = errors.New("ошибка парсинга файла сессии")
 	ErrFileSave       = errors.New("ошибка сохранения файла сессии")
 	ErrSessionInit    = errors.New("ошибка инициализации сессии")
+	ErrBackupNotFound = errors.New("резервная копия сессии не найдена")
+	ErrLoopDetected   = errors.New("обнаружено зацикливание ответа")
+	ErrNothingToRetry = errors.New("нет вопроса для повторной генерации")
+	ErrStreamStalled  = errors.New("модель перестала отвечать")
+	ErrInvalidOption  = errors.New("некорректное значение опции")
+	ErrEmptyImport    = errors.New("в импортируемом файле нет сообщений")
+	ErrInvalidAge     = errors.New("некорректный срок давности")
+	ErrSessionQuota   = errors.New("превышено максимальное количество сессий")
+	ErrBlankLineStop  = errors.New("генерация остановлена на пустой строке")
+	ErrInvalidImage   = errors.New("недопустимое изображение")
+	ErrSessionSize    = errors.New("файл сессии превышает допустимый размер")
+	ErrNoSession      = errors.New("сессия не найдена")
+	ErrInputRejected  = errors.New("текст отклонён политикой обработки")
+	ErrNoResponse     = errors.New("сервер не прислал ответ")
+	ErrProfileSave    = errors.New("ошибка сохранения профиля")
+	ErrServerResponse = errors.New("сервер вернул ошибку")
 )
+
+// GenerateError описывает неудачный запрос к модели: что именно было
+// отправлено и чем запрос завершился.
+type GenerateError struct {
+	Model     string
+	PromptLen int // длина промпта в символах
+	Options   map[string]any
+	Err       error
+}
+
+func (e *GenerateError) Error() string {
+	return fmt.Sprintf("модель %s, промпт %d символов: %v", e.Model, e.PromptLen, e.Err)
+}
+
+func (e *GenerateError) Unwrap() error {
+	return e.Err
+}
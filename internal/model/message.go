@@ -8,18 +8,32 @@ import (
 const (
 	RoleUser      = "user"
 	RoleAssistant = "assistant"
+	RoleSystem    = "system" // закреплённая инструкция сессии, всегда попадает в контекст
+)
+
+// Причины, по которым ответ модели был сохранён не полностью.
+const (
+	TruncatedLoop        = "loop"
+	TruncatedInterrupted = "interrupted" // генерация прервана пользователем (Ctrl+C)
 )
 
 type Message struct {
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	Truncated string    `json:"truncated,omitempty"`
+	Thinking  string    `json:"thinking,omitempty"`
 }
 
+// NewMessage создаёт сообщение с текущим временем. Пустое содержимое
+// проверяется раньше роли; неизвестная роль возвращает ErrInvalidRole.
 func NewMessage(role, content string) (*Message, error) {
 	if content == "" {
 		return nil, errors.ErrEmptyContent
 	}
+	if !ValidRole(role) {
+		return nil, errors.ErrInvalidRole
+	}
 
 	return &Message{
 		Role:      role,
@@ -28,10 +42,25 @@ func NewMessage(role, content string) (*Message, error) {
 	}, nil
 }
 
+// ValidRole сообщает, является ли role одной из ролей, которые хранятся
+// в истории сессии.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleAssistant, RoleSystem:
+		return true
+	default:
+		return false
+	}
+}
+
 func (m *Message) IsUser() bool {
 	return m.Role == RoleUser
 }
 
+func (m *Message) IsSystem() bool {
+	return m.Role == RoleSystem
+}
+
 func (m *Message) isAssistant() bool {
 	return m.Role == RoleAssistant
 }
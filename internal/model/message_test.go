@@ -30,12 +30,38 @@ func TestNewMessage(t *testing.T) {
 			wantRole:    RoleAssistant,
 			wantContent: "Hi there!",
 		},
+		{
+			name:        "valid system message",
+			role:        RoleSystem,
+			content:     "Отвечай только на русском.",
+			wantErr:     nil,
+			wantRole:    RoleSystem,
+			wantContent: "Отвечай только на русском.",
+		},
 		{
 			name:    "empty content returns error",
 			role:    RoleUser,
 			content: "",
 			wantErr: errors.ErrEmptyContent,
 		},
+		{
+			name:    "unknown role returns error",
+			role:    "robot",
+			content: "Beep",
+			wantErr: errors.ErrInvalidRole,
+		},
+		{
+			name:    "empty role returns error",
+			role:    "",
+			content: "Hello",
+			wantErr: errors.ErrInvalidRole,
+		},
+		{
+			name:    "empty content takes precedence over role",
+			role:    "robot",
+			content: "",
+			wantErr: errors.ErrEmptyContent,
+		},
 		{
 			name:        "unicode content",
 			role:        RoleUser,
@@ -118,6 +144,39 @@ func TestMessage_IsUser(t *testing.T) {
 	}
 }
 
+func TestMessage_IsSystem(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		want bool
+	}{
+		{
+			name: "system role returns true",
+			role: RoleSystem,
+			want: true,
+		},
+		{
+			name: "user role returns false",
+			role: RoleUser,
+			want: false,
+		},
+		{
+			name: "assistant role returns false",
+			role: RoleAssistant,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{Role: tt.role}
+			if got := msg.IsSystem(); got != tt.want {
+				t.Errorf("Message.IsSystem() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestMessage_isAssistant(t *testing.T) {
 	tests := []struct {
 		name string
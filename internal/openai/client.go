@@ -0,0 +1,257 @@
+package openai
+
+import (
+	"agent/internal/errors"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// maxErrorBody ограничивает, сколько текста ошибки сервера попадёт в сообщение.
+const maxErrorBody = 512
+
+// Client обращается к OpenAI-совместимому API (/v1/chat/completions)
+// и отдаёт ответ в тех же типах, что и клиент Ollama, поэтому чат
+// работает с ним без изменений.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient создаёт клиент для API по адресу baseURL (например,
+// http://localhost:8000/v1). Пустой apiKey — запросы без авторизации.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    http.DefaultClient,
+	}
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"` // строка или список частей с изображениями
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// Generate отправляет запрос в /chat/completions с потоковой передачей
+// и вызывает fn для каждого фрагмента ответа, а в конце — с Done: true.
+// Системный промпт и промпт превращаются в сообщения system и user.
+func (c *Client) Generate(ctx context.Context, req *api.GenerateRequest, fn api.GenerateResponseFunc) error {
+	body, err := json.Marshal(newChatRequest(req))
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	final := api.GenerateResponse{Model: req.Model, Done: true}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // пустые строки и комментарии SSE
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("%w: некорректный фрагмент потока: %v", errors.ErrServerResponse, err)
+		}
+		if chunk.Usage != nil {
+			final.PromptEvalCount = chunk.Usage.PromptTokens
+			final.EvalCount = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				final.DoneReason = *choice.FinishReason
+			}
+			if choice.Delta.Content == "" && choice.Delta.ReasoningContent == "" {
+				continue
+			}
+			err := fn(api.GenerateResponse{
+				Model:    req.Model,
+				Response: choice.Delta.Content,
+				Thinking: choice.Delta.ReasoningContent,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return fn(final)
+}
+
+// Heartbeat проверяет, что сервер отвечает, запросом списка моделей.
+func (c *Client) Heartbeat(ctx context.Context) error {
+	_, err := c.List(ctx)
+	return err
+}
+
+// List возвращает модели сервера (/models) в формате списка Ollama.
+func (c *Client) List(ctx context.Context) (*api.ListResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("%w: некорректный список моделей: %v", errors.ErrServerResponse, err)
+	}
+
+	list := &api.ListResponse{}
+	for _, m := range models.Data {
+		list.Models = append(list.Models, api.ListModelResponse{Name: m.ID, Model: m.ID})
+	}
+	return list, nil
+}
+
+// do выполняет запрос и возвращает ответ только при статусе 2xx.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("%w: %s: %s", errors.ErrServerResponse, resp.Status, strings.TrimSpace(string(text)))
+	}
+	return resp, nil
+}
+
+// newChatRequest переводит запрос Ollama в формат chat completions.
+// Опции, которых нет в OpenAI API (num_ctx, num_gpu и т. п.), не передаются.
+func newChatRequest(req *api.GenerateRequest) chatRequest {
+	chat := chatRequest{Model: req.Model, Stream: true}
+	if req.System != "" {
+		chat.Messages = append(chat.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	chat.Messages = append(chat.Messages, chatMessage{Role: "user", Content: userContent(req)})
+
+	if v, ok := floatOption(req.Options["temperature"]); ok {
+		chat.Temperature = &v
+	}
+	if v, ok := floatOption(req.Options["top_p"]); ok {
+		chat.TopP = &v
+	}
+	if v, ok := floatOption(req.Options["seed"]); ok {
+		seed := int(v)
+		chat.Seed = &seed
+	}
+	if v, ok := floatOption(req.Options["num_predict"]); ok && v > 0 {
+		chat.MaxTokens = int(v)
+	}
+	if stop, ok := req.Options["stop"].([]string); ok {
+		chat.Stop = stop
+	}
+	if string(req.Format) == `"json"` {
+		chat.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	return chat
+}
+
+// userContent возвращает текст промпта, а при вложенных изображениях —
+// список частей с изображениями в виде data URL.
+func userContent(req *api.GenerateRequest) any {
+	if len(req.Images) == 0 {
+		return req.Prompt
+	}
+
+	parts := []contentPart{{Type: "text", Text: req.Prompt}}
+	for _, image := range req.Images {
+		url := "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image)
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+	return parts
+}
+
+// floatOption приводит числовую опцию к float64: опции @key=value бывают
+// как дробными, так и целыми.
+func floatOption(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
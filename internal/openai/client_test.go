@@ -0,0 +1,180 @@
+package openai
+
+import (
+	"agent/internal/errors"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+)
+
+func TestClient_Generate(t *testing.T) {
+	var got chatRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"choices":[{"delta":{"role":"assistant"}}]}`,
+			`{"choices":[{"delta":{"reasoning_content":"хм"}}]}`,
+			`{"choices":[{"delta":{"content":"При"}}]}`,
+			`{"choices":[{"delta":{"content":"вет"}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":12,"completion_tokens":2}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/v1/", "secret")
+	req := &api.GenerateRequest{
+		Model:  "gpt-test",
+		System: "Будь краток.",
+		Prompt: "Привет",
+		Options: map[string]any{
+			"temperature": 0.3,
+			"stop":        []string{"User:"},
+			"num_predict": 0,
+			"seed":        7,
+			"num_ctx":     4096,
+		},
+	}
+
+	var responses []api.GenerateResponse
+	err := client.Generate(context.Background(), req, func(resp api.GenerateResponse) error {
+		responses = append(responses, resp)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", auth)
+	}
+	if !got.Stream || got.Model != "gpt-test" {
+		t.Errorf("request model = %q, stream = %t", got.Model, got.Stream)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[0].Content != "Будь краток." ||
+		got.Messages[1].Role != "user" || got.Messages[1].Content != "Привет" {
+		t.Errorf("messages = %+v, want system and user", got.Messages)
+	}
+	if got.Temperature == nil || *got.Temperature != 0.3 || got.Seed == nil || *got.Seed != 7 {
+		t.Errorf("temperature = %v, seed = %v", got.Temperature, got.Seed)
+	}
+	if got.MaxTokens != 0 || len(got.Stop) != 1 || got.Stop[0] != "User:" {
+		t.Errorf("max_tokens = %d, stop = %q", got.MaxTokens, got.Stop)
+	}
+
+	want := []api.GenerateResponse{
+		{Model: "gpt-test", Thinking: "хм"},
+		{Model: "gpt-test", Response: "При"},
+		{Model: "gpt-test", Response: "вет"},
+	}
+	if len(responses) != len(want)+1 {
+		t.Fatalf("got %d callbacks, want %d", len(responses), len(want)+1)
+	}
+	for i, w := range want {
+		r := responses[i]
+		if r.Response != w.Response || r.Thinking != w.Thinking || r.Model != w.Model || r.Done {
+			t.Errorf("chunk %d = %+v, want %+v", i, r, w)
+		}
+	}
+	final := responses[len(responses)-1]
+	if !final.Done || final.DoneReason != "stop" || final.PromptEvalCount != 12 || final.EvalCount != 2 {
+		t.Errorf("final = %+v, want done with usage", final)
+	}
+}
+
+func TestClient_Generate_callbackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"b\"}}]}\n\n")
+	}))
+	defer server.Close()
+
+	stop := stderrors.New("stop")
+	calls := 0
+	err := NewClient(server.URL, "").Generate(context.Background(), &api.GenerateRequest{Model: "m"}, func(api.GenerateResponse) error {
+		calls++
+		return stop
+	})
+	if !stderrors.Is(err, stop) || calls != 1 {
+		t.Errorf("Generate() error = %v after %d calls, want the callback error after 1", err, calls)
+	}
+}
+
+func TestClient_Generate_serverError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := NewClient(server.URL, "bad").Generate(context.Background(), &api.GenerateRequest{Model: "m"}, func(api.GenerateResponse) error {
+		t.Error("callback called on a failed request")
+		return nil
+	})
+	if !stderrors.Is(err, errors.ErrServerResponse) {
+		t.Errorf("Generate() error = %v, want ErrServerResponse", err)
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-test"},{"id":"other"}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "")
+	if err := client.Heartbeat(context.Background()); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	list, err := client.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list.Models) != 2 || list.Models[0].Name != "gpt-test" {
+		t.Errorf("List() = %+v, want gpt-test and other", list.Models)
+	}
+}
+
+func TestNewChatRequest_images(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	chat := newChatRequest(&api.GenerateRequest{
+		Prompt: "Что на картинке?",
+		Images: []api.ImageData{png},
+		Format: json.RawMessage(`"json"`),
+		Options: map[string]any{
+			"num_predict": 100,
+		},
+	})
+
+	parts, ok := chat.Messages[0].Content.([]contentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("content = %#v, want text and image parts", chat.Messages[0].Content)
+	}
+	if parts[0].Text != "Что на картинке?" || parts[1].ImageURL == nil || parts[1].ImageURL.URL[:22] != "data:image/png;base64," {
+		t.Errorf("parts = %+v", parts)
+	}
+	if chat.ResponseFormat == nil || chat.ResponseFormat.Type != "json_object" || chat.MaxTokens != 100 {
+		t.Errorf("response_format = %v, max_tokens = %d", chat.ResponseFormat, chat.MaxTokens)
+	}
+}
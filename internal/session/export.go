@@ -0,0 +1,104 @@
+package session
+
+import (
+	"agent/internal/model"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// htmlBlock — фрагмент сообщения: обычный текст или блок кода.
+type htmlBlock struct {
+	Code bool
+	Text string
+}
+
+type htmlMessage struct {
+	Role   string // класс «пузыря»: user, assistant или system
+	Time   string
+	Blocks []htmlBlock
+}
+
+var htmlPage = template.Must(template.New("session").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; background: #f4f4f6; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; }
+.message { border-radius: 1rem; padding: 0.75rem 1rem; margin: 0.75rem 0; max-width: 85%; white-space: pre-wrap; }
+.user { background: #d8ecff; margin-left: auto; }
+.assistant { background: #ffffff; border: 1px solid #e0e0e6; }
+.system { background: #fff6d8; margin: 0.75rem auto; font-style: italic; }
+.time { color: #888; font-size: 0.75rem; }
+pre { background: #272822; color: #f8f8f2; padding: 0.75rem; border-radius: 0.5rem; overflow-x: auto; white-space: pre; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Messages}}<div class="message {{.Role}}">
+<div class="time">{{.Time}}</div>
+{{range .Blocks}}{{if .Code}}<pre><code>{{.Text}}</code></pre>{{else}}<p>{{.Text}}</p>{{end}}
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// ExportHTML записывает сессию в w как самостоятельную HTML-страницу:
+// реплики оформлены «пузырями», текст экранирован, а блоки ```кода```
+// выводятся в <pre>.
+func ExportHTML(session *ChatSession, w io.Writer) error {
+	title := session.Title
+	if title == "" {
+		title = "Чат " + session.UserName
+	}
+
+	messages := make([]htmlMessage, 0, len(session.Messages))
+	for _, msg := range session.Messages {
+		messages = append(messages, htmlMessage{
+			Role:   htmlRole(msg.Role),
+			Time:   msg.Timestamp.Format("2006-01-02 15:04"),
+			Blocks: splitCodeBlocks(msg),
+		})
+	}
+
+	err := htmlPage.Execute(w, struct {
+		Title    string
+		Messages []htmlMessage
+	}{title, messages})
+	if err != nil {
+		return fmt.Errorf("экспорт в HTML: %w", err)
+	}
+	return nil
+}
+
+// htmlRole возвращает класс «пузыря» для роли сообщения; всё, кроме
+// вопросов и системных инструкций, оформляется как ответ ассистента.
+func htmlRole(role string) string {
+	if role == model.RoleUser || role == model.RoleSystem {
+		return role
+	}
+	return model.RoleAssistant
+}
+
+// splitCodeBlocks делит содержимое сообщения по огороженным ``` блокам.
+// Строка с языком после ``` отбрасывается.
+func splitCodeBlocks(msg model.Message) []htmlBlock {
+	var blocks []htmlBlock
+	parts := strings.Split(msg.Content, "```")
+	for i, part := range parts {
+		code := i%2 == 1
+		if code {
+			if lang, rest, ok := strings.Cut(part, "\n"); ok && !strings.ContainsAny(lang, " \t") {
+				part = rest
+			}
+		}
+		part = strings.Trim(part, "\n")
+		if part == "" {
+			continue
+		}
+		blocks = append(blocks, htmlBlock{Code: code, Text: part})
+	}
+	return blocks
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"agent/internal/model"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportHTML(t *testing.T) {
+	session := &ChatSession{
+		UserName: "testuser",
+		Messages: []model.Message{
+			{Role: model.RoleSystem, Content: "Отвечай кратко.", Timestamp: time.Now()},
+			{Role: model.RoleUser, Content: "Что делает <script>alert(1)</script>?", Timestamp: time.Now()},
+			{Role: model.RoleAssistant, Content: "Пример:\n```go\nif a < b && b > c {}\n```\nГотово.", Timestamp: time.Now()},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportHTML(session, &buf); err != nil {
+		t.Fatalf("ExportHTML() error = %v", err)
+	}
+	out := buf.String()
+
+	wants := []string{
+		"<!DOCTYPE html>",
+		"<title>Чат testuser</title>",
+		`<div class="message user">`,
+		`<div class="message assistant">`,
+		`<div class="message system">`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"<pre><code>if a &lt; b &amp;&amp; b &gt; c {}</code></pre>",
+		"<p>Готово.</p>",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("ExportHTML() output missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("ExportHTML() must escape message content")
+	}
+	if strings.Contains(out, "go\nif") {
+		t.Error("language tag of the code block should be dropped")
+	}
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// markdownTimeLayout — формат времени в заголовках сообщений Markdown.
+const markdownTimeLayout = "2006-01-02 15:04"
+
+// markdownHeadings сопоставляет заголовки сообщений в Markdown с ролями.
+var markdownHeadings = []struct {
+	heading string
+	role    string
+}{
+	{"## Пользователь", model.RoleUser},
+	{"## Ассистент", model.RoleAssistant},
+	{"## Система", model.RoleSystem},
+}
+
+// ExportMarkdown записывает сессию в w как Markdown: заголовок сессии (если
+// задан) и по разделу «## Пользователь (время)» / «## Ассистент (время)» /
+// «## Система (время)» на каждое сообщение. ImportMarkdown читает этот
+// формат обратно.
+func ExportMarkdown(session *ChatSession, w io.Writer) error {
+	var builder strings.Builder
+	if session.Title != "" {
+		builder.WriteString("# " + session.Title + "\n\n")
+	}
+
+	for _, msg := range session.Messages {
+		builder.WriteString(fmt.Sprintf("%s (%s)\n\n%s\n\n", markdownHeading(msg.Role), msg.Timestamp.Local().Format(markdownTimeLayout), msg.Content))
+	}
+
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("экспорт в Markdown: %w", err)
+	}
+	return nil
+}
+
+// markdownHeading возвращает заголовок раздела для роли; неизвестные роли
+// выводятся как ответы ассистента.
+func markdownHeading(role string) string {
+	for _, h := range markdownHeadings {
+		if h.role == role {
+			return h.heading
+		}
+	}
+	return markdownHeadings[1].heading
+}
+
+// ImportMarkdown разбирает Markdown, созданный ExportMarkdown, в новую
+// сессию. Заголовки внутри блоков ```кода``` не считаются началом нового
+// сообщения; текст до первого заголовка сообщения пропускается. Время из
+// заголовка восстанавливается, а если его нет — берётся текущее. Имя
+// пользователя не заполняется, как и в ImportTranscript.
+func ImportMarkdown(r io.Reader, cfg *config.Config) (*ChatSession, error) {
+	var messages []model.Message
+	var current *model.Message
+	var lines []string
+	var title string
+	var inCode bool
+	now := time.Now()
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		if current.Content != "" {
+			messages = append(messages, *current)
+		}
+		current, lines = nil, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+
+		if !inCode {
+			if role, timestamp, ok := parseMarkdownHeading(line, now); ok {
+				flush()
+				current = &model.Message{Role: role, Timestamp: timestamp}
+				continue
+			}
+			if current == nil && title == "" && strings.HasPrefix(line, "# ") {
+				title = strings.TrimSpace(line[2:])
+				continue
+			}
+		}
+
+		if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+	flush()
+
+	if len(messages) == 0 {
+		return nil, errors.ErrEmptyImport
+	}
+
+	return &ChatSession{
+		Title:    title,
+		Messages: messages,
+		Created:  messages[0].Timestamp,
+		Updated:  now,
+		Cfg:      cfg,
+	}, nil
+}
+
+// parseMarkdownHeading распознаёт заголовок сообщения вида
+// «## Пользователь (2006-01-02 15:04)». Время необязательно.
+func parseMarkdownHeading(line string, now time.Time) (role string, timestamp time.Time, ok bool) {
+	for _, h := range markdownHeadings {
+		rest, found := strings.CutPrefix(line, h.heading)
+		if !found {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return h.role, now, true
+		}
+		if value, ok := strings.CutPrefix(rest, "("); ok {
+			if value, ok = strings.CutSuffix(value, ")"); ok {
+				if t, err := time.ParseInLocation(markdownTimeLayout, value, time.Local); err == nil {
+					return h.role, t, true
+				}
+			}
+		}
+	}
+	return "", time.Time{}, false
+}
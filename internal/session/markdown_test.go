@@ -0,0 +1,63 @@
+package session
+
+import (
+	"agent/internal/config"
+	agenterrors "agent/internal/errors"
+	"agent/internal/model"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdown_roundTrip(t *testing.T) {
+	start := time.Date(2025, 12, 1, 10, 0, 0, 0, time.Local)
+	original := &ChatSession{
+		UserName: "testuser",
+		Title:    "Кофе",
+		Messages: []model.Message{
+			{Role: model.RoleSystem, Content: "Отвечай кратко.", Timestamp: start.Add(-time.Minute)},
+			{Role: model.RoleUser, Content: "Как сварить кофе?", Timestamp: start},
+			{Role: model.RoleAssistant, Content: "Вот шаги:\n1. Смелите зёрна.\n\n2. Залейте водой.", Timestamp: start.Add(time.Minute)},
+			{Role: model.RoleUser, Content: "Покажи пример разметки", Timestamp: start.Add(2 * time.Minute)},
+			{Role: model.RoleAssistant, Content: "```md\n## Пользователь\n```\nЭто заголовок.", Timestamp: start.Add(3 * time.Minute)},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportMarkdown(original, &buf); err != nil {
+		t.Fatalf("ExportMarkdown() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "# Кофе\n\n## Система (2025-12-01 09:59)\n\nОтвечай кратко.\n\n## Пользователь (2025-12-01 10:00)\n\nКак сварить кофе?\n") {
+		t.Errorf("unexpected Markdown:\n%s", buf.String())
+	}
+
+	imported, err := ImportMarkdown(&buf, &config.Config{})
+	if err != nil {
+		t.Fatalf("ImportMarkdown() error = %v", err)
+	}
+
+	if imported.Title != original.Title {
+		t.Errorf("Title = %q, want %q", imported.Title, original.Title)
+	}
+	if len(imported.Messages) != len(original.Messages) {
+		t.Fatalf("messages = %d, want %d: %+v", len(imported.Messages), len(original.Messages), imported.Messages)
+	}
+	for i, want := range original.Messages {
+		got := imported.Messages[i]
+		if got.Role != want.Role || got.Content != want.Content {
+			t.Errorf("message[%d] = {%s %q}, want {%s %q}", i, got.Role, got.Content, want.Role, want.Content)
+		}
+		if !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("message[%d] timestamp = %v, want %v", i, got.Timestamp, want.Timestamp)
+		}
+	}
+}
+
+func TestImportMarkdown_noMessages(t *testing.T) {
+	_, err := ImportMarkdown(strings.NewReader("# Заголовок\n\nпросто текст\n"), &config.Config{})
+	if !errors.Is(err, agenterrors.ErrEmptyImport) {
+		t.Errorf("ImportMarkdown() error = %v, want ErrEmptyImport", err)
+	}
+}
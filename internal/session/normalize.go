@@ -0,0 +1,54 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"fmt"
+)
+
+// normalizeRoles обрабатывает подряд идущие сообщения одной роли (например,
+// два ответа ассистента после сбоя во время /retry). В режиме merge они
+// объединяются, в режиме flag остаются как есть, но выводится предупреждение.
+func (c *ChatSession) normalizeRoles() {
+	runs := countRoleRuns(c.Messages)
+	if runs == 0 {
+		return
+	}
+
+	if c.Cfg != nil && c.Cfg.RoleRuns == config.RoleRunsMerge {
+		c.Messages = mergeRoleRuns(c.Messages)
+		fmt.Printf("🩹 Объединено %d повторяющихся сообщений одной роли\n", runs)
+		return
+	}
+
+	fmt.Printf("⚠️  В сессии %d раз(а) подряд идут сообщения одной роли (CONSECUTIVE_ROLES=merge объединит их)\n", runs)
+}
+
+// countRoleRuns возвращает количество сообщений, роль которых совпадает
+// с ролью предыдущего.
+func countRoleRuns(messages []model.Message) int {
+	runs := 0
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Role == messages[i-1].Role {
+			runs++
+		}
+	}
+	return runs
+}
+
+// mergeRoleRuns склеивает подряд идущие сообщения одной роли через пустую
+// строку. Время и отметка об обрезке берутся из последнего сообщения группы.
+func mergeRoleRuns(messages []model.Message) []model.Message {
+	merged := make([]model.Message, 0, len(messages))
+	for _, msg := range messages {
+		if n := len(merged); n > 0 && merged[n-1].Role == msg.Role {
+			last := &merged[n-1]
+			last.Content += "\n\n" + msg.Content
+			last.Timestamp = msg.Timestamp
+			last.Truncated = msg.Truncated
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	return merged
+}
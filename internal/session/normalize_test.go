@@ -0,0 +1,86 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const doubledAssistantSession = `{
+ "username": "crash",
+ "messages": [
+  {"role": "user", "content": "Вопрос", "timestamp": "2025-12-15T17:32:05Z"},
+  {"role": "assistant", "content": "Ответ 1", "timestamp": "2025-12-15T17:32:10Z"},
+  {"role": "assistant", "content": "Ответ 2", "timestamp": "2025-12-15T17:32:20Z"},
+  {"role": "user", "content": "Ещё вопрос", "timestamp": "2025-12-15T17:33:00Z"}
+ ]
+}`
+
+func TestNewChatSession_ConsecutiveRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantRoles   []string
+		wantContent []string
+	}{
+		{
+			name:        "flag keeps messages",
+			mode:        config.RoleRunsFlag,
+			wantRoles:   []string{model.RoleUser, model.RoleAssistant, model.RoleAssistant, model.RoleUser},
+			wantContent: []string{"Вопрос", "Ответ 1", "Ответ 2", "Ещё вопрос"},
+		},
+		{
+			name:        "merge joins doubled assistant messages",
+			mode:        config.RoleRunsMerge,
+			wantRoles:   []string{model.RoleUser, model.RoleAssistant, model.RoleUser},
+			wantContent: []string{"Вопрос", "Ответ 1\n\nОтвет 2", "Ещё вопрос"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", RoleRuns: tt.mode}
+			path := filepath.Join(cfg.CtxDir, "crash.json")
+			if err := os.WriteFile(path, []byte(doubledAssistantSession), 0644); err != nil {
+				t.Fatalf("writing session: %v", err)
+			}
+
+			session, err := NewChatSession("crash", cfg)
+			if err != nil {
+				t.Fatalf("NewChatSession() error = %v", err)
+			}
+
+			if len(session.Messages) != len(tt.wantRoles) {
+				t.Fatalf("messages = %d, want %d", len(session.Messages), len(tt.wantRoles))
+			}
+			for i := range tt.wantRoles {
+				if session.Messages[i].Role != tt.wantRoles[i] || session.Messages[i].Content != tt.wantContent[i] {
+					t.Errorf("message[%d] = {%s %q}, want {%s %q}", i,
+						session.Messages[i].Role, session.Messages[i].Content, tt.wantRoles[i], tt.wantContent[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergeRoleRuns_keepsLastTimestamp(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", RoleRuns: config.RoleRunsMerge}
+	path := filepath.Join(cfg.CtxDir, "crash.json")
+	if err := os.WriteFile(path, []byte(doubledAssistantSession), 0644); err != nil {
+		t.Fatalf("writing session: %v", err)
+	}
+
+	session, err := NewChatSession("crash", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+
+	if got := session.Messages[1].Timestamp.Format("15:04:05"); got != "17:32:20" {
+		t.Errorf("merged timestamp = %s, want the last message time 17:32:20", got)
+	}
+	if countRoleRuns(session.Messages) != 0 {
+		t.Error("no consecutive roles should remain after merge")
+	}
+}
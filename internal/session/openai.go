@@ -0,0 +1,54 @@
+package session
+
+import (
+	"agent/internal/model"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// openAIMessage — сообщение в формате Chat Completions API OpenAI.
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ExportOpenAIMessages записывает сессию в w JSON-массивом сообщений
+// OpenAI: [{"role": "...", "content": "..."}]. Системный промпт сессии
+// (или из конфигурации) идёт первым сообщением с ролью system.
+func ExportOpenAIMessages(session *ChatSession, w io.Writer) error {
+	messages := make([]openAIMessage, 0, len(session.Messages)+1)
+	if prompt := session.systemPrompt(); prompt != "" {
+		messages = append(messages, openAIMessage{Role: model.RoleSystem, Content: prompt})
+	}
+	for _, msg := range session.Messages {
+		messages = append(messages, openAIMessage{Role: openAIRole(msg.Role), Content: msg.Content})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(messages); err != nil {
+		return fmt.Errorf("экспорт в формат OpenAI: %w", err)
+	}
+	return nil
+}
+
+// openAIRole сопоставляет роль сообщения с ролью OpenAI. Всё, что не
+// вопрос пользователя и не системное сообщение, считается ответом ассистента.
+func openAIRole(role string) string {
+	switch role {
+	case model.RoleUser, model.RoleSystem:
+		return role
+	default:
+		return model.RoleAssistant
+	}
+}
+
+// systemPrompt возвращает системный промпт сессии, а если он не задан —
+// глобальный из конфигурации.
+func (c *ChatSession) systemPrompt() string {
+	if c.SystemPrompt != "" || c.Cfg == nil {
+		return c.SystemPrompt
+	}
+	return c.Cfg.SystemPrompt
+}
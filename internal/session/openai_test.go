@@ -0,0 +1,85 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/model"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExportOpenAIMessages(t *testing.T) {
+	now := time.Now()
+	session := &ChatSession{
+		UserName:     "testuser",
+		SystemPrompt: "Отвечай кратко.",
+		Cfg:          &config.Config{SystemPrompt: "Глобальный промпт"},
+		Messages: []model.Message{
+			{Role: model.RoleUser, Content: "Привет", Timestamp: now},
+			{Role: model.RoleAssistant, Content: "Здравствуйте!", Timestamp: now},
+			{Role: "system", Content: "Напоминание", Timestamp: now},
+			{Role: model.RoleUser, Content: "Как дела?", Timestamp: now},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportOpenAIMessages(session, &buf); err != nil {
+		t.Fatalf("ExportOpenAIMessages() error = %v", err)
+	}
+
+	var got []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array of messages: %v\n%s", err, buf.String())
+	}
+
+	want := []map[string]string{
+		{"role": "system", "content": "Отвечай кратко."},
+		{"role": "user", "content": "Привет"},
+		{"role": "assistant", "content": "Здравствуйте!"},
+		{"role": "system", "content": "Напоминание"},
+		{"role": "user", "content": "Как дела?"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("messages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if len(got[i]) != 2 || got[i]["role"] != want[i]["role"] || got[i]["content"] != want[i]["content"] {
+			t.Errorf("message %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExportOpenAIMessages_systemPromptFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		session *ChatSession
+		want    string
+	}{
+		{"config prompt", &ChatSession{Cfg: &config.Config{SystemPrompt: "Глобальный промпт"}}, "Глобальный промпт"},
+		{"no prompt", &ChatSession{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ExportOpenAIMessages(tt.session, &buf); err != nil {
+				t.Fatalf("ExportOpenAIMessages() error = %v", err)
+			}
+
+			var got []map[string]string
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("messages = %v, want an empty array", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0]["role"] != "system" || got[0]["content"] != tt.want {
+				t.Errorf("messages = %v, want a single system message %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,19 +5,34 @@ import (
 	"agent/internal/errors"
 	"agent/internal/model"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 type ChatSession struct {
-	UserName string          `json:"username"`
-	Messages []model.Message `json:"messages"`
-	Created  time.Time       `json:"created"`
-	Updated  time.Time       `json:"updated"`
-	Cfg      *config.Config  `json:"-"`
+	UserName     string          `json:"username"`
+	Title        string          `json:"title,omitempty"`
+	Category     string          `json:"category,omitempty"`
+	Messages     []model.Message `json:"messages"`
+	Created      time.Time       `json:"created"`
+	Updated      time.Time       `json:"updated"`
+	SystemPrompt string          `json:"system_prompt,omitempty"` // переопределяет SYSTEM_PROMPT для этого чата
+	Notes        string          `json:"notes,omitempty"`         // заметки пользователя, модели не отправляются
+	Cfg          *config.Config  `json:"-"`
+
+	filePath     string    // явный путь файла сессии (--file), иначе вычисляется по имени
+	modTime      time.Time // mtime файла при загрузке или последнем сохранении
+	store        Store     // куда сохраняется сессия, nil — FileStore
+	isNew        bool      // сессия ещё не сохранялась: перед первой записью проверяется MAX_SESSIONS
+	confirmQuota func(oldest []SessionInfo) bool
+	notifyTrim   func(removed int)
 }
 
 func NewChatSession(userName string, cfg *config.Config) (*ChatSession, error) {
@@ -25,41 +40,424 @@ func NewChatSession(userName string, cfg *config.Config) (*ChatSession, error) {
 		return nil, fmt.Errorf("создание директории чатов: %w", err)
 	}
 
-	return loadOrCreateSession(userName, cfg)
+	return NewChatSessionWithStore(userName, cfg, NewFileStore(cfg))
 }
 
+// NewChatSessionWithStore загружает сессию пользователя из store или создаёт
+// новую; последующие сохранения идут в тот же store.
+func NewChatSessionWithStore(userName string, cfg *config.Config, store Store) (*ChatSession, error) {
+	return loadOrCreateSession(norm.NFC.String(userName), cfg, store)
+}
+
+// OpenSessionFile загружает сессию из произвольного JSON-файла. Имя
+// пользователя берётся из файла, а при его отсутствии — из имени файла.
+// Последующие сохранения записываются по тому же пути.
+func OpenSessionFile(filePath string, cfg *config.Config) (*ChatSession, error) {
+	session, err := loadSessionFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserName == "" {
+		session.UserName = strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	}
+	if session.Messages == nil {
+		session.Messages = make([]model.Message, 0)
+	}
+
+	session.Cfg = cfg
+	session.filePath = filePath
+	session.store = NewFileStore(cfg)
+	session.rememberModTime(filePath)
+	session.normalizeRoles()
+	return session, nil
+}
+
+// FilePath возвращает путь, по которому сохраняется сессия.
+func (c *ChatSession) FilePath() string {
+	if c.filePath != "" {
+		return c.filePath
+	}
+	return getSessionFilePath(c.UserName, c.Cfg)
+}
+
+// SaveSession записывает сессию в её хранилище (по умолчанию — в файл, см.
+// FileStore.Save).
 func (c *ChatSession) SaveSession(session *ChatSession) error {
-	filePath := getSessionFilePath(session.UserName, c.Cfg)
+	return c.save(session, false)
+}
 
-	data, err := json.MarshalIndent(session, "", " ")
+// AutoSave сохраняет сессию во время разговора. При COMPACT_AUTOSAVE JSON
+// пишется без отступов: на длинных сессиях это заметно быстрее и меньше по
+// размеру, а явные сохранения по-прежнему форматируются для чтения.
+func (c *ChatSession) AutoSave() error {
+	return c.save(c, c.Cfg.CompactAutosave)
+}
+
+func (c *ChatSession) save(session *ChatSession, compact bool) error {
+	if session.isNew {
+		if err := session.enforceQuota(); err != nil {
+			return err
+		}
+	}
+
+	data, err := marshalSession(session, compact)
 	if err != nil {
 		return fmt.Errorf("%w: ошибка сериализации: %v", errors.ErrFileSave, err)
 	}
+	data, removed, err := session.fitSizeLimit(data, compact)
+	if err != nil {
+		return err
+	}
+	if err := session.storage().Save(session, data); err != nil {
+		return err
+	}
+	session.isNew = false
+	if removed > 0 && session.notifyTrim != nil {
+		session.notifyTrim(removed)
+	}
+	return nil
+}
+
+// SetQuotaConfirm задаёт, как спросить разрешение удалить самые старые
+// сессии, если первое сохранение новой сессии превысит MAX_SESSIONS при
+// SESSION_QUOTA_POLICY=delete_oldest. Без подтверждения сохранение
+// отклоняется с ErrSessionQuota.
+func (c *ChatSession) SetQuotaConfirm(confirm func(oldest []SessionInfo) bool) {
+	c.confirmQuota = confirm
+}
+
+// SetTrimNotice задаёт, как сообщить об удалённых старых сообщениях, если
+// сессия превысила MAX_SESSION_BYTES при SESSION_SIZE_POLICY=trim.
+func (c *ChatSession) SetTrimNotice(notify func(removed int)) {
+	c.notifyTrim = notify
+}
+
+// storage возвращает хранилище сессии. Сессии, собранные без загрузки
+// (например, при импорте), сохраняются в файлы.
+func (c *ChatSession) storage() Store {
+	if c.store == nil {
+		return NewFileStore(c.Cfg)
+	}
+	return c.store
+}
+
+// fitSizeLimit следит за MAX_SESSION_BYTES. Если сериализованная сессия
+// больше лимита, в режиме trim удаляются самые старые сообщения, кроме
+// системных, пока она не поместится; в режиме refuse сохранение
+// отклоняется. Возвращает данные и число удалённых сообщений.
+func (c *ChatSession) fitSizeLimit(data []byte, compact bool) ([]byte, int, error) {
+	limit := c.Cfg.MaxSessionBytes
+	if limit <= 0 || int64(len(data)) <= limit {
+		return data, 0, nil
+	}
+	if c.Cfg.SessionSizePolicy == config.SizeRefuse {
+		return nil, 0, fmt.Errorf("%w: %d байт при лимите %d", errors.ErrSessionSize, len(data), limit)
+	}
+
+	removed := 0
+	for int64(len(data)) > limit {
+		i := slices.IndexFunc(c.Messages, func(msg model.Message) bool { return !msg.IsSystem() })
+		if i < 0 {
+			break
+		}
+		// Новый срез, чтобы не менять массив, который может разделять вызывающий
+		c.Messages = append(c.Messages[:i:i], c.Messages[i+1:]...)
+		removed++
+
+		var err error
+		if data, err = marshalSession(c, compact); err != nil {
+			return nil, 0, fmt.Errorf("%w: ошибка сериализации: %v", errors.ErrFileSave, err)
+		}
+	}
+	if int64(len(data)) > limit {
+		return nil, 0, fmt.Errorf("%w: %d байт без сообщений, кроме системных, при лимите %d", errors.ErrSessionSize, len(data), limit)
+	}
+	return data, removed, nil
+}
+
+// writeFileAtomic пишет данные во временный файл рядом с path и заменяет им
+// path. Прерванная запись оставляет прежний файл целым.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func marshalSession(session *ChatSession, compact bool) ([]byte, error) {
+	if compact {
+		return json.Marshal(session)
+	}
+	return json.MarshalIndent(session, "", " ")
+}
+
+// changedOnDisk сообщает, изменился ли файл после загрузки или последнего
+// сохранения этой сессии.
+func (c *ChatSession) changedOnDisk(filePath string) bool {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(c.modTime)
+}
+
+func (c *ChatSession) rememberModTime(filePath string) {
+	if info, err := os.Stat(filePath); err == nil {
+		c.modTime = info.ModTime()
+	}
+}
+
+// conflictMarker отличает копии сессии, сохранённые при конфликте записи.
+const conflictMarker = ".conflict-"
+
+// conflictPath возвращает путь вида name.conflict-20060102-150405.json.
+func conflictPath(filePath string) string {
+	ext := filepath.Ext(filePath)
+	return fmt.Sprintf("%s"+conflictMarker+"%s%s", strings.TrimSuffix(filePath, ext), time.Now().Format("20060102-150405"), ext)
+}
+
+// PruneBefore удаляет сообщения, отправленные раньше cutoff, и возвращает
+// количество удалённых.
+func (c *ChatSession) PruneBefore(cutoff time.Time) int {
+	kept := c.Messages[:0]
+	for _, msg := range c.Messages {
+		if !msg.Timestamp.Before(cutoff) {
+			kept = append(kept, msg)
+		}
+	}
+
+	removed := len(c.Messages) - len(kept)
+	c.Messages = kept
+	if removed > 0 {
+		c.Updated = time.Now()
+	}
+	return removed
+}
+
+// DeleteMessage удаляет сообщение с индексом i (с нуля); следующие
+// сообщения сдвигаются. При pair удаляется и парное сообщение — ответ на
+// этот вопрос или вопрос, на который дан этот ответ, — чтобы не нарушить
+// чередование ролей. Возвращает количество удалённых сообщений.
+func (c *ChatSession) DeleteMessage(i int, pair bool) int {
+	if i < 0 || i >= len(c.Messages) {
+		return 0
+	}
+
+	from, to := i, i+1
+	if pair {
+		if c.Messages[i].IsUser() && to < len(c.Messages) && c.Messages[to].Role == model.RoleAssistant {
+			to++
+		} else if c.Messages[i].Role == model.RoleAssistant && from > 0 && c.Messages[from-1].IsUser() {
+			from--
+		}
+	}
+
+	c.Messages = append(c.Messages[:from], c.Messages[to:]...)
+	c.Updated = time.Now()
+	return to - from
+}
+
+// RestoreBackup заменяет файл сессии пользователя резервной копией с номером n
+// (1 — самая свежая).
+func RestoreBackup(userName string, n int, cfg *config.Config) error {
+	filePath := getSessionFilePath(userName, cfg)
+	backup := backupPath(filePath, n)
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("%w: ошибка записи: %v", errors.ErrFileSave, err)
+	if _, err := os.Stat(backup); err != nil {
+		return fmt.Errorf("%w: %s", errors.ErrBackupNotFound, backup)
 	}
 
+	if err := copyFile(backup, filePath); err != nil {
+		return fmt.Errorf("%w: %v", errors.ErrFileSave, err)
+	}
 	return nil
 }
 
-func ensureChatsDir(cfg *config.Config) error {
-	return os.MkdirAll(cfg.CtxDir, os.ModePerm)
+// SessionInfo описывает сохранённую сессию в директории чатов.
+type SessionInfo struct {
+	Name     string
+	Path     string
+	ModTime  time.Time
+	Size     int64  // размер файла в байтах
+	Category string // категория из файла сессии, пусто — без категории
+}
+
+// ListSessions возвращает сессии из CTX_DIR, начиная с недавно изменённых.
+// Резервные копии и архивы в список не попадают.
+func ListSessions(cfg *config.Config) ([]SessionInfo, error) {
+	return NewFileStore(cfg).List()
+}
+
+// readCategory читает категорию сессии, не загружая остальные поля.
+// Нечитаемые файлы считаются сессиями без категории.
+func readCategory(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return categoryOf(data)
+}
+
+// categoryOf извлекает категорию из сериализованной сессии.
+func categoryOf(data []byte) string {
+	var header struct {
+		Category string `json:"category"`
+	}
+	if json.Unmarshal(data, &header) != nil {
+		return ""
+	}
+	return header.Category
+}
+
+// FilterByCategory оставляет сессии указанной категории (без учёта регистра).
+// Пустая категория возвращает список без изменений.
+func FilterByCategory(sessions []SessionInfo, category string) []SessionInfo {
+	if category == "" {
+		return sessions
+	}
+
+	var filtered []SessionInfo
+	for _, info := range sessions {
+		if strings.EqualFold(info.Category, category) {
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered
+}
+
+// enforceQuota проверяет перед первым сохранением новой сессии, что она
+// не превысит MAX_SESSIONS. В режиме delete_oldest после подтверждения
+// освобождает место, удаляя самые старые сессии вместе с их резервными
+// копиями; иначе возвращает ErrSessionQuota.
+func (c *ChatSession) enforceQuota() error {
+	cfg := c.Cfg
+	if cfg.MaxSessions <= 0 {
+		return nil
+	}
+
+	store := c.storage()
+	sessions, err := store.List()
+	if err != nil {
+		return err
+	}
+	excess := len(sessions) - cfg.MaxSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	oldest := sessions[len(sessions)-excess:]
+	if cfg.QuotaPolicy != config.QuotaDeleteOldest || c.confirmQuota == nil || !c.confirmQuota(oldest) {
+		return fmt.Errorf("%w: %d из %d, удалите старые чаты или увеличьте MAX_SESSIONS",
+			errors.ErrSessionQuota, len(sessions), cfg.MaxSessions)
+	}
+
+	for _, old := range oldest {
+		if err := store.Delete(old.Name); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func loadOrCreateSession(userName string, cfg *config.Config) (*ChatSession, error) {
+// SessionExists сообщает, есть ли уже сохранённая сессия пользователя.
+func SessionExists(userName string, cfg *config.Config) bool {
+	_, err := os.Stat(getSessionFilePath(userName, cfg))
+	return err == nil
+}
+
+// ArchiveSession переименовывает файл сессии в name.json.archive.<время>,
+// чтобы следующий запуск начал чат с чистого листа. Возвращает путь архива.
+func ArchiveSession(userName string, cfg *config.Config) (string, error) {
 	filePath := getSessionFilePath(userName, cfg)
+	archive := fmt.Sprintf("%s.archive.%s", filePath, time.Now().Format("20060102-150405"))
 
+	if err := os.Rename(filePath, archive); err != nil {
+		return "", fmt.Errorf("%w: архивирование: %v", errors.ErrFileSave, err)
+	}
+	return archive, nil
+}
+
+// rotateBackups сдвигает name.bak.1 → name.bak.2 … и копирует текущий файл
+// в name.bak.1, храня не более count копий.
+func rotateBackups(filePath string, count int) error {
+	if count <= 0 {
+		return nil
+	}
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.Remove(backupPath(filePath, count)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := count - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(filePath, i), backupPath(filePath, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return copyFile(filePath, backupPath(filePath, 1))
+}
+
+func backupPath(filePath string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", filePath, n)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+func ensureChatsDir(cfg *config.Config) error {
+	return os.MkdirAll(cfg.CtxDir, os.ModePerm)
+}
+
+func loadOrCreateSession(userName string, cfg *config.Config, store Store) (*ChatSession, error) {
+	session, err := store.Load(userName)
+	if stderrors.Is(err, errors.ErrNoSession) {
 		return &ChatSession{
 			UserName: userName,
+			Category: cfg.SessionCategory,
 			Messages: make([]model.Message, 0),
 			Created:  time.Now(),
 			Updated:  time.Now(),
 			Cfg:      cfg,
+			store:    store,
+			isNew:    true,
 		}, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
+	session.Cfg = cfg
+	session.store = store
+	session.normalizeRoles()
+	return session, nil
+}
+
+func loadSessionFile(filePath string) (*ChatSession, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
@@ -70,7 +468,6 @@ func loadOrCreateSession(userName string, cfg *config.Config) (*ChatSession, err
 		return nil, fmt.Errorf("%w: %v", errors.ErrFileParse, err)
 	}
 
-	session.Cfg = cfg
 	return &session, nil
 }
 
@@ -79,8 +476,12 @@ func getSessionFilePath(userName string, cfg *config.Config) string {
 	return filepath.Join(cfg.CtxDir, fmt.Sprintf("%s%s", safeUserName, cfg.CtxFileExt))
 }
 
+// sanitizeUserName приводит имя к NFC, чтобы канонически равные имена
+// («é» и «e» + комбинируемый акцент) указывали на один файл, и заменяет
+// недопустимые в путях символы.
 func sanitizeUserName(userName string) string {
-	safeUserName := strings.ReplaceAll(userName, " ", "_")
+	safeUserName := norm.NFC.String(userName)
+	safeUserName = strings.ReplaceAll(safeUserName, " ", "_")
 	safeUserName = strings.ReplaceAll(safeUserName, "/", "_")
 	safeUserName = strings.ReplaceAll(safeUserName, "\\", "_")
 	safeUserName = strings.ReplaceAll(safeUserName, ":", "_")
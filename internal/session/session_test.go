@@ -2,9 +2,14 @@ package session
 
 import (
 	"agent/internal/config"
+	agenterrors "agent/internal/errors"
 	"agent/internal/model"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -185,3 +190,689 @@ func TestEnsureChatsDir(t *testing.T) {
 		t.Error("ensureChatsDir() did not create directory")
 	}
 }
+
+func TestChatSession_SaveRotatesBackups(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := &config.Config{
+		CtxDir:      tempDir,
+		CtxFileExt:  ".json",
+		BackupCount: 2,
+	}
+
+	session, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+
+	for i := 1; i <= 4; i++ {
+		session.Messages = append(session.Messages, model.Message{
+			Role: model.RoleUser, Content: fmt.Sprintf("msg %d", i), Timestamp: time.Now(),
+		})
+		if err := session.SaveSession(session); err != nil {
+			t.Fatalf("SaveSession() #%d error = %v", i, err)
+		}
+	}
+
+	filePath := getSessionFilePath("testuser", cfg)
+	for n := 1; n <= 2; n++ {
+		if _, err := os.Stat(backupPath(filePath, n)); err != nil {
+			t.Errorf("backup #%d should exist: %v", n, err)
+		}
+	}
+	if _, err := os.Stat(backupPath(filePath, 3)); !os.IsNotExist(err) {
+		t.Error("backup #3 should not exist when BackupCount = 2")
+	}
+
+	// bak.1 — состояние перед последним сохранением (3 сообщения)
+	backup, err := loadSessionFile(backupPath(filePath, 1))
+	if err != nil {
+		t.Fatalf("reading backup #1: %v", err)
+	}
+	if len(backup.Messages) != 3 {
+		t.Errorf("backup #1 messages = %d, want 3", len(backup.Messages))
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := &config.Config{
+		CtxDir:      tempDir,
+		CtxFileExt:  ".json",
+		BackupCount: 3,
+	}
+
+	session, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+
+	session.Messages = []model.Message{{Role: model.RoleUser, Content: "original", Timestamp: time.Now()}}
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	session.Messages = []model.Message{{Role: model.RoleUser, Content: "corrupted", Timestamp: time.Now()}}
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	if err := RestoreBackup("testuser", 1, cfg); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+
+	restored, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("loading restored session: %v", err)
+	}
+	if len(restored.Messages) != 1 || restored.Messages[0].Content != "original" {
+		t.Errorf("restored messages = %+v, want single \"original\" message", restored.Messages)
+	}
+
+	if err := RestoreBackup("testuser", 5, cfg); !errors.Is(err, agenterrors.ErrBackupNotFound) {
+		t.Errorf("RestoreBackup() missing backup error = %v, want ErrBackupNotFound", err)
+	}
+}
+
+func TestOpenSessionFile_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "exported", "my chat.json")
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		t.Fatalf("creating dir: %v", err)
+	}
+
+	data := `{"messages":[{"role":"user","content":"Hello","timestamp":"2025-12-15T17:32:05Z"}]}`
+	if err := os.WriteFile(filePath, []byte(data), 0644); err != nil {
+		t.Fatalf("writing session file: %v", err)
+	}
+
+	cfg := &config.Config{CtxDir: filepath.Join(dir, "chats"), CtxFileExt: ".json"}
+
+	session, err := OpenSessionFile(filePath, cfg)
+	if err != nil {
+		t.Fatalf("OpenSessionFile() error = %v", err)
+	}
+
+	if session.UserName != "my chat" {
+		t.Errorf("UserName = %q, want name derived from file %q", session.UserName, "my chat")
+	}
+	if session.FilePath() != filePath {
+		t.Errorf("FilePath() = %q, want %q", session.FilePath(), filePath)
+	}
+
+	session.Messages = append(session.Messages, model.Message{
+		Role: model.RoleAssistant, Content: "Hi!", Timestamp: time.Now(),
+	})
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	if _, err := os.Stat(getSessionFilePath("my chat", cfg)); !os.IsNotExist(err) {
+		t.Error("SaveSession() should not write to the name-based path")
+	}
+
+	reloaded, err := OpenSessionFile(filePath, cfg)
+	if err != nil {
+		t.Fatalf("reopening session: %v", err)
+	}
+	if len(reloaded.Messages) != 2 || reloaded.Messages[1].Content != "Hi!" {
+		t.Errorf("reloaded messages = %+v, want 2 messages ending with \"Hi!\"", reloaded.Messages)
+	}
+	if reloaded.UserName != "my chat" {
+		t.Errorf("reloaded UserName = %q, want %q", reloaded.UserName, "my chat")
+	}
+}
+
+func TestNewChatSession_UnicodeNormalization(t *testing.T) {
+	tempDir := t.TempDir()
+
+	cfg := &config.Config{
+		CtxDir:     tempDir,
+		CtxFileExt: ".json",
+	}
+
+	composed := "José"    // «é» одним символом
+	decomposed := "José" // «e» + комбинируемый акцент
+
+	if composed == decomposed {
+		t.Fatal("test names must differ byte-wise")
+	}
+
+	if getSessionFilePath(composed, cfg) != getSessionFilePath(decomposed, cfg) {
+		t.Errorf("paths differ: %q vs %q",
+			getSessionFilePath(composed, cfg), getSessionFilePath(decomposed, cfg))
+	}
+
+	first, err := NewChatSession(composed, cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	first.Messages = []model.Message{{Role: model.RoleUser, Content: "Hola", Timestamp: time.Now()}}
+	if err := first.SaveSession(first); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	second, err := NewChatSession(decomposed, cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	if len(second.Messages) != 1 {
+		t.Errorf("decomposed name should resume the same session, got %d messages", len(second.Messages))
+	}
+	if second.UserName != composed {
+		t.Errorf("UserName = %q, want NFC form %q", second.UserName, composed)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected a single session file, got %d", len(entries))
+	}
+}
+
+func TestChatSession_SystemPromptPersistence(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	session, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+
+	session.SystemPrompt = "Отвечай стихами"
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	loaded, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("loading session: %v", err)
+	}
+	if loaded.SystemPrompt != "Отвечай стихами" {
+		t.Errorf("loaded.SystemPrompt = %q, want %q", loaded.SystemPrompt, "Отвечай стихами")
+	}
+}
+
+func TestArchiveSession(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	if SessionExists("testuser", cfg) {
+		t.Fatal("SessionExists() = true before the session was saved")
+	}
+
+	session, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	session.Messages = []model.Message{{Role: model.RoleUser, Content: "old chat", Timestamp: time.Now()}}
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	if !SessionExists("testuser", cfg) {
+		t.Fatal("SessionExists() = false after save")
+	}
+
+	archive, err := ArchiveSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("ArchiveSession() error = %v", err)
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Errorf("archive file %s is missing: %v", archive, err)
+	}
+
+	fresh, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() after archive error = %v", err)
+	}
+	if len(fresh.Messages) != 0 {
+		t.Errorf("messages after archive = %d, want a fresh session", len(fresh.Messages))
+	}
+}
+
+func TestChatSession_SaveDetectsExternalModification(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", BackupCount: 3}
+
+	first, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession() error = %v", err)
+	}
+	first.Messages = []model.Message{{Role: model.RoleUser, Content: "original", Timestamp: time.Now()}}
+	if err := first.SaveSession(first); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	loaded, err := NewChatSession("testuser", cfg)
+	if err != nil {
+		t.Fatalf("loading session: %v", err)
+	}
+
+	// Другой процесс переписал файл после загрузки
+	path := loaded.FilePath()
+	external := []byte(`{"username": "testuser", "messages": [{"role": "user", "content": "external"}]}`)
+	if err := os.WriteFile(path, external, 0644); err != nil {
+		t.Fatalf("external write: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+
+	loaded.Messages = append(loaded.Messages, model.Message{Role: model.RoleAssistant, Content: "mine", Timestamp: time.Now()})
+	if err := loaded.SaveSession(loaded); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != string(external) {
+		t.Error("externally modified file must not be overwritten")
+	}
+
+	conflict := loaded.FilePath()
+	if conflict == path || !strings.Contains(filepath.Base(conflict), ".conflict-") || filepath.Ext(conflict) != ".json" {
+		t.Fatalf("FilePath() after conflict = %q, want a .conflict-*.json file", conflict)
+	}
+	saved, err := OpenSessionFile(conflict, cfg)
+	if err != nil {
+		t.Fatalf("reading conflict file: %v", err)
+	}
+	if len(saved.Messages) != 2 || saved.Messages[1].Content != "mine" {
+		t.Errorf("conflict file messages = %+v, want local changes", saved.Messages)
+	}
+
+	// Следующее сохранение продолжает писать в конфликтный файл без нового конфликта
+	if err := loaded.SaveSession(loaded); err != nil {
+		t.Fatalf("second SaveSession() error = %v", err)
+	}
+	if loaded.FilePath() != conflict {
+		t.Errorf("FilePath() = %q, want %q", loaded.FilePath(), conflict)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", BackupCount: 2}
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"alice", "bob", "carol"} {
+		session, err := NewChatSession(name, cfg)
+		if err != nil {
+			t.Fatalf("NewChatSession(%q) error = %v", name, err)
+		}
+		session.Messages = []model.Message{{Role: model.RoleUser, Content: "hi", Timestamp: time.Now()}}
+		// Второе сохранение создаёт резервную копию, которая не должна попасть в список
+		for range 2 {
+			if err := session.SaveSession(session); err != nil {
+				t.Fatalf("SaveSession() error = %v", err)
+			}
+		}
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(session.FilePath(), modTime, modTime); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(cfg.CtxDir, "nested.json"), 0755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	sessions, err := ListSessions(cfg)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	var names []string
+	for _, s := range sessions {
+		names = append(names, s.Name)
+	}
+	if got, want := strings.Join(names, ","), "carol,bob,alice"; got != want {
+		t.Errorf("ListSessions() names = %s, want %s (most recent first)", got, want)
+	}
+
+	missing := &config.Config{CtxDir: filepath.Join(cfg.CtxDir, "missing"), CtxFileExt: ".json"}
+	if sessions, err := ListSessions(missing); err != nil || len(sessions) != 0 {
+		t.Errorf("ListSessions() on missing dir = %v, %v; want empty, nil", sessions, err)
+	}
+}
+
+func TestNewChatSession_Quota(t *testing.T) {
+	setup := func(t *testing.T, policy string) *config.Config {
+		cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", BackupCount: 1, MaxSessions: 3, QuotaPolicy: policy}
+
+		base := time.Now().Add(-time.Hour)
+		for i, name := range []string{"oldest", "middle", "newest"} {
+			session, err := NewChatSession(name, cfg)
+			if err != nil {
+				t.Fatalf("NewChatSession(%q) error = %v", name, err)
+			}
+			session.Messages = []model.Message{{Role: model.RoleUser, Content: name, Timestamp: time.Now()}}
+			for range 2 {
+				if err := session.SaveSession(session); err != nil {
+					t.Fatalf("SaveSession() error = %v", err)
+				}
+			}
+			modTime := base.Add(time.Duration(i) * time.Minute)
+			if err := os.Chtimes(session.FilePath(), modTime, modTime); err != nil {
+				t.Fatalf("Chtimes() error = %v", err)
+			}
+		}
+		return cfg
+	}
+
+	// saveFresh открывает новую сессию и сохраняет её в первый раз
+	saveFresh := func(t *testing.T, cfg *config.Config, confirm func([]SessionInfo) bool) error {
+		session, err := NewChatSession("fresh", cfg)
+		if err != nil {
+			t.Fatalf("NewChatSession() error = %v", err)
+		}
+		if confirm != nil {
+			session.SetQuotaConfirm(confirm)
+		}
+		session.Messages = []model.Message{{Role: model.RoleUser, Content: "fresh", Timestamp: time.Now()}}
+		return session.SaveSession(session)
+	}
+
+	t.Run("refuse", func(t *testing.T) {
+		cfg := setup(t, config.QuotaRefuse)
+
+		if err := saveFresh(t, cfg, func([]SessionInfo) bool { return true }); !errors.Is(err, agenterrors.ErrSessionQuota) {
+			t.Errorf("SaveSession() error = %v, want ErrSessionQuota", err)
+		}
+		if SessionExists("fresh", cfg) || !SessionExists("oldest", cfg) {
+			t.Error("refused session must not be written and old sessions must be kept")
+		}
+		if _, err := NewChatSession("middle", cfg); err != nil {
+			t.Errorf("opening an existing session must not be limited, got %v", err)
+		}
+	})
+
+	t.Run("delete oldest confirmed", func(t *testing.T) {
+		cfg := setup(t, config.QuotaDeleteOldest)
+
+		// Открытие новой сессии ещё ничего не удаляет
+		if _, err := NewChatSession("fresh", cfg); err != nil || !SessionExists("oldest", cfg) {
+			t.Fatalf("NewChatSession() error = %v, oldest kept = %t", err, SessionExists("oldest", cfg))
+		}
+
+		var asked []string
+		err := saveFresh(t, cfg, func(oldest []SessionInfo) bool {
+			for _, info := range oldest {
+				asked = append(asked, info.Name)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("SaveSession() error = %v", err)
+		}
+		if len(asked) != 1 || asked[0] != "oldest" {
+			t.Errorf("confirmation asked for %v, want [oldest]", asked)
+		}
+		if SessionExists("oldest", cfg) {
+			t.Error("the oldest session should be deleted")
+		}
+		if _, err := os.Stat(backupPath(getSessionFilePath("oldest", cfg), 1)); !os.IsNotExist(err) {
+			t.Error("backups of the deleted session should be removed")
+		}
+		if !SessionExists("middle", cfg) || !SessionExists("newest", cfg) || !SessionExists("fresh", cfg) {
+			t.Error("newer sessions must be kept")
+		}
+	})
+
+	t.Run("delete oldest declined", func(t *testing.T) {
+		for name, confirm := range map[string]func([]SessionInfo) bool{
+			"declined":        func([]SessionInfo) bool { return false },
+			"no confirmation": nil,
+		} {
+			cfg := setup(t, config.QuotaDeleteOldest)
+
+			if err := saveFresh(t, cfg, confirm); !errors.Is(err, agenterrors.ErrSessionQuota) {
+				t.Errorf("%s: SaveSession() error = %v, want ErrSessionQuota", name, err)
+			}
+			if !SessionExists("oldest", cfg) || SessionExists("fresh", cfg) {
+				t.Errorf("%s: nothing should be deleted or written", name)
+			}
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		cfg := setup(t, config.QuotaRefuse)
+		cfg.MaxSessions = 0
+
+		if err := saveFresh(t, cfg, nil); err != nil {
+			t.Errorf("SaveSession() without quota error = %v", err)
+		}
+	})
+}
+
+func TestSession_Category(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", SessionCategory: "work"}
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		session, err := NewChatSession(name, cfg)
+		if err != nil {
+			t.Fatalf("NewChatSession(%q) error = %v", name, err)
+		}
+		if session.Category != "work" {
+			t.Errorf("new session Category = %q, want SESSION_CATEGORY", session.Category)
+		}
+		if name == "bob" {
+			session.Category = "home"
+		}
+		if name == "carol" {
+			session.Category = ""
+		}
+		if err := session.SaveSession(session); err != nil {
+			t.Fatalf("SaveSession() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.CtxDir, "carol.json"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if strings.Contains(string(data), "category") {
+		t.Errorf("empty category should be omitted from the file:\n%s", data)
+	}
+
+	reloaded, err := NewChatSession("bob", cfg)
+	if err != nil {
+		t.Fatalf("NewChatSession(bob) error = %v", err)
+	}
+	if reloaded.Category != "home" {
+		t.Errorf("persisted Category = %q, want home", reloaded.Category)
+	}
+
+	sessions, err := ListSessions(cfg)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{"work", []string{"alice"}},
+		{"HOME", []string{"bob"}},
+		{"", []string{"alice", "bob", "carol"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, info := range FilterByCategory(sessions, tt.category) {
+			names = append(names, info.Name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("FilterByCategory(%q) = %v, want %v", tt.category, names, tt.want)
+		}
+	}
+}
+
+func largeSession(n int) *ChatSession {
+	base := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	session := &ChatSession{UserName: "bench", Created: base, Updated: base}
+	for i := range n {
+		role := model.RoleUser
+		if i%2 == 1 {
+			role = model.RoleAssistant
+		}
+		session.Messages = append(session.Messages, model.Message{Role: role, Content: strings.Repeat("слово ", 50), Timestamp: base.Add(time.Duration(i) * time.Minute)})
+	}
+	return session
+}
+
+func TestSession_AutoSave_compactReloadsIdentically(t *testing.T) {
+	var loaded []*ChatSession
+	var sizes []int64
+
+	for _, compact := range []bool{false, true} {
+		cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", CompactAutosave: compact}
+		session := largeSession(20)
+		session.Cfg = cfg
+		session.Title = "Заголовок"
+
+		if err := session.AutoSave(); err != nil {
+			t.Fatalf("AutoSave(compact=%v) error = %v", compact, err)
+		}
+		info, err := os.Stat(session.FilePath())
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		sizes = append(sizes, info.Size())
+
+		reloaded, err := NewChatSession("bench", cfg)
+		if err != nil {
+			t.Fatalf("NewChatSession() error = %v", err)
+		}
+		loaded = append(loaded, reloaded)
+	}
+
+	pretty, compact := loaded[0], loaded[1]
+	if pretty.Title != compact.Title || len(pretty.Messages) != len(compact.Messages) {
+		t.Fatalf("reloaded sessions differ: %q/%d vs %q/%d", pretty.Title, len(pretty.Messages), compact.Title, len(compact.Messages))
+	}
+	for i := range pretty.Messages {
+		p, c := pretty.Messages[i], compact.Messages[i]
+		if p.Role != c.Role || p.Content != c.Content || !p.Timestamp.Equal(c.Timestamp) {
+			t.Errorf("message %d differs after reload: %+v vs %+v", i, p, c)
+		}
+	}
+	if sizes[1] >= sizes[0] {
+		t.Errorf("compact file is %d bytes, want smaller than indented %d", sizes[1], sizes[0])
+	}
+}
+
+func BenchmarkMarshalSession(b *testing.B) {
+	session := largeSession(2000)
+
+	for _, tt := range []struct {
+		name    string
+		compact bool
+	}{
+		{"indent", false},
+		{"compact", true},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			var size int
+			for b.Loop() {
+				data, err := marshalSession(session, tt.compact)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes/op-file")
+		})
+	}
+}
+
+func TestSession_SaveSession_maxSessionBytes(t *testing.T) {
+	const limit = 4096
+
+	t.Run("trim", func(t *testing.T) {
+		cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", MaxSessionBytes: limit, SessionSizePolicy: config.SizeTrim}
+		session := largeSession(40)
+		session.Cfg = cfg
+		session.Messages = append([]model.Message{{Role: model.RoleSystem, Content: "Отвечай кратко."}}, session.Messages...)
+		last := session.Messages[len(session.Messages)-1]
+		var removed int
+		session.SetTrimNotice(func(n int) { removed = n })
+
+		if err := session.SaveSession(session); err != nil {
+			t.Fatalf("SaveSession() error = %v", err)
+		}
+
+		info, err := os.Stat(session.FilePath())
+		if err != nil {
+			t.Fatalf("Stat() error = %v", err)
+		}
+		if info.Size() > limit {
+			t.Errorf("saved file is %d bytes, want at most %d", info.Size(), limit)
+		}
+		if len(session.Messages) == 0 || len(session.Messages) >= 40 {
+			t.Fatalf("len(Messages) = %d, want oldest messages trimmed", len(session.Messages))
+		}
+		if got := session.Messages[len(session.Messages)-1]; !got.Timestamp.Equal(last.Timestamp) {
+			t.Error("the newest message should be kept")
+		}
+		if !session.Messages[0].IsSystem() {
+			t.Error("system messages should not be trimmed")
+		}
+		if removed != 41-len(session.Messages) {
+			t.Errorf("trim notice reported %d removed messages, want %d", removed, 41-len(session.Messages))
+		}
+
+		reloaded, err := NewChatSession("bench", cfg)
+		if err != nil {
+			t.Fatalf("NewChatSession() error = %v", err)
+		}
+		if len(reloaded.Messages) != len(session.Messages) {
+			t.Errorf("reloaded %d messages, want %d", len(reloaded.Messages), len(session.Messages))
+		}
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json", MaxSessionBytes: limit, SessionSizePolicy: config.SizeRefuse}
+		session := largeSession(40)
+		session.Cfg = cfg
+
+		if err := session.SaveSession(session); !errors.Is(err, agenterrors.ErrSessionSize) {
+			t.Fatalf("SaveSession() error = %v, want ErrSessionSize", err)
+		}
+		if len(session.Messages) != 40 {
+			t.Errorf("refuse policy should not trim, got %d messages", len(session.Messages))
+		}
+		if _, err := os.Stat(session.FilePath()); !os.IsNotExist(err) {
+			t.Errorf("refused session should not be written, Stat() error = %v", err)
+		}
+	})
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "user.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("content = %q, want %q", data, "new")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("files in dir = %d, want 1 (temporary file should be renamed)", len(entries))
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
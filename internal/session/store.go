@@ -0,0 +1,202 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store — хранилище сессий. Сериализация, лимит размера и нормализация ролей
+// выполняются в ChatSession, хранилище только читает и пишет готовые данные.
+type Store interface {
+	// Load загружает сессию пользователя или возвращает ErrNoSession.
+	Load(userName string) (*ChatSession, error)
+	// Save записывает сериализованную сессию.
+	Save(session *ChatSession, data []byte) error
+	// List возвращает сохранённые сессии, начиная с недавно изменённых.
+	List() ([]SessionInfo, error)
+	// Delete удаляет сессию по имени из List.
+	Delete(name string) error
+}
+
+// FileStore хранит каждую сессию в JSON-файле в CTX_DIR — хранилище по
+// умолчанию. Перед записью ведёт резервные копии, а изменённый другим
+// процессом файл не перезаписывает.
+type FileStore struct {
+	cfg *config.Config
+}
+
+func NewFileStore(cfg *config.Config) *FileStore {
+	return &FileStore{cfg: cfg}
+}
+
+func (s *FileStore) Load(userName string) (*ChatSession, error) {
+	filePath := getSessionFilePath(userName, s.cfg)
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil, errors.ErrNoSession
+	}
+
+	session, err := loadSessionFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	session.rememberModTime(filePath)
+	return session, nil
+}
+
+// Save записывает сессию атомарно. Если файл успел изменить другой процесс
+// (mtime не совпадает с запомненным), сессия сохраняется в конфликтный файл
+// рядом, и дальнейшие сохранения идут туда же.
+func (s *FileStore) Save(session *ChatSession, data []byte) error {
+	filePath := session.FilePath()
+
+	if session.changedOnDisk(filePath) {
+		filePath = conflictPath(filePath)
+		fmt.Printf("⚠️  Файл сессии изменён другим процессом, сохраняем в %s\n", filePath)
+		session.filePath = filePath
+	} else if err := rotateBackups(filePath, s.cfg.BackupCount); err != nil {
+		return fmt.Errorf("%w: ошибка резервного копирования: %v", errors.ErrFileSave, err)
+	}
+
+	if err := writeFileAtomic(filePath, data); err != nil {
+		return fmt.Errorf("%w: ошибка записи: %v", errors.ErrFileSave, err)
+	}
+
+	session.rememberModTime(filePath)
+	return nil
+}
+
+// List возвращает сессии из CTX_DIR. Резервные копии и архивы в список не
+// попадают.
+func (s *FileStore) List() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(s.cfg.CtxDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, s.cfg.CtxFileExt) {
+			continue
+		}
+		// Копии после конфликта записи — не отдельные сессии: они не
+		// показываются в /sessions и не учитываются в MAX_SESSIONS
+		if strings.Contains(name, conflictMarker) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, SessionInfo{
+			Name:     strings.TrimSuffix(name, s.cfg.CtxFileExt),
+			Path:     filepath.Join(s.cfg.CtxDir, name),
+			ModTime:  info.ModTime(),
+			Size:     info.Size(),
+			Category: readCategory(filepath.Join(s.cfg.CtxDir, name)),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ModTime.After(sessions[j].ModTime)
+	})
+	return sessions, nil
+}
+
+// Delete удаляет файл сессии вместе с резервными копиями.
+func (s *FileStore) Delete(name string) error {
+	filePath := getSessionFilePath(name, s.cfg)
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("%w: удаление %s: %v", errors.ErrFileSave, filePath, err)
+	}
+	for n := 1; n <= s.cfg.BackupCount; n++ {
+		os.Remove(backupPath(filePath, n))
+	}
+	return nil
+}
+
+// MemoryStore хранит сессии в памяти процесса. Нужен для тестов и случаев,
+// когда историю не требуется сохранять между запусками.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+	seq      int
+}
+
+type memoryEntry struct {
+	data    []byte
+	modTime time.Time
+	seq     int // порядок сохранения: при равном времени новее та, что больше
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Load(userName string) (*ChatSession, error) {
+	s.mu.Lock()
+	entry, ok := s.sessions[userName]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.ErrNoSession
+	}
+
+	var session ChatSession
+	if err := json.Unmarshal(entry.data, &session); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileParse, err)
+	}
+	return &session, nil
+}
+
+func (s *MemoryStore) Save(session *ChatSession, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.sessions[session.UserName] = memoryEntry{data: append([]byte(nil), data...), modTime: time.Now(), seq: s.seq}
+	return nil
+}
+
+func (s *MemoryStore) List() ([]SessionInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make([]SessionInfo, 0, len(s.sessions))
+	seqs := make(map[string]int, len(s.sessions))
+	for name, entry := range s.sessions {
+		sessions = append(sessions, SessionInfo{
+			Name:     name,
+			ModTime:  entry.modTime,
+			Size:     int64(len(entry.data)),
+			Category: categoryOf(entry.data),
+		})
+		seqs[name] = entry.seq
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return seqs[sessions[i].Name] > seqs[sessions[j].Name]
+	})
+	return sessions, nil
+}
+
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[name]; !ok {
+		return errors.ErrNoSession
+	}
+	delete(s.sessions, name)
+	return nil
+}
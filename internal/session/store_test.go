@@ -0,0 +1,174 @@
+package session
+
+import (
+	"agent/internal/config"
+	agenterrors "agent/internal/errors"
+	"agent/internal/model"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stores возвращает хранилища, на которых проверяется одинаковое поведение
+// сессий.
+func stores(t *testing.T, cfg *config.Config) map[string]Store {
+	t.Helper()
+	cfg.CtxDir = t.TempDir()
+	cfg.CtxFileExt = ".json"
+	if err := ensureChatsDir(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{
+		"file":   NewFileStore(cfg),
+		"memory": NewMemoryStore(),
+	}
+}
+
+func TestStore_SaveAndLoad(t *testing.T) {
+	cfg := &config.Config{SessionCategory: "work"}
+	for name, store := range stores(t, cfg) {
+		t.Run(name, func(t *testing.T) {
+			session, err := NewChatSessionWithStore("Анна", cfg, store)
+			if err != nil {
+				t.Fatalf("NewChatSessionWithStore() error = %v", err)
+			}
+			if len(session.Messages) != 0 || session.Category != "work" {
+				t.Fatalf("new session = %+v, want empty session in category work", session)
+			}
+
+			session.Title = "Кофе"
+			session.Messages = []model.Message{
+				{Role: model.RoleUser, Content: "Как сварить кофе?", Timestamp: time.Now()},
+				{Role: model.RoleAssistant, Content: "Смелите зёрна.", Timestamp: time.Now()},
+			}
+			if err := session.SaveSession(session); err != nil {
+				t.Fatalf("SaveSession() error = %v", err)
+			}
+
+			loaded, err := NewChatSessionWithStore("Анна", cfg, store)
+			if err != nil {
+				t.Fatalf("reloading session: %v", err)
+			}
+			if loaded.Title != "Кофе" || len(loaded.Messages) != 2 || loaded.Messages[1].Content != "Смелите зёрна." {
+				t.Errorf("loaded session = %+v", loaded)
+			}
+
+			list, err := store.List()
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(list) != 1 || list[0].Name != "Анна" || list[0].Category != "work" || list[0].Size == 0 {
+				t.Errorf("List() = %+v", list)
+			}
+
+			if err := store.Delete("Анна"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := store.Load("Анна"); !errors.Is(err, agenterrors.ErrNoSession) {
+				t.Errorf("Load() after Delete error = %v, want ErrNoSession", err)
+			}
+		})
+	}
+}
+
+func TestFileStore_List_skipsConflictCopies(t *testing.T) {
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+	for _, name := range []string{"Анна.json", "Анна.conflict-20260101-120000.json"} {
+		if err := os.WriteFile(filepath.Join(cfg.CtxDir, name), []byte(`{"messages":[]}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := NewFileStore(cfg).List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || list[0].Name != "Анна" {
+		t.Errorf("List() = %+v, want only the session without the conflict copy", list)
+	}
+}
+
+func TestStore_Quota(t *testing.T) {
+	for _, policy := range []string{config.QuotaRefuse, config.QuotaDeleteOldest} {
+		cfg := &config.Config{MaxSessions: 2, QuotaPolicy: policy}
+		for name, store := range stores(t, cfg) {
+			t.Run(policy+"/"+name, func(t *testing.T) {
+				for _, user := range []string{"oldest", "newest"} {
+					session, err := NewChatSessionWithStore(user, cfg, store)
+					if err != nil {
+						t.Fatalf("NewChatSessionWithStore(%q) error = %v", user, err)
+					}
+					session.Messages = []model.Message{{Role: model.RoleUser, Content: user, Timestamp: time.Now()}}
+					if err := session.SaveSession(session); err != nil {
+						t.Fatalf("SaveSession() error = %v", err)
+					}
+					// Файловое хранилище упорядочивает сессии по mtime
+					time.Sleep(10 * time.Millisecond)
+				}
+
+				fresh, err := NewChatSessionWithStore("fresh", cfg, store)
+				if err != nil {
+					t.Fatalf("NewChatSessionWithStore() error = %v", err)
+				}
+				fresh.SetQuotaConfirm(func([]SessionInfo) bool { return true })
+				fresh.Messages = []model.Message{{Role: model.RoleUser, Content: "fresh", Timestamp: time.Now()}}
+				err = fresh.SaveSession(fresh)
+				if policy == config.QuotaRefuse {
+					if !errors.Is(err, agenterrors.ErrSessionQuota) {
+						t.Errorf("SaveSession() error = %v, want ErrSessionQuota", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("SaveSession() error = %v", err)
+				}
+				if _, err := store.Load("oldest"); !errors.Is(err, agenterrors.ErrNoSession) {
+					t.Errorf("the oldest session should be deleted, Load() error = %v", err)
+				}
+				if _, err := store.Load("newest"); err != nil {
+					t.Errorf("the newest session must be kept, Load() error = %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestStore_SizeLimitAndRoles(t *testing.T) {
+	cfg := &config.Config{MaxSessionBytes: 400, SessionSizePolicy: config.SizeTrim, RoleRuns: config.RoleRunsMerge}
+	for name, store := range stores(t, cfg) {
+		t.Run(name, func(t *testing.T) {
+			session, err := NewChatSessionWithStore("user", cfg, store)
+			if err != nil {
+				t.Fatalf("NewChatSessionWithStore() error = %v", err)
+			}
+			for range 10 {
+				session.Messages = append(session.Messages,
+					model.Message{Role: model.RoleUser, Content: "вопрос", Timestamp: time.Now()},
+					model.Message{Role: model.RoleAssistant, Content: "ответ", Timestamp: time.Now()},
+				)
+			}
+			// Два ответа подряд должны быть объединены при загрузке
+			session.Messages = append(session.Messages, model.Message{Role: model.RoleAssistant, Content: "ещё", Timestamp: time.Now()})
+
+			if err := session.SaveSession(session); err != nil {
+				t.Fatalf("SaveSession() error = %v", err)
+			}
+
+			list, _ := store.List()
+			if len(list) != 1 || list[0].Size > cfg.MaxSessionBytes {
+				t.Fatalf("stored session exceeds MAX_SESSION_BYTES: %+v", list)
+			}
+
+			loaded, err := NewChatSessionWithStore("user", cfg, store)
+			if err != nil {
+				t.Fatalf("reloading session: %v", err)
+			}
+			last := loaded.Messages[len(loaded.Messages)-1]
+			if last.Content != "ответ\n\nещё" {
+				t.Errorf("last message = %q, want merged assistant run", last.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// transcriptLabels сопоставляет метки ролей в текстовой расшифровке
+// с ролями сообщений. Сравнение без учёта регистра.
+var transcriptLabels = []struct {
+	label string
+	role  string
+}{
+	{"user:", model.RoleUser},
+	{"human:", model.RoleUser},
+	{"пользователь:", model.RoleUser},
+	{"assistant:", model.RoleAssistant},
+	{"ai:", model.RoleAssistant},
+	{"ассистент:", model.RoleAssistant},
+}
+
+// ImportTranscript разбирает текстовую расшифровку вида
+//
+//	User: вопрос
+//	Assistant: ответ
+//
+// в новую сессию. Строки без метки продолжают предыдущее сообщение,
+// строки до первой метки пропускаются. Имя пользователя не заполняется —
+// его задаёт вызывающий код перед сохранением.
+func ImportTranscript(r io.Reader, cfg *config.Config) (*ChatSession, error) {
+	var messages []model.Message
+	var current *model.Message
+	var lines []string
+	now := time.Now()
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+		if current.Content != "" {
+			messages = append(messages, *current)
+		}
+		current, lines = nil, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if role, rest, ok := parseTranscriptLabel(line); ok {
+			flush()
+			current = &model.Message{Role: role, Timestamp: now}
+			lines = []string{rest}
+			continue
+		}
+
+		if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrFileRead, err)
+	}
+	flush()
+
+	if len(messages) == 0 {
+		return nil, errors.ErrEmptyImport
+	}
+
+	return &ChatSession{
+		Messages: messages,
+		Created:  now,
+		Updated:  now,
+		Cfg:      cfg,
+	}, nil
+}
+
+func parseTranscriptLabel(line string) (role, rest string, ok bool) {
+	lower := strings.ToLower(line)
+	for _, l := range transcriptLabels {
+		if strings.HasPrefix(lower, l.label) {
+			return l.role, strings.TrimSpace(line[len(l.label):]), true
+		}
+	}
+	return "", "", false
+}
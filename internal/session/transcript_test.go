@@ -0,0 +1,69 @@
+package session
+
+import (
+	"agent/internal/config"
+	"agent/internal/errors"
+	"agent/internal/model"
+	"strings"
+	"testing"
+)
+
+func TestImportTranscript(t *testing.T) {
+	transcript := `Экспорт из другого инструмента
+---
+User: Как сварить кофе?
+Assistant: Вот шаги:
+1. Смелите зёрна.
+
+2. Залейте водой.
+Human: Спасибо!
+Ассистент: Пожалуйста 😊
+`
+
+	cfg := &config.Config{CtxDir: t.TempDir(), CtxFileExt: ".json"}
+
+	session, err := ImportTranscript(strings.NewReader(transcript), cfg)
+	if err != nil {
+		t.Fatalf("ImportTranscript() error = %v", err)
+	}
+
+	want := []model.Message{
+		{Role: model.RoleUser, Content: "Как сварить кофе?"},
+		{Role: model.RoleAssistant, Content: "Вот шаги:\n1. Смелите зёрна.\n\n2. Залейте водой."},
+		{Role: model.RoleUser, Content: "Спасибо!"},
+		{Role: model.RoleAssistant, Content: "Пожалуйста 😊"},
+	}
+
+	if len(session.Messages) != len(want) {
+		t.Fatalf("messages = %d, want %d: %+v", len(session.Messages), len(want), session.Messages)
+	}
+	for i, w := range want {
+		got := session.Messages[i]
+		if got.Role != w.Role || got.Content != w.Content {
+			t.Errorf("message[%d] = {%s %q}, want {%s %q}", i, got.Role, got.Content, w.Role, w.Content)
+		}
+		if got.Timestamp.IsZero() {
+			t.Errorf("message[%d] timestamp should be set", i)
+		}
+	}
+
+	session.UserName = "imported"
+	if err := session.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	loaded, err := NewChatSession("imported", cfg)
+	if err != nil {
+		t.Fatalf("loading imported session: %v", err)
+	}
+	if len(loaded.Messages) != len(want) {
+		t.Errorf("loaded messages = %d, want %d", len(loaded.Messages), len(want))
+	}
+}
+
+func TestImportTranscript_noMessages(t *testing.T) {
+	_, err := ImportTranscript(strings.NewReader("просто текст\nбез меток\n"), &config.Config{})
+	if err != errors.ErrEmptyImport {
+		t.Errorf("ImportTranscript() error = %v, want ErrEmptyImport", err)
+	}
+}
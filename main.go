@@ -1,48 +1,342 @@
 package main
 
 import (
+	"agent/internal/cache"
 	"agent/internal/chat"
 	"agent/internal/config"
+	"agent/internal/session"
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 )
 
 func main() {
-	cfg := config.NewConfig()
+	restoreName := flag.String("restore", "", "восстановить сессию из резервной копии: --restore <имя> [номер]")
+	sessionFile := flag.String("file", "", "открыть сессию из указанного JSON-файла")
+	transcriptFile := flag.String("import-transcript", "", "импортировать текстовую расшифровку User:/Assistant: в новую сессию")
+	markdownFile := flag.String("import-markdown", "", "восстановить сессию из Markdown, экспортированного командой /export md")
+	bare := flag.Bool("bare", false, "выводить только текст ответа модели, без меток, баннеров и размышлений")
+	jsonOutput := flag.Bool("json", false, "выводить каждый ответ одним JSON-объектом {\"role\", \"content\", \"thinking\"}; подразумевает --bare")
+	yes := flag.Bool("yes", false, "выполнять разрушительные команды (/prune, /replay, /delete-msg, /clear, /cache clear) без подтверждения")
+	doctor := flag.Bool("doctor", false, "проверить подключение к Ollama, наличие модели и настройки, затем выйти")
+	list := flag.Bool("list", false, "показать сохранённые сессии и выйти")
+	category := flag.String("category", "", "с --list: показать только сессии этой категории")
+	clearCache := flag.Bool("clear-cache", false, "очистить кэш ответов (RESPONSE_CACHE_DIR) и выйти")
+	usage := flag.Bool("usage", false, "показать, сколько места на диске занимают сессии, затем выйти")
+	batchFile := flag.String("batch", "", "выполнить запросы из файла (по одному на строку) без интерактивного режима")
+	batchOut := flag.String("batch-out", "batch_results.md", "файл или существующая директория для ответов --batch")
+	batchWorkers := flag.Int("batch-workers", 1, "сколько запросов --batch выполнять одновременно")
+	stdinJSON := flag.Bool("stdin-json", false, "прочитать массив сообщений JSON из stdin, вывести один ответ модели и выйти")
+	prompt := flag.String("p", "", "отправить один вопрос, вывести ответ, сохранить сессию и выйти")
+	stdinJSONMessages := flag.Bool("stdin-json-messages", false, "с --stdin-json: вывести обновлённый массив сообщений вместо текста ответа")
+	var envFiles stringList
+	flag.Var(&envFiles, "env", "env-файл с настройками; можно указать несколько раз, поздние перекрывают ранние")
+	flag.Parse()
+
+	// --json печатает в stdout только JSON-объекты ответов
+	if *jsonOutput {
+		*bare = true
+	}
+
+	// В режиме --bare stdout содержит только ответы, служебный вывод уходит в stderr
+	status := io.Writer(os.Stdout)
+	if *bare {
+		status = os.Stderr
+		config.SetWarningOutput(os.Stderr)
+	}
+
+	cfg := config.NewConfig(envFiles...)
 	if cfg == nil {
 		log.Fatal("Ошибка инициализации конфигурации")
 	}
+	cfg.Bare = *bare
+	if *jsonOutput {
+		cfg.OutputFormat = config.OutputJSON
+	}
+	cfg.AssumeYes = *yes
+
+	if *restoreName != "" {
+		restoreBackup(*restoreName, cfg)
+		return
+	}
+
+	if *transcriptFile != "" {
+		importSession(*transcriptFile, cfg, session.ImportTranscript)
+		return
+	}
+
+	if *markdownFile != "" {
+		importSession(*markdownFile, cfg, session.ImportMarkdown)
+		return
+	}
+
+	if *doctor {
+		runDoctor(cfg)
+		return
+	}
 
-	cfg.DisplayConfig()
+	if *list {
+		listSessions(cfg, *category)
+		return
+	}
+
+	if *clearCache {
+		clearResponseCache(cfg)
+		return
+	}
+
+	if *usage {
+		showUsage(cfg)
+		return
+	}
+
+	if *stdinJSON {
+		runOneShotJSON(cfg, *stdinJSONMessages)
+		return
+	}
 
-	userName := getUserName()
+	if *batchFile != "" {
+		runBatch(*batchFile, *batchOut, *batchWorkers, cfg)
+		return
+	}
+
+	if *prompt != "" {
+		runOnce(*prompt, *sessionFile, cfg)
+		return
+	}
 
-	curChat, err := chat.NewChat(userName, cfg)
+	if !cfg.Bare {
+		cfg.DisplayConfig()
+	}
+
+	var curChat *chat.Chat
+	var err error
+	if *sessionFile != "" {
+		curChat, err = chat.NewChatFromFile(*sessionFile, cfg)
+	} else {
+		curChat, err = chat.NewChat(chooseSession(getUserName(status), cfg, status), cfg)
+	}
 	if err != nil {
 		log.Fatal("Ошибка создания сессии чата:", err)
 	}
 
-	fmt.Printf("🤖 Добро пожаловать, %s!\n", userName)
+	if !cfg.Bare {
+		curChat.PrintWelcome()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go curChat.HandleShutdown(signals, os.Exit)
+
+	curChat.StartChat()
+}
+
+// stringList — флаг, который можно указать несколько раз.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func restoreBackup(userName string, cfg *config.Config) {
+	n := 1
+	if flag.NArg() > 0 {
+		parsed, err := strconv.Atoi(flag.Arg(0))
+		if err != nil || parsed < 1 {
+			log.Fatalf("Некорректный номер резервной копии: %q", flag.Arg(0))
+		}
+		n = parsed
+	}
+
+	if err := session.RestoreBackup(userName, n, cfg); err != nil {
+		log.Fatal("Ошибка восстановления сессии:", err)
+	}
+	fmt.Printf("♻️  Сессия %s восстановлена из резервной копии #%d\n", userName, n)
+}
+
+// importSession создаёт сессию из расшифровки или Markdown; имя
+// пользователя берётся из имени файла.
+func importSession(path string, cfg *config.Config, parse func(io.Reader, *config.Config) (*session.ChatSession, error)) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Ошибка открытия файла импорта:", err)
+	}
+	defer file.Close()
+
+	imported, err := parse(file, cfg)
+	if err != nil {
+		log.Fatal("Ошибка импорта:", err)
+	}
+
+	if err := os.MkdirAll(cfg.CtxDir, os.ModePerm); err != nil {
+		log.Fatal("Ошибка создания директории чатов:", err)
+	}
+
+	imported.UserName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if err := imported.SaveSession(imported); err != nil {
+		log.Fatal("Ошибка сохранения сессии:", err)
+	}
+	fmt.Printf("📥 Импортировано %d сообщений в сессию %s (%s)\n",
+		len(imported.Messages), imported.UserName, imported.FilePath())
+}
 
-	if len(curChat.GetMessages()) > 0 {
-		fmt.Printf("📚 Продолжаем существующий чат (%d сообщений в истории)\n", len(curChat.GetMessages()))
-		fmt.Println("\n📜 Последние сообщения:")
-		curChat.DisplayRecentMessages(curChat.GetMessages(), 4)
+// runDoctor выполняет диагностику и завершает процесс с кодом 1, если
+// хотя бы одна проверка не прошла.
+func runDoctor(cfg *config.Config) {
+	client, err := chat.NewClient(cfg)
+	if err != nil {
+		log.Fatal("Ошибка инициализации клиента:", err)
+	}
+
+	healthy := chat.PrintDoctor(chat.RunDoctor(client, cfg))
+	fmt.Println()
+	cfg.DisplaySources()
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+func listSessions(cfg *config.Config, category string) {
+	list, err := session.ListSessions(cfg)
+	if err != nil {
+		log.Fatal("Ошибка чтения директории чатов:", err)
+	}
+	chat.PrintSessions(os.Stdout, session.FilterByCategory(list, category), "")
+}
+
+func clearResponseCache(cfg *config.Config) {
+	if cfg.ResponseCacheDir == "" {
+		fmt.Println("🗄️  Кэш ответов отключён (RESPONSE_CACHE_DIR не задан)")
+		return
+	}
+	removed, err := cache.New(cfg.ResponseCacheDir).Clear()
+	if err != nil {
+		log.Fatal("Ошибка очистки кэша:", err)
+	}
+	fmt.Printf("🧹 Удалено записей кэша: %d\n", removed)
+}
+
+func showUsage(cfg *config.Config) {
+	usage, err := chat.CollectUsage(cfg)
+	if err != nil {
+		log.Fatal("Ошибка чтения директории чатов:", err)
+	}
+	chat.PrintUsage(os.Stdout, usage)
+}
+
+// runOneShotJSON продолжает разговор из stdin одним ответом, минуя файлы сессий.
+func runOneShotJSON(cfg *config.Config, withMessages bool) {
+	client, err := chat.NewClient(cfg)
+	if err != nil {
+		log.Fatal("Ошибка инициализации клиента:", err)
+	}
+
+	if err := chat.RunOneShotJSON(client, cfg, os.Stdin, os.Stdout, withMessages); err != nil {
+		log.Fatal("Ошибка генерации ответа:", err)
+	}
+}
+
+// runOnce отвечает на один вопрос без интерактивного режима. Имя сессии
+// берётся из переменной окружения USER, чтобы не ждать ввода; чат с этим
+// именем продолжается без вопроса.
+func runOnce(prompt, sessionFile string, cfg *config.Config) {
+	var curChat *chat.Chat
+	var err error
+	if sessionFile != "" {
+		curChat, err = chat.NewChatFromFile(sessionFile, cfg)
 	} else {
-		fmt.Println("🆕 Начинаем новый чат")
+		curChat, err = chat.NewChat(defaultUserName(), cfg)
+	}
+	if err != nil {
+		log.Fatal("Ошибка создания сессии чата:", err)
 	}
 
-	fmt.Println("Введите 'exit' или 'quit' для выхода")
-	fmt.Println("----------------------------------")
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go curChat.HandleShutdown(signals, os.Exit)
 
-	curChat.StartChat()
+	if err := curChat.RunOnce(prompt); err != nil {
+		log.Fatal("Ошибка генерации ответа:", err)
+	}
+}
+
+// defaultUserName возвращает имя пользователя ОС или «user», если USER не задан.
+func defaultUserName() string {
+	if name := strings.TrimSpace(os.Getenv("USER")); name != "" {
+		return name
+	}
+	return "user"
+}
+
+// runBatch выполняет запросы из файла, каждый с чистым контекстом,
+// и сохраняет ответы в out.
+func runBatch(path, out string, workers int, cfg *config.Config) {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Ошибка открытия файла запросов:", err)
+	}
+	defer file.Close()
+
+	prompts, err := chat.ReadBatchPrompts(file)
+	if err != nil {
+		log.Fatal("Ошибка чтения файла запросов:", err)
+	}
+
+	client, err := chat.NewClient(cfg)
+	if err != nil {
+		log.Fatal("Ошибка инициализации клиента:", err)
+	}
+
+	results := chat.RunBatch(client, cfg, prompts, workers)
+	if err := chat.WriteBatchResults(results, out); err != nil {
+		log.Fatal("Ошибка записи ответов:", err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	fmt.Printf("📦 Обработано запросов: %d (ошибок: %d), ответы сохранены в %s\n", len(results), failed, out)
+}
+
+// chooseSession предлагает продолжить найденный чат, начать заново или открыть
+// другую сессию. Вопрос не задаётся при AUTO_RESUME, --bare и вводе не из TTY.
+func chooseSession(userName string, cfg *config.Config, prompt io.Writer) string {
+	if cfg.AutoResume || cfg.Bare || !chat.StdinIsTerminal() || !session.SessionExists(userName, cfg) {
+		return userName
+	}
+
+	choice, other := chat.AskStartupChoice(os.Stdin, prompt, userName)
+	switch choice {
+	case chat.StartupFresh:
+		archive, err := session.ArchiveSession(userName, cfg)
+		if err != nil {
+			log.Fatal("Ошибка архивирования сессии:", err)
+		}
+		fmt.Fprintf(prompt, "🗄️  Старый чат сохранён в %s\n", archive)
+	case chat.StartupOther:
+		return other
+	}
+	return userName
 }
 
-func getUserName() string {
-	fmt.Print("👤 Введите ваше имя: ")
+func getUserName(prompt io.Writer) string {
+	fmt.Fprint(prompt, "👤 Введите ваше имя: ")
 
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -54,6 +348,6 @@ func getUserName() string {
 				return name
 			}
 		}
-		fmt.Print("❌ Имя не может быть пустым. Попробуйте еще раз: ")
+		fmt.Fprint(prompt, "❌ Имя не может быть пустым. Попробуйте еще раз: ")
 	}
 }